@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"event_planner_backend/utils"
+)
+
+// defaultJWTSecret signs tokens in development when JWT_SECRET is not set. It
+// is refused in release mode.
+const defaultJWTSecret = "dev_secret_change_me"
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted in release mode.
+const minJWTSecretLength = 32
+
+// JWTKeys signs and verifies the tokens of the application. It is set by
+// InitJWTKeys.
+var JWTKeys *utils.JWTKeySet
+
+// InitJWTKeys sets JWTKeys from the environment. With JWT_PRIVATE_KEY_FILE, a
+// PEM RSA (RS256) or Ed25519 (EdDSA) private key, tokens are signed with it;
+// otherwise with JWT_SECRET (HS256). The PEM keys listed in
+// JWT_VERIFICATION_KEY_FILES, comma separated, are accepted too, to rotate
+// keys without invalidating tokens. JWT_SECRET, when set alongside a private
+// key, still verifies the tokens signed with it before.
+// In release mode (GIN_MODE=release) the server refuses to start with the
+// default or a short secret, even one only used to verify tokens.
+func InitJWTKeys() {
+	keys, err := loadJWTKeys()
+	if err != nil {
+		log.Fatalf("invalid JWT keys: %v", err)
+	}
+	JWTKeys = keys
+	signing := keys.SigningKey()
+	log.Printf("signing tokens with %s key %s", signing.Method.Alg(), signing.ID)
+}
+
+// loadJWTKeys reads the keys described by InitJWTKeys.
+func loadJWTKeys() (*utils.JWTKeySet, error) {
+	release := GetEnv("GIN_MODE", "") == "release"
+	secret := GetEnv("JWT_SECRET", "")
+	privateKeyFile := GetEnv("JWT_PRIVATE_KEY_FILE", "")
+
+	var verifiers []*utils.JWTKey
+	for _, path := range strings.Split(GetEnv("JWT_VERIFICATION_KEY_FILES", ""), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		key, err := readJWTKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, key)
+	}
+
+	if privateKeyFile != "" {
+		signing, err := readJWTKeyFile(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if !signing.CanSign() {
+			return nil, fmt.Errorf("%s holds no private key", privateKeyFile)
+		}
+		if secret != "" && secret != defaultJWTSecret {
+			if release && len(secret) < minJWTSecretLength {
+				return nil, fmt.Errorf("JWT_SECRET needs at least %d characters in release mode; unset it to verify with the keys only", minJWTSecretLength)
+			}
+			verifiers = append(verifiers, utils.NewHMACKey(secret))
+		}
+		return utils.NewJWTKeySet(signing, verifiers...)
+	}
+
+	switch {
+	case release && (secret == "" || secret == defaultJWTSecret):
+		return nil, errors.New("set JWT_SECRET or JWT_PRIVATE_KEY_FILE; the default secret is refused in release mode")
+	case release && len(secret) < minJWTSecretLength:
+		return nil, fmt.Errorf("JWT_SECRET needs at least %d characters in release mode", minJWTSecretLength)
+	case secret == "":
+		log.Printf("env JWT_SECRET not set, using default")
+		secret = defaultJWTSecret
+	}
+	return utils.NewJWTKeySet(utils.NewHMACKey(secret), verifiers...)
+}
+
+// readJWTKeyFile reads a PEM key file.
+func readJWTKeyFile(path string) (*utils.JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := utils.ParseJWTKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
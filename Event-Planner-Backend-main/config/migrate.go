@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// legacyEventDuration is assigned to migrated events, which had no end time.
+const legacyEventDuration = time.Hour
+
+// legacyEventRow is the shape of an event row before start/end instants existed.
+type legacyEventRow struct {
+	ID        uint      `gorm:"column:event_id"`
+	EventDate time.Time `gorm:"column:event_date"`
+	EventTime string    `gorm:"column:event_time"`
+}
+
+// MigrateLegacyEventSchedule converts events stored with the old
+// event_date/event_time columns into starts_at/ends_at instants.
+// The old values carry no zone, so they are read as wall-clock time in
+// DEFAULT_TIMEZONE (UTC if unset). The legacy columns are dropped afterwards,
+// which makes this a no-op on later runs. It must run before AutoMigrate so
+// the new NOT NULL columns are populated first.
+func MigrateLegacyEventSchedule(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable("events") || !m.HasColumn("events", "event_date") {
+		return nil
+	}
+
+	tzName := GetEnv("DEFAULT_TIMEZONE", "UTC")
+	loc, err := time.LoadLocation(tzName)
+	if err != nil {
+		return fmt.Errorf("invalid DEFAULT_TIMEZONE %q: %w", tzName, err)
+	}
+
+	// Add the new columns as nullable so existing rows can be backfilled.
+	// MySQL commits DDL implicitly, so only the backfill runs in a transaction;
+	// an interrupted run is resumed on the next start.
+	columns := []struct{ name, definition string }{
+		{"starts_at", "DATETIME NULL"},
+		{"ends_at", "DATETIME NULL"},
+		{"timezone", "VARCHAR(64) NOT NULL DEFAULT 'UTC'"},
+	}
+	for _, col := range columns {
+		if m.HasColumn("events", col.name) {
+			continue
+		}
+		if err := db.Exec("ALTER TABLE `events` ADD COLUMN `" + col.name + "` " + col.definition).Error; err != nil {
+			return fmt.Errorf("add column %s: %w", col.name, err)
+		}
+	}
+
+	var migrated int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var rows []legacyEventRow
+		if err := tx.Table("events").
+			Select("event_id, event_date, event_time").
+			Where("starts_at IS NULL").
+			Scan(&rows).Error; err != nil {
+			return fmt.Errorf("read legacy events: %w", err)
+		}
+
+		for _, row := range rows {
+			clock, err := time.Parse("15:04:05", row.EventTime)
+			if err != nil {
+				log.Printf("event %d has invalid event_time %q, using midnight", row.ID, row.EventTime)
+				clock = time.Time{}
+			}
+			startsAt := time.Date(row.EventDate.Year(), row.EventDate.Month(), row.EventDate.Day(),
+				clock.Hour(), clock.Minute(), clock.Second(), 0, loc)
+			if err := tx.Table("events").Where("event_id = ?", row.ID).Updates(map[string]interface{}{
+				"starts_at": startsAt,
+				"ends_at":   startsAt.Add(legacyEventDuration),
+				"timezone":  tzName,
+			}).Error; err != nil {
+				return fmt.Errorf("migrate event %d: %w", row.ID, err)
+			}
+		}
+		migrated = len(rows)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := m.DropColumn("events", "event_time"); err != nil {
+		return fmt.Errorf("drop event_time: %w", err)
+	}
+	if err := m.DropColumn("events", "event_date"); err != nil {
+		return fmt.Errorf("drop event_date: %w", err)
+	}
+
+	log.Printf("migrated %d events to start/end instants in %s", migrated, tzName)
+	return nil
+}
+
+// MigrateEmailVerification adds the email_verified_at column to users and
+// marks the accounts created before email verification existed as verified,
+// so they keep working. It is a no-op once the column exists, and must run
+// before AutoMigrate, which would add the column with every user unverified.
+func MigrateEmailVerification(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable("users") || m.HasColumn("users", "email_verified_at") {
+		return nil
+	}
+
+	if err := db.Exec("ALTER TABLE `users` ADD COLUMN `email_verified_at` DATETIME NULL").Error; err != nil {
+		return fmt.Errorf("add column email_verified_at: %w", err)
+	}
+	result := db.Exec("UPDATE `users` SET `email_verified_at` = `created_at`")
+	if result.Error != nil {
+		return fmt.Errorf("mark existing users verified: %w", result.Error)
+	}
+
+	log.Printf("marked %d existing users as verified", result.RowsAffected)
+	return nil
+}
+
+// fullTextIndexes are the FULLTEXT indexes search relies on, as defined in
+// event_planer_schema.sql.
+var fullTextIndexes = []struct{ table, name, columns string }{
+	{"events", "ft_events_title_desc", "`title`, `description`"},
+	{"tasks", "ft_tasks_description", "`description`"},
+}
+
+// EnsureFullTextIndexes creates the FULLTEXT indexes of search on databases
+// created by AutoMigrate, which does not know them. Search falls back to LIKE
+// when they cannot be created. It must run after AutoMigrate.
+func EnsureFullTextIndexes(db *gorm.DB) error {
+	m := db.Migrator()
+	for _, index := range fullTextIndexes {
+		if !m.HasTable(index.table) || m.HasIndex(index.table, index.name) {
+			continue
+		}
+		if err := db.Exec("ALTER TABLE `" + index.table + "` ADD FULLTEXT KEY `" + index.name + "` (" + index.columns + ")").Error; err != nil {
+			return fmt.Errorf("add index %s: %w", index.name, err)
+		}
+		log.Printf("created full-text index %s", index.name)
+	}
+	return nil
+}
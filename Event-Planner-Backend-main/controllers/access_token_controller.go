@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/utils"
+)
+
+const (
+	// maxAccessTokens bounds the active personal access tokens of a user.
+	maxAccessTokens = 50
+	// maxAccessTokenDays bounds the validity of personal access tokens.
+	maxAccessTokenDays = 365
+)
+
+// CreateAccessTokenRequest defines the payload to create a personal access
+// token. Without expiresInDays, the token never expires.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays *int     `json:"expiresInDays"`
+}
+
+// formatAccessToken describes a personal access token, without its secret.
+func formatAccessToken(token models.PersonalAccessToken, now time.Time) gin.H {
+	status := "active"
+	switch {
+	case token.RevokedAt != nil:
+		status = "revoked"
+	case token.ExpiresAt != nil && !now.Before(*token.ExpiresAt):
+		status = "expired"
+	}
+	return gin.H{
+		"id":         token.ID,
+		"name":       token.Name,
+		"prefix":     token.Prefix,
+		"scopes":     strings.Split(token.Scopes, ","),
+		"status":     status,
+		"createdAt":  token.CreatedAt,
+		"expiresAt":  token.ExpiresAt,
+		"lastUsedAt": token.LastUsedAt,
+		"revokedAt":  token.RevokedAt,
+	}
+}
+
+// GetAccessTokens lists the personal access tokens of the current user,
+// newest first. Revoked and expired ones are listed too, with their last use.
+func (s *Service) GetAccessTokens(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+	if !s.available(c) {
+		return
+	}
+
+	tokens, err := s.store.AccessTokens().List(userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch tokens: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	result := make([]gin.H, len(tokens))
+	for i, token := range tokens {
+		result[i] = formatAccessToken(token, now)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tokens":          result,
+		"count":           len(result),
+		"availableScopes": models.PersonalAccessTokenScopes,
+	})
+}
+
+// CreateAccessToken creates a personal access token for the current user.
+// The token is returned only this once; only its hash is stored.
+func (s *Service) CreateAccessToken(c *gin.Context) {
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.JSONError(c, http.StatusBadRequest, "name is required")
+		return
+	}
+	requested := map[string]bool{}
+	for _, s := range req.Scopes {
+		scope := strings.TrimSpace(strings.ToLower(s))
+		if !middleware.HasScope(strings.Join(models.PersonalAccessTokenScopes, ","), scope) {
+			utils.JSONError(c, http.StatusBadRequest, "unknown scope "+strconv.Quote(s)+". Use "+strings.Join(models.PersonalAccessTokenScopes, ", "))
+			return
+		}
+		requested[scope] = true
+	}
+	scopes := []string{}
+	for _, scope := range models.PersonalAccessTokenScopes {
+		if requested[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	now := time.Now()
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxAccessTokenDays {
+			utils.JSONError(c, http.StatusBadRequest, "expiresInDays must be between 1 and "+strconv.Itoa(maxAccessTokenDays))
+			return
+		}
+		t := now.AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+	if !s.available(c) {
+		return
+	}
+
+	active, err := s.store.AccessTokens().CountActive(userID, now)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to count tokens: "+err.Error())
+		return
+	}
+	if active >= maxAccessTokens {
+		utils.JSONError(c, http.StatusConflict, "too many active tokens; revoke unused ones first")
+		return
+	}
+
+	secret, err := utils.GenerateToken(32)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	token := middleware.AccessTokenPrefix + secret
+	stored := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:len(middleware.AccessTokenPrefix)+6],
+		Scopes:    strings.Join(scopes, ","),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.store.AccessTokens().Create(&stored); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create token: "+err.Error())
+		return
+	}
+
+	result := formatAccessToken(stored, now)
+	result["token"] = token
+	result["message"] = "store this token somewhere safe, it is shown only once"
+	c.JSON(http.StatusCreated, result)
+}
+
+// RevokeAccessToken revokes one of the personal access tokens of the current
+// user. Requests with it are refused from then on.
+func (s *Service) RevokeAccessToken(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+	tokenID, ok := idParam(c, "tokenId", "token")
+	if !ok || !s.available(c) {
+		return
+	}
+
+	token, err := s.store.AccessTokens().Find(tokenID, userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "token not found")
+		return
+	}
+	if token.RevokedAt == nil {
+		now := time.Now()
+		if err := s.store.AccessTokens().Revoke(token.ID, now); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to revoke token: "+err.Error())
+			return
+		}
+		token.RevokedAt = &now
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "token revoked",
+		"token":   formatAccessToken(token, time.Now()),
+	})
+}
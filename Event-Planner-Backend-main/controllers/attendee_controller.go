@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// UpdateAttendeeRoleRequest defines the payload to change an attendee's role.
+type UpdateAttendeeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=organizer attendee"`
+}
+
+// RemoveAttendee removes a user from an event (organizers only). Their
+// occurrence responses are deleted, their tasks of the event unassigned, a
+// pending ownership transfer to them cancelled, and their place, if they were
+// going, given to the waitlist. The creator and the
+// last organizer cannot be removed.
+func (s *Service) RemoveAttendee(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	targetID, ok := attendeeUserID(c)
+	if !ok || !s.available(c) {
+		return
+	}
+
+	// Use transaction for atomicity
+	var event models.Event
+	var promoted []models.EventAttendee
+	var unassigned int64
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can remove attendees"); !ok {
+			return errResponded
+		}
+
+		if targetID == event.CreatedBy {
+			utils.JSONError(c, http.StatusConflict, "the event creator cannot be removed")
+			return errResponded
+		}
+
+		attendee, ok := loadAttendee(c, store, event, targetID)
+		if !ok {
+			return errResponded
+		}
+
+		if attendee.Role == "organizer" && !keepsOrganizer(c, store, event, targetID, "cannot remove the last organizer of the event") {
+			return errResponded
+		}
+
+		if err := cancelOwnershipTransfers(store, event.ID, targetID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return errResponded
+		}
+
+		if err := store.Occurrences().DeleteUserResponses(event.ID, targetID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to delete occurrence responses: "+err.Error())
+			return errResponded
+		}
+
+		var err error
+		if unassigned, err = store.Tasks().UnassignUser(event.ID, targetID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to unassign tasks: "+err.Error())
+			return errResponded
+		}
+
+		if err := store.Attendees().Delete(event.ID, targetID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to remove attendee: "+err.Error())
+			return errResponded
+		}
+
+		if attendee.Status == "going" {
+			if promoted, err = promoteWaitlisted(store, event); err != nil {
+				utils.JSONError(c, http.StatusInternalServerError, err.Error())
+				return errResponded
+			}
+		}
+
+		message := fmt.Sprintf("You have been removed from %q.", event.Title)
+		if err := notify(store, targetID, &event.ID, "attendee_removed", message); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to notify attendee: "+err.Error())
+			return errResponded
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to remove attendee: "+err.Error())
+		return
+	}
+
+	promotedIDs := make([]uint, len(promoted))
+	for i, p := range promoted {
+		promotedIDs[i] = p.UserID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "attendee removed successfully",
+		"eventId":         event.ID,
+		"userId":          targetID,
+		"unassignedTasks": unassigned,
+		"promoted":        promotedIDs,
+	})
+}
+
+// UpdateAttendeeRole makes an attendee an organizer or a plain attendee
+// (organizers only). The creator and the last organizer cannot be demoted.
+func (s *Service) UpdateAttendeeRole(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	targetID, ok := attendeeUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAttendeeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	// Use transaction for atomicity
+	var event models.Event
+	var previousRole string
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can change attendee roles"); !ok {
+			return errResponded
+		}
+
+		if targetID == event.CreatedBy && req.Role != "organizer" {
+			utils.JSONError(c, http.StatusConflict, "the event creator must remain an organizer")
+			return errResponded
+		}
+
+		attendee, ok := loadAttendee(c, store, event, targetID)
+		if !ok {
+			return errResponded
+		}
+		previousRole = attendee.Role
+
+		if attendee.Role == req.Role {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "role unchanged",
+				"eventId": event.ID,
+				"userId":  targetID,
+				"role":    attendee.Role,
+			})
+			return errResponded
+		}
+
+		if attendee.Role == "organizer" && !keepsOrganizer(c, store, event, targetID, "cannot demote the last organizer of the event") {
+			return errResponded
+		}
+
+		if err := store.Attendees().UpdateRole(event.ID, targetID, req.Role); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to update role: "+err.Error())
+			return errResponded
+		}
+
+		// Only organizers can be nominated as owner
+		if err := cancelOwnershipTransfers(store, event.ID, targetID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return errResponded
+		}
+
+		message := fmt.Sprintf("You are now an %s of %q.", req.Role, event.Title)
+		if err := notify(store, targetID, &event.ID, "role_changed", message); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to notify attendee: "+err.Error())
+			return errResponded
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update role: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "role updated successfully",
+		"eventId":      event.ID,
+		"userId":       targetID,
+		"role":         req.Role,
+		"previousRole": previousRole,
+	})
+}
+
+// attendeeUserID parses the userId route parameter.
+func attendeeUserID(c *gin.Context) (uint, bool) {
+	return idParam(c, "userId", "user")
+}
+
+// loadAttendee loads the attendee record of userID for event. It writes the
+// error response and returns ok=false on failure.
+func loadAttendee(c *gin.Context, store repository.Store, event models.Event, userID uint) (models.EventAttendee, bool) {
+	attendee, err := store.Attendees().Find(event.ID, userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "attendee not found")
+			return attendee, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+		return attendee, false
+	}
+	return attendee, true
+}
+
+// keepsOrganizer checks that event keeps an organizer without userID: the
+// creator, or another attendee with the organizer role. It writes the error
+// response and returns false otherwise. The event row should be locked by the
+// caller.
+func keepsOrganizer(c *gin.Context, store repository.Store, event models.Event, userID uint, conflict string) bool {
+	kept, err := store.Attendees().HasOtherOrganizer(event, userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to count organizers: "+err.Error())
+		return false
+	}
+	if !kept {
+		utils.JSONError(c, http.StatusConflict, conflict)
+		return false
+	}
+	return true
+}
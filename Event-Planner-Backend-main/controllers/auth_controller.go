@@ -1,16 +1,17 @@
 package controllers
 
 import (
-	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
-	"event_planner_backend/config"
+	"event_planner_backend/middleware"
 	"event_planner_backend/models"
+	"event_planner_backend/repository"
 	"event_planner_backend/utils"
-	"gorm.io/gorm"
 )
 
 // Simple in-memory fallback store when DB is not connected.
@@ -18,9 +19,10 @@ var inMemoryUsers = map[string]*models.User{}
 
 // SignupRequest represents the expected payload for signup.
 type SignupRequest struct {
-	Name     string `json:"name" binding:"required,min=2,max=100"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6,max=72"`
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required,min=6,max=72"`
+	InviteToken string `json:"inviteToken"` // Optional, from an invite link sent to another email
 }
 
 // LoginRequest represents the expected payload for login.
@@ -29,8 +31,9 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// Signup creates a new user with hashed password.
-func Signup(c *gin.Context) {
+// Signup creates a new user with hashed password, and mails them a link to
+// verify their email.
+func (s *Service) Signup(c *gin.Context) {
 	var req SignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.JSONError(c, http.StatusBadRequest, "invalid payload")
@@ -52,16 +55,37 @@ func Signup(c *gin.Context) {
 
     user := &models.User{Name: name, Email: email, PasswordHash: hash}
 
-	if config.DB != nil {
-		// Persist with GORM
-		if err := config.DB.Create(user).Error; err != nil {
-			if strings.Contains(strings.ToLower(err.Error()), "duplicate") || strings.Contains(strings.ToLower(err.Error()), "unique") {
+	invitedEvents := []uint{}
+	if s.store != nil {
+		// Persist through the store, attaching the invitation of inviteToken;
+		// those sent to this email wait until it is verified
+		now := time.Now()
+		user.VerificationSentAt = &now
+		var created bool
+		err := s.store.Transaction(func(store repository.Store) error {
+			if err := store.Users().Create(user); err != nil {
+				return err
+			}
+			created = true
+			var err error
+			invitedEvents, err = attachInvitations(store, *user, strings.TrimSpace(req.InviteToken))
+			return err
+		})
+		if err != nil {
+			if err == errInvitationInvalid {
+				utils.JSONError(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			if !created && (strings.Contains(strings.ToLower(err.Error()), "duplicate") || strings.Contains(strings.ToLower(err.Error()), "unique")) {
 				utils.JSONError(c, http.StatusConflict, "email already registered")
 				return
 			}
 			utils.JSONError(c, http.StatusInternalServerError, "failed to create user")
 			return
 		}
+		if err := sendVerificationEmail(*user); err != nil {
+			log.Printf("failed to send verification email to %s: %v", user.Email, err)
+		}
 	} else {
 		// In-memory fallback
 		if _, exists := inMemoryUsers[email]; exists {
@@ -73,22 +97,23 @@ func Signup(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":    user.ID,
-		"name":  user.Name,
-		"email": user.Email,
+		"id":            user.ID,
+		"name":          user.Name,
+		"email":         user.Email,
+		"emailVerified": user.EmailVerifiedAt != nil,
+		"invitedEvents": invitedEvents,
 	})
 }
 
-// findUserByEmail tries DB first, then in-memory.
-func findUserByEmail(email string) (*models.User, error) {
-	if config.DB != nil {
-		var user models.User
-		res := config.DB.Where("email = ?", email).First(&user)
-		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+// findUserByEmail tries the store first, then in-memory.
+func (s *Service) findUserByEmail(email string) (*models.User, error) {
+	if s.store != nil {
+		user, err := s.store.Users().FindByEmail(email)
+		if err == repository.ErrNotFound {
 			return nil, nil
 		}
-		if res.Error != nil {
-			return nil, res.Error
+		if err != nil {
+			return nil, err
 		}
 		return &user, nil
 	}
@@ -98,8 +123,18 @@ func findUserByEmail(email string) (*models.User, error) {
 	return nil, nil
 }
 
-// Login verifies credentials and returns a JWT.
-func Login(c *gin.Context) {
+// releaseLogin gives back the reservation of a login attempt that was not a
+// failure.
+func releaseLogin(store repository.Store, reservation *loginReservation, email string) {
+	if err := releaseLoginAttempt(store, reservation); err != nil {
+		log.Printf("failed to release login attempt of %s: %v", email, err)
+	}
+}
+
+// Login verifies credentials and returns a short-lived access token and a
+// refresh token. Failed attempts are throttled per email and per client IP.
+// Users with two-factor login get a challenge token instead, see LoginMFA.
+func (s *Service) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.JSONError(c, http.StatusBadRequest, "invalid payload")
@@ -107,29 +142,71 @@ func Login(c *gin.Context) {
 	}
 
 	email := strings.ToLower(strings.TrimSpace(req.Email))
-	user, err := findUserByEmail(email)
+	// The attempt counts as failed until the password proved right, so that
+	// parallel guesses cannot all pass the throttle
+	var reservation *loginReservation
+	if s.store != nil {
+		var ok bool
+		if reservation, ok = reserveLoginAttempt(c, s.store, loginSubjects(email, c.ClientIP())); !ok {
+			return
+		}
+	}
+
+	user, err := s.findUserByEmail(email)
 	if err != nil {
+		releaseLogin(s.store, reservation, email)
 		utils.JSONError(c, http.StatusInternalServerError, "failed to query user")
 		return
 	}
-	if user == nil || !utils.CheckPassword(user.PasswordHash, req.Password) {
+	// Unknown emails cost a password check too, and count as failures, so
+	// that neither the timing nor the throttling reveals which ones exist
+	hash := ""
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !utils.CheckPasswordConstantTime(hash, req.Password) {
 		utils.JSONError(c, http.StatusUnauthorized, "invalid email or password")
 		return
 	}
 
-	secret := config.MustGetEnv("JWT_SECRET", "dev_secret_change_me")
-	token, err := utils.GenerateJWT(secret, user.ID, user.Email)
+	if s.store != nil && user.EmailVerifiedAt == nil && middleware.RestrictedForUnverified(middleware.ActionLogin) {
+		releaseLogin(s.store, reservation, email)
+		// Send the link again (throttled), so the user can verify and log in
+		if claimed, _, err := claimVerificationEmail(s.store, user.ID); err != nil {
+			log.Printf("failed to update user %d: %v", user.ID, err)
+		} else if claimed {
+			if err := sendVerificationEmail(*user); err != nil {
+				log.Printf("failed to send verification email to %s: %v", user.Email, err)
+			}
+		}
+		utils.JSONError(c, http.StatusForbidden, middleware.UnverifiedMessage(middleware.ActionLogin)+". Check your inbox for the verification link")
+		return
+	}
+
+	if s.store != nil && user.TOTPEnabledAt != nil {
+		// Failures are cleared once the code is checked too, so that knowing
+		// the password does not allow trying codes endlessly
+		releaseLogin(s.store, reservation, email)
+		challenge, err := issueMFAChallenge(*user)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+	if s.store != nil {
+		if err := clearLoginFailures(s.store, reservation, email); err != nil {
+			log.Printf("failed to clear failed logins of %s: %v", email, err)
+		}
+	}
+
+	tokens, err := issueTokens(s.store, *user, "")
 	if err != nil {
 		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user": gin.H{
-			"id":    user.ID,
-			"name":  user.Name,
-			"email": user.Email,
-		},
-	})
+	tokens["user"] = loginUserPayload(*user)
+	c.JSON(http.StatusOK, tokens)
 }
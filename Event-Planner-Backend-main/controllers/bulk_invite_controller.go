@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// maxBulkInvites bounds the rows of one bulk invitation.
+	maxBulkInvites = 1000
+	// maxBulkInviteSize bounds the size of a bulk invitation request.
+	maxBulkInviteSize = 1 << 20
+)
+
+// inviteInvalidRole is the outcome of rows with an unknown role.
+const inviteInvalidRole = "invalid_role"
+
+// BulkInviteEntry is one person to invite: a row of the CSV file or an
+// element of the JSON array.
+type BulkInviteEntry struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"` // Default: the role query parameter, or attendee
+	row   int
+}
+
+// BulkInviteToEvent invites many people at once, from a JSON array or a CSV
+// file with email, name and role columns (organizers only). Each row goes
+// through the same checks as InviteUserToEvent; all rows are processed in one
+// transaction and the response reports the outcome of each.
+func (s *Service) BulkInviteToEvent(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	defaultRole := c.DefaultQuery("role", "attendee")
+	if defaultRole != "organizer" && defaultRole != "attendee" {
+		utils.JSONError(c, http.StatusBadRequest, "invalid role. Use organizer or attendee")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkInviteSize)
+	entries, err := readBulkInvites(c)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		utils.JSONError(c, http.StatusBadRequest, "no invitations given")
+		return
+	}
+	if len(entries) > maxBulkInvites {
+		utils.JSONError(c, http.StatusBadRequest, fmt.Sprintf("too many invitations. At most %d per request", maxBulkInvites))
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var event models.Event
+	var results []gin.H
+	summary := map[string]int{
+		inviteInvited:        0,
+		inviteAlreadyInvited: 0,
+		inviteUnknownUser:    0,
+		inviteInvalidEmail:   0,
+		inviteInvalidRole:    0,
+	}
+	err = s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can invite users"); !ok {
+			return errResponded
+		}
+
+		results = make([]gin.H, 0, len(entries))
+		for _, entry := range entries {
+			email := strings.ToLower(strings.TrimSpace(entry.Email))
+			name := truncateRunes(strings.TrimSpace(entry.Name), 100)
+			role := strings.ToLower(strings.TrimSpace(entry.Role))
+			if role == "" {
+				role = defaultRole
+			}
+			result := gin.H{"row": entry.row, "email": email, "role": role}
+			if name != "" {
+				result["name"] = name
+			}
+
+			switch {
+			case binding.Validator.ValidateStruct(&InviteUserRequest{Email: email}) != nil:
+				result["result"] = inviteInvalidEmail
+				result["message"] = "invalid email address"
+			case binding.Validator.ValidateStruct(&InviteUserRequest{Email: email, Role: role}) != nil:
+				result["result"] = inviteInvalidRole
+				result["message"] = "invalid role. Use organizer or attendee"
+			default:
+				outcome, err := inviteToEvent(store, event, email, name, role, userID)
+				if err != nil {
+					utils.JSONError(c, http.StatusInternalServerError, fmt.Sprintf("row %d: %s", entry.row, err.Error()))
+					return errResponded
+				}
+				result["result"] = outcome.Result
+				switch outcome.Result {
+				case inviteInvited:
+					result["message"] = "user invited"
+					result["userId"] = outcome.User.ID
+				case inviteAlreadyInvited:
+					result["message"] = "already invited to this event"
+					if outcome.User != nil {
+						result["userId"] = outcome.User.ID
+					}
+				case inviteUnknownUser:
+					result["message"] = "no verified account uses this email; an invitation link was created"
+					result["invitationId"] = outcome.Invitation.ID
+					result["inviteUrl"] = invitationURL(c, outcome.Token)
+					result["expiresAt"] = outcome.Invitation.ExpiresAt
+				}
+			}
+			summary[result["result"].(string)]++
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create invitations: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId": event.ID,
+		"total":   len(results),
+		"summary": summary,
+		"results": results,
+	})
+}
+
+// readBulkInvites reads the people to invite from a CSV file (multipart field
+// "file", or a text/csv body) or a JSON array of emails or objects.
+func readBulkInvites(c *gin.Context) ([]BulkInviteEntry, error) {
+	contentType := c.ContentType()
+	switch {
+	case strings.HasPrefix(contentType, "multipart/"):
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("file is required: " + err.Error())
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, errors.New("failed to read file: " + err.Error())
+		}
+		defer file.Close()
+		return parseBulkInviteCSV(file)
+	case contentType == "text/csv" || contentType == "application/csv":
+		return parseBulkInviteCSV(c.Request.Body)
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, errors.New("failed to read request: " + err.Error())
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		// Also accept {"invitations": [...]}
+		var wrapper struct {
+			Invitations []json.RawMessage `json:"invitations"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil || wrapper.Invitations == nil {
+			return nil, errors.New("invalid payload: expected a JSON array of emails or {email, name, role} objects, or a CSV file")
+		}
+		items = wrapper.Invitations
+	}
+
+	entries := make([]BulkInviteEntry, len(items))
+	for i, item := range items {
+		item = bytes.TrimSpace(item)
+		if len(item) > 0 && item[0] == '"' {
+			if err := json.Unmarshal(item, &entries[i].Email); err != nil {
+				return nil, fmt.Errorf("invalid payload: row %d: %v", i+1, err)
+			}
+		} else if err := json.Unmarshal(item, &entries[i]); err != nil {
+			return nil, fmt.Errorf("invalid payload: row %d: expected an email or an object", i+1)
+		}
+		entries[i].row = i + 1
+	}
+	return entries, nil
+}
+
+// parseBulkInviteCSV reads email, name and role columns. A header row naming
+// the columns is optional; without one the columns are read in that order.
+func parseBulkInviteCSV(r io.Reader) ([]BulkInviteEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("invalid CSV file: " + err.Error())
+	}
+
+	columns := map[string]int{"email": 0, "name": 1, "role": 2}
+	start := 0
+	if len(records) > 0 {
+		header := map[string]int{}
+		for i, cell := range records[0] {
+			header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff")))] = i
+		}
+		if _, ok := header["email"]; ok {
+			columns = map[string]int{"email": -1, "name": -1, "role": -1}
+			for name := range columns {
+				if i, ok := header[name]; ok {
+					columns[name] = i
+				}
+			}
+			start = 1
+		}
+	}
+
+	cell := func(record []string, name string) string {
+		if i := columns[name]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var entries []BulkInviteEntry
+	for i := start; i < len(records); i++ {
+		record := records[i]
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		entries = append(entries, BulkInviteEntry{
+			Email: strings.TrimPrefix(cell(record, "email"), "\ufeff"),
+			Name:  cell(record, "name"),
+			Role:  cell(record, "role"),
+			row:   i + 1,
+		})
+	}
+	return entries, nil
+}
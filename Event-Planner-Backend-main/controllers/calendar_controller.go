@@ -0,0 +1,419 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/config"
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// icalPartstats maps attendance statuses to iCalendar PARTSTAT values.
+var icalPartstats = map[string]string{
+	"going":      "ACCEPTED",
+	"maybe":      "TENTATIVE",
+	"not_going":  "DECLINED",
+	"pending":    "NEEDS-ACTION",
+	"waitlisted": "TENTATIVE",
+}
+
+// feedTokenBytes is the entropy of calendar feed tokens.
+const feedTokenBytes = 32
+
+// icalUID returns the iCalendar UID of an event, stable across exports:
+// the original UID for imported events.
+func icalUID(event models.Event) string {
+	if event.UID != nil && *event.UID != "" {
+		return *event.UID
+	}
+	return fmt.Sprintf(icalUIDFormat, event.ID)
+}
+
+// icalUIDFormat builds the UID of events created here.
+const icalUIDFormat = "event-%d@event-planner"
+
+// publicBaseURL returns the base URL clients reach the API at, from
+// PUBLIC_BASE_URL or else the request.
+func publicBaseURL(c *gin.Context) string {
+	if base := config.GetEnv("PUBLIC_BASE_URL", ""); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// renderCalendar renders events as a VCALENDAR. Events must be loaded with
+// their organizer and attendees. Edited occurrences, and occurrences someone
+// answered individually, are written as extra VEVENTs with a RECURRENCE-ID.
+func renderCalendar(store repository.Store, events []models.Event, name string) (string, error) {
+	eventIDs := make([]uint, len(events))
+	for i, event := range events {
+		eventIDs[i] = event.ID
+	}
+	overrides, _, err := loadOccurrenceData(store, eventIDs, 0)
+	if err != nil {
+		return "", err
+	}
+	var rows []models.EventAttendeeOccurrence
+	if len(eventIDs) > 0 {
+		if rows, err = store.Occurrences().Responses(eventIDs, 0); err != nil {
+			return "", err
+		}
+	}
+	responses := map[uint]map[int64]map[uint]string{} // event -> occurrence -> user -> status
+	for _, r := range rows {
+		if responses[r.EventID] == nil {
+			responses[r.EventID] = map[int64]map[uint]string{}
+		}
+		key := r.OccurrenceStart.Unix()
+		if responses[r.EventID][key] == nil {
+			responses[r.EventID][key] = map[uint]string{}
+		}
+		responses[r.EventID][key][r.UserID] = r.Status
+	}
+
+	var w utils.ICalWriter
+	w.Begin("VCALENDAR")
+	w.Prop("VERSION", "2.0")
+	w.Prop("PRODID", "-//Event Planner//Event Planner Backend//EN")
+	w.Prop("CALSCALE", "GREGORIAN")
+	w.Prop("METHOD", "PUBLISH")
+	w.Text("X-WR-CALNAME", name)
+	w.Prop("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	w.Prop("X-PUBLISHED-TTL", "PT1H")
+
+	// One VTIMEZONE per zone, covering the events planned in it
+	type span struct{ from, to time.Time }
+	zones := map[string]span{}
+	var zoneOrder []string
+	for _, event := range events {
+		to := event.EndsAt
+		if event.IsRecurring() {
+			if horizon := time.Now().AddDate(2, 0, 0); horizon.After(to) {
+				to = horizon
+			}
+		}
+		s, ok := zones[event.Timezone]
+		if !ok {
+			zoneOrder = append(zoneOrder, event.Timezone)
+			s = span{event.StartsAt, to}
+		}
+		if event.StartsAt.Before(s.from) {
+			s.from = event.StartsAt
+		}
+		if to.After(s.to) {
+			s.to = to
+		}
+		zones[event.Timezone] = s
+	}
+	for _, tz := range zoneOrder {
+		loc, err := utils.LoadLocation(tz)
+		if err != nil {
+			continue
+		}
+		w.TimeZone(loc, zones[tz].from, zones[tz].to)
+	}
+
+	stamp := time.Now()
+	for _, event := range events {
+		loc := eventLocation(event)
+		set, err := loadRecurrence(event)
+		if err != nil {
+			return "", fmt.Errorf("event %d: %w", event.ID, err)
+		}
+		start := event.StartsAt.In(loc)
+		writeVEvent(&w, event, newOccurrence(event, start, nil), nil, set, nil, stamp)
+
+		if !event.IsRecurring() {
+			continue
+		}
+		// Occurrences that differ from the series
+		exceptions := map[int64]*models.EventOccurrence{}
+		for i := range overrides[event.ID] {
+			exceptions[overrides[event.ID][i].OccurrenceStart.Unix()] = &overrides[event.ID][i]
+		}
+		for key := range responses[event.ID] {
+			if _, ok := exceptions[key]; !ok {
+				exceptions[key] = nil
+			}
+		}
+		keys := make([]int64, 0, len(exceptions))
+		for key := range exceptions {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, key := range keys {
+			override := exceptions[key]
+			occStart := time.Unix(key, 0).In(loc)
+			if !set.includes(occStart) {
+				continue
+			}
+			writeVEvent(&w, event, newOccurrence(event, occStart, override), &occStart, set, responses[event.ID][key], stamp)
+		}
+	}
+
+	w.End("VCALENDAR")
+	return w.String(), nil
+}
+
+// writeVEvent writes an event, or one of its occurrences when recurrenceID
+// is set. statuses holds per-occurrence answers that replace the series status.
+func writeVEvent(w *utils.ICalWriter, event models.Event, occ occurrence, recurrenceID *time.Time, set recurrenceSet, statuses map[uint]string, stamp time.Time) {
+	loc := eventLocation(event)
+	w.Begin("VEVENT")
+	w.Prop("UID", icalUID(event))
+	w.Prop("DTSTAMP", utils.FormatICalUTC(stamp))
+	w.Prop("CREATED", utils.FormatICalUTC(event.CreatedAt))
+	if recurrenceID != nil {
+		w.Time("RECURRENCE-ID", recurrenceID.In(loc))
+	}
+	w.Time("DTSTART", occ.StartsAt.In(loc))
+	w.Time("DTEND", occ.EndsAt.In(loc))
+	w.Text("SUMMARY", occ.Title)
+	if occ.Description != "" {
+		w.Text("DESCRIPTION", occ.Description)
+	}
+	w.Text("LOCATION", occ.Location)
+	if recurrenceID == nil {
+		if event.RRule != "" {
+			w.Prop("RRULE", event.RRule)
+		}
+		w.Times("EXDATE", inLocation(set.exdates, loc))
+		w.Times("RDATE", inLocation(set.rdates, loc))
+	}
+
+	if event.Organizer.ID != 0 {
+		w.Prop("ORGANIZER;CN="+utils.QuoteICalParam(event.Organizer.Name), "mailto:"+event.Organizer.Email)
+	}
+	for _, att := range event.Attendees {
+		status := att.Status
+		if s, ok := statuses[att.UserID]; ok {
+			status = s
+		}
+		role := "REQ-PARTICIPANT"
+		if att.Role == "organizer" {
+			role = "CHAIR"
+		}
+		params := "ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=" + role + ";PARTSTAT=" + icalPartstats[status]
+		if status == "pending" {
+			params += ";RSVP=TRUE"
+		}
+		params += ";CN=" + utils.QuoteICalParam(att.User.Name)
+		w.Prop(params, "mailto:"+att.User.Email)
+	}
+	w.End("VEVENT")
+}
+
+// inLocation converts instants to loc.
+func inLocation(times []time.Time, loc *time.Location) []time.Time {
+	result := make([]time.Time, len(times))
+	for i, t := range times {
+		result[i] = t.In(loc)
+	}
+	return result
+}
+
+// writeCalendar sends an iCalendar document.
+func writeCalendar(c *gin.Context, body, filename string) {
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(body))
+}
+
+// GetEventICS exports an event as an iCalendar file (event members only).
+func (s *Service) GetEventICS(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	eventID, ok := idParam(c, "id", "event")
+	if !ok || !s.available(c) {
+		return
+	}
+
+	event, err := s.store.Events().FindForCalendar(eventID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "event not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return
+	}
+
+	hasAccess := event.CreatedBy == userID
+	for _, att := range event.Attendees {
+		if att.UserID == userID {
+			hasAccess = true
+			break
+		}
+	}
+	if !hasAccess {
+		utils.JSONError(c, http.StatusForbidden, "you are not authorized to view this event")
+		return
+	}
+
+	body, err := renderCalendar(s.store, []models.Event{event}, event.Title)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to export event: "+err.Error())
+		return
+	}
+	writeCalendar(c, body, fmt.Sprintf("event-%d.ics", event.ID))
+}
+
+// GetCalendarFeedStatus reports whether the user has a subscription feed.
+func (s *Service) GetCalendarFeedStatus(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	feed, err := s.store.CalendarFeeds().FindByUser(userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch calendar feed: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":    true,
+		"createdAt":  feed.CreatedAt,
+		"lastUsedAt": feed.LastUsedAt,
+	})
+}
+
+// CreateCalendarFeed creates the user's secret subscription feed URL,
+// replacing (and so revoking) any previous one. The URL is only shown once.
+func (s *Service) CreateCalendarFeed(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	token, err := utils.GenerateToken(feedTokenBytes)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate feed token")
+		return
+	}
+
+	feed := models.CalendarFeed{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.CalendarFeeds().Upsert(&feed); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create calendar feed: "+err.Error())
+		return
+	}
+
+	url := publicBaseURL(c) + "/api/calendar/feeds/" + token + ".ics"
+	webcal := url
+	if i := strings.Index(url, "://"); i >= 0 {
+		webcal = "webcal" + url[i:]
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "calendar feed created. Keep the URL secret; create a new one to revoke it",
+		"url":       url,
+		"webcalUrl": webcal,
+		"createdAt": feed.CreatedAt,
+	})
+}
+
+// DeleteCalendarFeed revokes the user's subscription feed URL.
+func (s *Service) DeleteCalendarFeed(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	deleted, err := s.store.CalendarFeeds().Delete(userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to revoke calendar feed: "+err.Error())
+		return
+	}
+	if !deleted {
+		utils.JSONError(c, http.StatusNotFound, "calendar feed not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "calendar feed revoked successfully",
+	})
+}
+
+// GetCalendarFeed serves all events a user organizes or is invited to as an
+// iCalendar feed. It is public: the secret token in the URL authenticates
+// the request, so calendar clients can subscribe without a JWT.
+func (s *Service) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		utils.JSONError(c, http.StatusNotFound, "calendar feed not found")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	feed, err := s.store.CalendarFeeds().FindByHash(utils.HashToken(token))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "calendar feed not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch calendar feed: "+err.Error())
+		return
+	}
+
+	user, err := s.store.Users().FindByID(feed.UserID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch user: "+err.Error())
+		return
+	}
+
+	events, err := s.store.Events().ListVisibleTo(user.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch events: "+err.Error())
+		return
+	}
+
+	body, err := renderCalendar(s.store, events, "Event Planner - "+user.Name)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to export events: "+err.Error())
+		return
+	}
+
+	s.store.CalendarFeeds().Touch(feed.UserID, time.Now())
+
+	writeCalendar(c, body, "event-planner.ics")
+}
@@ -1,15 +1,18 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
-	"event_planner_backend/config"
 	"event_planner_backend/middleware"
 	"event_planner_backend/models"
+	"event_planner_backend/repository"
 	"event_planner_backend/utils"
 )
 
@@ -18,8 +21,42 @@ type CreateEventRequest struct {
 	Title       string `json:"title" binding:"required,min=1,max=255"`
 	Description string `json:"description"`
 	Location    string `json:"location" binding:"required,min=1,max=255"`
-	EventDate   string `json:"eventDate" binding:"required"` // Format: "2006-01-02"
-	EventTime   string `json:"eventTime" binding:"required"` // Format: "15:04:05" or "15:04"
+	StartsAt    string `json:"startsAt"`                                  // RFC 3339, or "2006-01-02T15:04" local to timezone
+	EndsAt      string `json:"endsAt"`                                    // Same formats as startsAt; optional
+	Duration    int    `json:"durationMinutes" binding:"omitempty,min=1"` // Used when endsAt is empty (default: 60)
+	Timezone    string `json:"timezone"`                                  // IANA name, e.g. "Europe/Berlin" (default: "UTC")
+	Capacity    *uint  `json:"capacity" binding:"omitempty,min=1"`        // Maximum attendees going; omit for no limit
+
+	// Recurrence, see RFC 5545
+	RRule   string   `json:"rrule" binding:"max=500"` // e.g. "FREQ=WEEKLY;BYDAY=MO"
+	ExDates []string `json:"exdates"`                 // Occurrence starts to skip, same formats as startsAt
+	RDates  []string `json:"rdates"`                  // Additional occurrence starts
+
+	// Legacy schedule fields, used when startsAt is empty
+	EventDate string `json:"eventDate"` // Format: "2006-01-02"
+	EventTime string `json:"eventTime"` // Format: "15:04:05" or "15:04"
+}
+
+// UpdateEventRequest represents the payload for partially updating an event.
+// Omitted fields are left untouched.
+type UpdateEventRequest struct {
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+	Location    *string `json:"location" binding:"omitempty,min=1,max=255"`
+	StartsAt    *string `json:"startsAt"`
+	EndsAt      *string `json:"endsAt"`
+	Duration    *int    `json:"durationMinutes" binding:"omitempty,min=1"`
+	Timezone    *string `json:"timezone"` // Keeps the local wall-clock times unless startsAt/endsAt are given
+	Capacity    *uint   `json:"capacity"` // 0 removes the limit
+
+	// Recurrence; an empty rrule makes the event a one-off
+	RRule   *string   `json:"rrule" binding:"omitempty,max=500"`
+	ExDates *[]string `json:"exdates"`
+	RDates  *[]string `json:"rdates"`
+
+	// Legacy schedule fields, replacing the date or time of the start
+	EventDate *string `json:"eventDate"`
+	EventTime *string `json:"eventTime"`
 }
 
 // InviteUserRequest represents the payload for inviting a user to an event.
@@ -29,7 +66,7 @@ type InviteUserRequest struct {
 }
 
 // CreateEvent creates a new event and marks the creator as organizer.
-func CreateEvent(c *gin.Context) {
+func (s *Service) CreateEvent(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
@@ -42,21 +79,76 @@ func CreateEvent(c *gin.Context) {
 		return
 	}
 
-	// Parse event date
-	eventDate, err := time.Parse("2006-01-02", req.EventDate)
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	// Resolve the event time zone
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := utils.LoadLocation(timezone)
 	if err != nil {
-		utils.JSONError(c, http.StatusBadRequest, "invalid eventDate format. Use YYYY-MM-DD: "+err.Error())
+		utils.JSONError(c, http.StatusBadRequest, "invalid timezone. Use an IANA name such as Europe/Berlin: "+err.Error())
 		return
 	}
 
-	// Parse event time (support both HH:MM:SS and HH:MM formats)
-	eventTime := req.EventTime
-	if len(eventTime) == 5 && eventTime[2] == ':' {
-		eventTime = eventTime + ":00" // Add seconds if missing
+	// Parse the start, falling back to the legacy date/time fields
+	var startsAt time.Time
+	switch {
+	case req.StartsAt != "":
+		startsAt, err = utils.ParseInstant(req.StartsAt, loc)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid startsAt: "+err.Error())
+			return
+		}
+	case req.EventDate != "" && req.EventTime != "":
+		startsAt, err = parseLegacyStart(req.EventDate, req.EventTime, loc)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		utils.JSONError(c, http.StatusBadRequest, "startsAt is required")
+		return
+	}
+
+	// Parse the end, or derive it from the duration
+	endsAt := startsAt.Add(defaultEventDuration)
+	if req.EndsAt != "" {
+		endsAt, err = utils.ParseInstant(req.EndsAt, loc)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid endsAt: "+err.Error())
+			return
+		}
+	} else if req.Duration > 0 {
+		endsAt = startsAt.Add(time.Duration(req.Duration) * time.Minute)
+	}
+	if !endsAt.After(startsAt) {
+		utils.JSONError(c, http.StatusBadRequest, "endsAt must be after startsAt")
+		return
 	}
-	_, err = time.Parse("15:04:05", eventTime)
+
+	// Parse the recurrence
+	var rrule string
+	if value := strings.TrimSpace(req.RRule); value != "" {
+		rule, err := utils.ParseRRule(value, loc)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid rrule: "+err.Error())
+			return
+		}
+		rrule = rule.String()
+	}
+	exdates, err := resolveDateList("", &req.ExDates, loc, false, nil)
 	if err != nil {
-		utils.JSONError(c, http.StatusBadRequest, "invalid eventTime format. Use HH:MM:SS or HH:MM: "+err.Error())
+		utils.JSONError(c, http.StatusBadRequest, "invalid exdates: "+err.Error())
+		return
+	}
+	rdates, err := resolveDateList("", &req.RDates, loc, false, nil)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid rdates: "+err.Error())
 		return
 	}
 
@@ -64,125 +156,602 @@ func CreateEvent(c *gin.Context) {
 		Title:       req.Title,
 		Description: req.Description,
 		Location:    req.Location,
-		EventDate:   eventDate,
-		EventTime:   eventTime,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		Timezone:    timezone,
+		RRule:       rrule,
+		ExDates:     exdates,
+		RDates:      rdates,
+		Capacity:    req.Capacity,
 		CreatedBy:   userID,
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	if !s.available(c) {
 		return
 	}
 
-	// Start transaction
-	tx := config.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Create event
-	if err := tx.Create(event).Error; err != nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusInternalServerError, "failed to create event: "+err.Error())
+	if err := s.store.Transaction(func(store repository.Store) error {
+		return createEvent(store, event)
+	}); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	response := formatEventResponse(*event, viewerLoc)
+	delete(response, "attendees")
+	c.JSON(http.StatusCreated, response)
+}
+
+// createEvent inserts an event and marks its creator as organizer. It must
+// run in a transaction.
+func createEvent(store repository.Store, event *models.Event) error {
+	if err := store.Events().Create(event); err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
 	// Mark creator as organizer
 	attendee := &models.EventAttendee{
 		EventID:   event.ID,
-		UserID:    userID,
+		UserID:    event.CreatedBy,
 		Role:      "organizer",
 		Status:    "going", // Creator is automatically going
 		InvitedAt: time.Now(),
 	}
+	if err := store.Attendees().Create(attendee); err != nil {
+		return fmt.Errorf("failed to create attendee record: %w", err)
+	}
+	return nil
+}
+
+// defaultEventDuration is used when an event is created without an end.
+const defaultEventDuration = time.Hour
+
+// parseEventDate parses an event date in YYYY-MM-DD format.
+func parseEventDate(value string) (time.Time, error) {
+	return time.Parse("2006-01-02", value)
+}
+
+// normalizeEventTime validates an event time and returns it as HH:MM:SS.
+func normalizeEventTime(value string) (string, error) {
+	eventTime := value
+	if len(eventTime) == 5 && eventTime[2] == ':' {
+		eventTime = eventTime + ":00" // Add seconds if missing
+	}
+	if _, err := time.Parse("15:04:05", eventTime); err != nil {
+		return "", err
+	}
+	return eventTime, nil
+}
+
+// parseLegacyStart combines the legacy eventDate/eventTime fields into an
+// instant, reading them as wall-clock time in loc.
+func parseLegacyStart(eventDate, eventTime string, loc *time.Location) (time.Time, error) {
+	date, err := parseEventDate(eventDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid eventDate format. Use YYYY-MM-DD: %w", err)
+	}
+	clock, err := normalizeEventTime(eventTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid eventTime format. Use HH:MM:SS or HH:MM: %w", err)
+	}
+	t, _ := time.Parse("15:04:05", clock)
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+}
+
+// withWallClock returns the instant showing the same wall-clock time as t in loc.
+func withWallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// shiftWallClock moves t by the wall-clock difference between from and to:
+// the same number of calendar days and the same change of time of day, read
+// in the location of to. Dates tied to a recurring event keep their position
+// relative to its start this way, also across DST changes.
+func shiftWallClock(t, from, to time.Time) time.Time {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(toDay.Sub(fromDay).Hours() / 24)
+	seconds := (to.Hour()-from.Hour())*3600 + (to.Minute()-from.Minute())*60 + to.Second() - from.Second()
+	local := t.In(from.Location())
+	return time.Date(local.Year(), local.Month(), local.Day()+days, local.Hour(), local.Minute(), local.Second()+seconds, 0, to.Location())
+}
+
+// resolveDateList returns the stored form of an exdates/rdates list: the
+// parsed values when given, otherwise the current list, shifted when the
+// event start moved.
+func resolveDateList(current string, values *[]string, loc *time.Location, moved bool, shift func(time.Time) time.Time) (string, error) {
+	var times []time.Time
+	if values != nil {
+		for _, value := range *values {
+			t, err := utils.ParseInstant(value, loc)
+			if err != nil {
+				return "", fmt.Errorf("%q: %w", value, err)
+			}
+			times = append(times, t)
+		}
+	} else {
+		if !moved || current == "" {
+			return current, nil
+		}
+		parsed, err := utils.ParseICalDateList(current, time.UTC)
+		if err != nil {
+			return "", err
+		}
+		for _, t := range parsed {
+			times = append(times, shift(t))
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return utils.FormatICalDateList(times), nil
+}
+
+// eventLocation returns the time zone of an event, falling back to UTC.
+func eventLocation(event models.Event) *time.Location {
+	loc, err := utils.LoadLocation(event.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// viewerLocation returns the time zone the client wants times rendered in,
+// taken from the `tz` query parameter or the X-Timezone header. A nil
+// location means each event is rendered in its own time zone. It writes a
+// 400 response and returns ok=false for unknown zones.
+func viewerLocation(c *gin.Context) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" {
+		name = c.GetHeader("X-Timezone")
+	}
+	if name == "" {
+		return nil, true
+	}
+	loc, err := utils.LoadLocation(name)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid tz. Use an IANA name such as Europe/Berlin: "+err.Error())
+		return nil, false
+	}
+	return loc, true
+}
+
+// UpdateEvent partially updates an event (organizer only) and records each
+// modified field in the event's change history.
+func (s *Service) UpdateEvent(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
 
-	if err := tx.Create(attendee).Error; err != nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusInternalServerError, "failed to create attendee record: "+err.Error())
+	var req UpdateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
 		return
 	}
 
-	tx.Commit()
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"id":          event.ID,
-		"title":       event.Title,
-		"description": event.Description,
-		"location":    event.Location,
-		"eventDate":   event.EventDate.Format("2006-01-02"),
-		"eventTime":   event.EventTime,
-		"createdBy":   event.CreatedBy,
-		"createdAt":   event.CreatedAt,
+	if !s.available(c) {
+		return
+	}
+
+	// Use transaction so the update and its change records land together
+	var event models.Event
+	var changes []models.EventChange
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can update events"); !ok {
+			return errResponded
+		}
+		if changes, ok = applyEventUpdate(c, store, event, userID, req); !ok {
+			return errResponded
+		}
+		return nil
 	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update event: "+err.Error())
+		return
+	}
+
+	updated, err := s.store.Events().FindWithDetails(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return
+	}
+
+	response := formatEventResponse(updated, viewerLoc)
+	response["changes"] = formatEventChanges(changes)
+	c.JSON(http.StatusOK, response)
+}
+
+// applyEventUpdate applies req to an event locked in the transaction of store
+// and records the changes. When the start of a recurring event moves, its
+// excluded and extra dates, occurrence overrides and per-occurrence responses
+// move with it. It writes the error response and returns ok=false on
+// failure; the caller rolls back.
+func applyEventUpdate(c *gin.Context, store repository.Store, event models.Event, userID uint, req UpdateEventRequest) ([]models.EventChange, bool) {
+	updates := map[string]interface{}{}
+	var changes []models.EventChange
+	track := func(field, column, oldValue, newValue string, value interface{}) {
+		if oldValue == newValue {
+			return
+		}
+		updates[column] = value
+		changes = append(changes, models.EventChange{
+			EventID:   event.ID,
+			ChangedBy: userID,
+			Field:     field,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+			ChangedAt: time.Now(),
+		})
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			utils.JSONError(c, http.StatusBadRequest, "title cannot be empty")
+			return nil, false
+		}
+		track("title", "title", event.Title, title, title)
+	}
+	if req.Description != nil {
+		track("description", "description", event.Description, *req.Description, *req.Description)
+	}
+	if req.Location != nil {
+		location := strings.TrimSpace(*req.Location)
+		if location == "" {
+			utils.JSONError(c, http.StatusBadRequest, "location cannot be empty")
+			return nil, false
+		}
+		track("location", "location", event.Location, location, location)
+	}
+	if req.Capacity != nil {
+		var capacity *uint
+		if *req.Capacity > 0 {
+			capacity = req.Capacity
+		}
+		track("capacity", "capacity", formatCapacity(event.Capacity), formatCapacity(capacity), capacity)
+	}
+
+	// Resolve the new schedule, starting from the current one
+	oldLoc := eventLocation(event)
+	loc, timezone := oldLoc, event.Timezone
+	startsAt, endsAt := event.StartsAt.In(oldLoc), event.EndsAt.In(oldLoc)
+	var err error
+	if req.Timezone != nil {
+		timezone = strings.TrimSpace(*req.Timezone)
+		if loc, err = utils.LoadLocation(timezone); err != nil || timezone == "" {
+			utils.JSONError(c, http.StatusBadRequest, "invalid timezone. Use an IANA name such as Europe/Berlin")
+			return nil, false
+		}
+		startsAt, endsAt = withWallClock(startsAt, loc), withWallClock(endsAt, loc)
+	}
+	duration := endsAt.Sub(startsAt)
+
+	if req.StartsAt != nil {
+		if startsAt, err = utils.ParseInstant(*req.StartsAt, loc); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid startsAt: "+err.Error())
+			return nil, false
+		}
+	} else if req.EventDate != nil || req.EventTime != nil {
+		local := startsAt.In(loc)
+		eventDate, eventTime := local.Format("2006-01-02"), local.Format("15:04:05")
+		if req.EventDate != nil {
+			eventDate = *req.EventDate
+		}
+		if req.EventTime != nil {
+			eventTime = *req.EventTime
+		}
+		if startsAt, err = parseLegacyStart(eventDate, eventTime, loc); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+	}
+
+	switch {
+	case req.EndsAt != nil:
+		if endsAt, err = utils.ParseInstant(*req.EndsAt, loc); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid endsAt: "+err.Error())
+			return nil, false
+		}
+	case req.Duration != nil:
+		endsAt = startsAt.Add(time.Duration(*req.Duration) * time.Minute)
+	default:
+		// Moving the start keeps the duration
+		endsAt = startsAt.Add(duration)
+	}
+	if !endsAt.After(startsAt) {
+		utils.JSONError(c, http.StatusBadRequest, "endsAt must be after startsAt")
+		return nil, false
+	}
+
+	track("timezone", "timezone", event.Timezone, timezone, timezone)
+	if !startsAt.Equal(event.StartsAt) {
+		track("startsAt", "starts_at", event.StartsAt.In(oldLoc).Format(time.RFC3339), startsAt.In(loc).Format(time.RFC3339), startsAt)
+	}
+	if !endsAt.Equal(event.EndsAt) {
+		track("endsAt", "ends_at", event.EndsAt.In(oldLoc).Format(time.RFC3339), endsAt.In(loc).Format(time.RFC3339), endsAt)
+	}
+
+	// Resolve the recurrence. Unless replaced, dates tied to the old start
+	// keep their position relative to it.
+	moved := !startsAt.Equal(event.StartsAt)
+	oldStart, newStart := event.StartsAt.In(oldLoc), startsAt.In(loc)
+	shift := func(t time.Time) time.Time { return shiftWallClock(t, oldStart, newStart) }
+
+	rrule := event.RRule
+	if req.RRule != nil {
+		rrule = ""
+		if value := strings.TrimSpace(*req.RRule); value != "" {
+			rule, err := utils.ParseRRule(value, loc)
+			if err != nil {
+				utils.JSONError(c, http.StatusBadRequest, "invalid rrule: "+err.Error())
+				return nil, false
+			}
+			rrule = rule.String()
+		}
+	} else if moved && rrule != "" {
+		if rule, err := utils.ParseRRule(rrule, oldLoc); err == nil && !rule.Until.IsZero() {
+			rule.Until = shift(rule.Until)
+			rrule = rule.String()
+		}
+	}
+	exdates, err := resolveDateList(event.ExDates, req.ExDates, loc, moved, shift)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid exdates: "+err.Error())
+		return nil, false
+	}
+	rdates, err := resolveDateList(event.RDates, req.RDates, loc, moved, shift)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid rdates: "+err.Error())
+		return nil, false
+	}
+	track("rrule", "rrule", event.RRule, rrule, rrule)
+	track("exdates", "exdates", event.ExDates, exdates, exdates)
+	track("rdates", "rdates", event.RDates, rdates, rdates)
+
+	if len(changes) == 0 {
+		return changes, true
+	}
+	if err := store.Events().Update(event.ID, updates); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update event: "+err.Error())
+		return nil, false
+	}
+	if err := store.Changes().Create(changes); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to record event changes: "+err.Error())
+		return nil, false
+	}
+	if moved && event.IsRecurring() {
+		if err := shiftOccurrenceRecords(store, event.ID, shift); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return nil, false
+		}
+	}
+	if _, ok := updates["capacity"]; ok {
+		// A larger capacity makes room for the waitlist
+		if event, err = store.Events().FindByID(event.ID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+			return nil, false
+		}
+		if _, err := promoteWaitlisted(store, event); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return nil, false
+		}
+	}
+	return changes, true
+}
+
+// formatCapacity describes an event capacity in the change history.
+func formatCapacity(capacity *uint) string {
+	if capacity == nil {
+		return "unlimited"
+	}
+	return strconv.FormatUint(uint64(*capacity), 10)
+}
+
+// GetEventChanges returns the change history of an event (event members only).
+func (s *Service) GetEventChanges(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, _, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+
+	changes, err := s.store.Changes().ListByEvent(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event changes: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":    event.ID,
+		"eventTitle": event.Title,
+		"changes":    formatEventChanges(changes),
+	})
+}
+
+// formatEventChanges formats change records for JSON response.
+func formatEventChanges(changes []models.EventChange) []gin.H {
+	result := make([]gin.H, len(changes))
+	for i, change := range changes {
+		result[i] = gin.H{
+			"id":        change.ID,
+			"field":     change.Field,
+			"oldValue":  change.OldValue,
+			"newValue":  change.NewValue,
+			"summary":   describeEventChange(change),
+			"changedBy": change.ChangedBy,
+			"changedAt": change.ChangedAt,
+		}
+		if change.User.ID != 0 {
+			result[i]["changedByName"] = change.User.Name
+		}
+		if change.Occurrence != nil {
+			result[i]["occurrenceId"] = occurrenceID(*change.Occurrence)
+		}
+	}
+	return result
+}
+
+// describeEventChange returns a human readable summary of a change,
+// e.g. "moved from 18:00 to 19:30".
+func describeEventChange(change models.EventChange) string {
+	switch change.Field {
+	case "startsAt", "endsAt":
+		prefix := "moved from "
+		if change.Field == "endsAt" {
+			prefix = "end moved from "
+		}
+		oldTime, oldErr := time.Parse(time.RFC3339, change.OldValue)
+		newTime, newErr := time.Parse(time.RFC3339, change.NewValue)
+		if oldErr != nil || newErr != nil {
+			return prefix + change.OldValue + " to " + change.NewValue
+		}
+		layout := "2006-01-02 15:04"
+		if oldTime.Format("2006-01-02") == newTime.Format("2006-01-02") {
+			layout = "15:04"
+		}
+		if oldTime.Format(layout) == newTime.Format(layout) {
+			// Same wall-clock time in another zone
+			layout += " -07:00"
+		}
+		return prefix + oldTime.Format(layout) + " to " + newTime.Format(layout)
+	case "eventDate":
+		return "moved from " + change.OldValue + " to " + change.NewValue
+	case "eventTime":
+		return "moved from " + strings.TrimSuffix(change.OldValue, ":00") + " to " + strings.TrimSuffix(change.NewValue, ":00")
+	case "description":
+		return "description updated"
+	case "rrule":
+		if change.NewValue == "" {
+			return "recurrence removed"
+		}
+		return "recurrence set to " + change.NewValue
+	case "exdates":
+		return "excluded dates updated"
+	case "rdates":
+		return "additional dates updated"
+	case "occurrence":
+		return "occurrence " + change.NewValue
+	case "owner":
+		return "ownership transferred from " + change.OldValue + " to " + change.NewValue
+	default:
+		return change.Field + " changed from \"" + change.OldValue + "\" to \"" + change.NewValue + "\""
+	}
 }
 
 // GetMyOrganizedEvents returns all events organized by the authenticated user.
-func GetMyOrganizedEvents(c *gin.Context) {
+// With `from`/`to` query parameters it returns the occurrences in that window
+// instead, recurring events expanded.
+func (s *Service) GetMyOrganizedEvents(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
 		return
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	if !s.available(c) {
 		return
 	}
 
-	var events []models.Event
-	if err := config.DB.Where("created_by = ?", userID).
-		Preload("Attendees", func(db *gorm.DB) *gorm.DB {
-			return db.Preload("User")
-		}).
-		Order("created_at DESC").
-		Find(&events).Error; err != nil {
+	events, err := s.store.Events().ListOrganizedBy(userID)
+	if err != nil {
 		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch events: "+err.Error())
 		return
 	}
 
+	// Expand occurrences when a date window is requested
+	if hasOccurrenceWindow(c) {
+		from, to, ok := occurrenceWindow(c, viewerLoc)
+		if !ok {
+			return
+		}
+		response, err := expandEventOccurrences(s.store, events, from, to, viewerLoc, nil)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to expand occurrences: "+err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	// Format response
 	response := make([]gin.H, len(events))
 	for i, event := range events {
-		response[i] = formatEventResponse(event)
+		response[i] = formatEventResponse(event, viewerLoc)
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
 // GetMyInvitedEvents returns all events the authenticated user is invited to (as attendee or organizer).
-func GetMyInvitedEvents(c *gin.Context) {
+// Like GetMyOrganizedEvents, it expands occurrences within `from`/`to` when given.
+func (s *Service) GetMyInvitedEvents(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
 		return
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	if !s.available(c) {
 		return
 	}
 
-	var attendees []models.EventAttendee
-	if err := config.DB.Where("user_id = ?", userID).
-		Preload("Event", func(db *gorm.DB) *gorm.DB {
-			return db.Preload("Organizer").Preload("Attendees", func(db *gorm.DB) *gorm.DB {
-				return db.Preload("User")
-			})
-		}).
-		Preload("User").
-		Order("invited_at DESC").
-		Find(&attendees).Error; err != nil {
+	attendees, err := s.store.Attendees().ListByUser(userID)
+	if err != nil {
 		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch events: "+err.Error())
 		return
 	}
 
+	// Expand occurrences when a date window is requested, with the
+	// response to each occurrence as status
+	if hasOccurrenceWindow(c) {
+		from, to, ok := occurrenceWindow(c, viewerLoc)
+		if !ok {
+			return
+		}
+		events := make([]models.Event, len(attendees))
+		membership := make(map[uint]models.EventAttendee, len(attendees))
+		for i, attendee := range attendees {
+			events[i] = attendee.Event
+			membership[attendee.EventID] = attendee
+		}
+		response, err := expandEventOccurrences(s.store, events, from, to, viewerLoc, membership)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to expand occurrences: "+err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	// Format response with role and status
 	response := make([]gin.H, len(attendees))
 	for i, attendee := range attendees {
 		event := attendee.Event
-		response[i] = formatEventResponse(event)
+		response[i] = formatEventResponse(event, viewerLoc)
 		response[i]["role"] = attendee.Role
 		response[i]["status"] = attendee.Status
 		response[i]["invitedAt"] = attendee.InvitedAt
@@ -191,20 +760,15 @@ func GetMyInvitedEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// InviteUserToEvent invites a user to an event.
-func InviteUserToEvent(c *gin.Context) {
+// InviteUserToEvent invites a user to an event. Emails without an account get
+// a pending invitation with a signed invite link instead.
+func (s *Service) InviteUserToEvent(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
 		return
 	}
 
-	eventID := c.Param("id")
-	if eventID == "" {
-		utils.JSONError(c, http.StatusBadRequest, "event ID required")
-		return
-	}
-
 	var req InviteUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
@@ -217,64 +781,112 @@ func InviteUserToEvent(c *gin.Context) {
 		role = "attendee"
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	if !s.available(c) {
 		return
 	}
 
-	// Use transaction for atomicity
-	tx := config.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Verify event exists and user is organizer in one query
+	// Find user by email and invite them, or the email when it has no account
+	email := strings.ToLower(strings.TrimSpace(req.Email))
 	var event models.Event
-	if err := tx.Where("event_id = ? AND created_by = ?", eventID, userID).
-		First(&event).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Check if user is organizer via attendees table
-			var attendee models.EventAttendee
-			if err := tx.Where("event_id = ? AND user_id = ? AND role = ?", eventID, userID, "organizer").
-				First(&attendee).Error; err != nil {
-				tx.Rollback()
-				utils.JSONError(c, http.StatusForbidden, "only organizers can invite users")
-				return
-			}
-			// Get event details
-			if err := tx.First(&event, eventID).Error; err != nil {
-				tx.Rollback()
-				utils.JSONError(c, http.StatusNotFound, "event not found")
-				return
+	var outcome inviteOutcome
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can invite users"); !ok {
+			return errResponded
+		}
+		var err error
+		if outcome, err = inviteToEvent(store, event, email, "", role, userID); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return errResponded
+		}
+		if outcome.Result == inviteAlreadyInvited {
+			if outcome.User == nil {
+				utils.JSONError(c, http.StatusConflict, "email is already invited to this event")
+				return errResponded
 			}
-		} else {
-			tx.Rollback()
-			utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
-			return
+			utils.JSONError(c, http.StatusConflict, "user is already invited to this event")
+			return errResponded
 		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create invitation: "+err.Error())
+		return
 	}
 
-	// Find user by email
-	var invitedUser models.User
-	if err := tx.Where("email = ?", req.Email).First(&invitedUser).Error; err != nil {
-		tx.Rollback()
-		if err == gorm.ErrRecordNotFound {
-			utils.JSONError(c, http.StatusNotFound, "user not found")
-			return
-		}
-		utils.JSONError(c, http.StatusInternalServerError, "failed to find user: "+err.Error())
+	if outcome.Result == inviteUnknownUser {
+		c.JSON(http.StatusCreated, gin.H{
+			"message":      "invitation created. The user is added to the event when they sign up and verify their email",
+			"eventId":      event.ID,
+			"email":        email,
+			"role":         role,
+			"pending":      true,
+			"invitationId": outcome.Invitation.ID,
+			"inviteToken":  outcome.Token,
+			"inviteUrl":    invitationURL(c, outcome.Token),
+			"expiresAt":    outcome.Invitation.ExpiresAt,
+		})
 		return
 	}
 
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "user invited successfully",
+		"eventId": event.ID,
+		"userId":  outcome.User.ID,
+		"email":   outcome.User.Email,
+		"role":    role,
+	})
+}
+
+// Outcomes of inviting an email to an event.
+const (
+	inviteInvited        = "invited"
+	inviteAlreadyInvited = "already_invited"
+	inviteUnknownUser    = "unknown_user" // No verified account: a pending invitation was created
+	inviteInvalidEmail   = "invalid_email"
+)
+
+// inviteOutcome is the result of inviteToEvent. User is set when the email
+// has an account, Invitation and Token when it has none.
+type inviteOutcome struct {
+	Result     string
+	User       *models.User
+	Invitation *models.EventInvitation
+	Token      string
+}
+
+// inviteToEvent invites the user with the given email to event, or
+// creates a pending invitation when no account uses the email. name is kept
+// for invitations of emails without an account.
+//
+// Accounts whose email is not verified get a pending invitation too: it is
+// attached once the email is verified, so that an account typed with someone
+// else's email does not receive their invitations.
+func inviteToEvent(store repository.Store, event models.Event, email, name, role string, invitedBy uint) (inviteOutcome, error) {
+	invitedUser, err := store.Users().FindByEmail(email)
+	if err != nil && err != repository.ErrNotFound {
+		return inviteOutcome{}, fmt.Errorf("failed to find user: %w", err)
+	}
+	if err == repository.ErrNotFound || invitedUser.EmailVerifiedAt == nil {
+		// No verified account: invite the email, attached when it is verified
+		inv, token, ok, err := inviteEmail(store, event, email, name, role, invitedBy)
+		if err != nil {
+			return inviteOutcome{}, fmt.Errorf("failed to create invitation: %w", err)
+		}
+		if !ok {
+			return inviteOutcome{Result: inviteAlreadyInvited, Invitation: &inv}, nil
+		}
+		return inviteOutcome{Result: inviteUnknownUser, Invitation: &inv, Token: token}, nil
+	}
+
 	// Check if user is already invited
-	var existing models.EventAttendee
-	if err := tx.Where("event_id = ? AND user_id = ?", eventID, invitedUser.ID).
-		First(&existing).Error; err == nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusConflict, "user is already invited to this event")
-		return
+	if _, err := store.Attendees().Find(event.ID, invitedUser.ID); err == nil {
+		return inviteOutcome{Result: inviteAlreadyInvited, User: &invitedUser}, nil
+	} else if err != repository.ErrNotFound {
+		return inviteOutcome{}, fmt.Errorf("failed to check invitation: %w", err)
 	}
 
 	// Create invitation
@@ -285,117 +897,73 @@ func InviteUserToEvent(c *gin.Context) {
 		Status:    "pending",
 		InvitedAt: time.Now(),
 	}
-
-	if err := tx.Create(newAttendee).Error; err != nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusInternalServerError, "failed to create invitation: "+err.Error())
-		return
+	if err := store.Attendees().Create(newAttendee); err != nil {
+		return inviteOutcome{}, fmt.Errorf("failed to create invitation: %w", err)
 	}
-
-	tx.Commit()
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "user invited successfully",
-		"eventId": event.ID,
-		"userId":  invitedUser.ID,
-		"email":   invitedUser.Email,
-		"role":    role,
-	})
+	return inviteOutcome{Result: inviteInvited, User: &invitedUser}, nil
 }
 
 // DeleteEvent deletes an event (only if user is the creator).
-func DeleteEvent(c *gin.Context) {
+func (s *Service) DeleteEvent(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
 		return
 	}
 
-	eventID := c.Param("id")
-	if eventID == "" {
-		utils.JSONError(c, http.StatusBadRequest, "event ID required")
-		return
-	}
-
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	eventID, ok := idParam(c, "id", "event")
+	if !ok || !s.available(c) {
 		return
 	}
 
 	// Use transaction to delete event and related records
-	tx := config.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Verify event exists and user is the creator
-	var event models.Event
-	if err := tx.Where("event_id = ? AND created_by = ?", eventID, userID).First(&event).Error; err != nil {
-		tx.Rollback()
-		if err == gorm.ErrRecordNotFound {
+	err := s.store.Transaction(func(store repository.Store) error {
+		// Verify event exists and user is the creator
+		event, err := store.Events().FindByIDForUpdate(eventID)
+		if err == repository.ErrNotFound || err == nil && event.CreatedBy != userID {
 			utils.JSONError(c, http.StatusNotFound, "event not found or you don't have permission")
-			return
+			return errResponded
 		}
-		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
-		return
-	}
-
-	// Delete attendees first
-	if err := tx.Where("event_id = ?", eventID).Delete(&models.EventAttendee{}).Error; err != nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusInternalServerError, "failed to delete attendees: "+err.Error())
-		return
-	}
-
-	// Delete tasks
-	if err := tx.Where("event_id = ?", eventID).Delete(&models.Task{}).Error; err != nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusInternalServerError, "failed to delete tasks: "+err.Error())
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+			return errResponded
+		}
+		return store.Events().DeleteCascade(event)
+	})
+	if err == errResponded {
 		return
 	}
-
-	// Delete event
-	if err := tx.Delete(&event).Error; err != nil {
-		tx.Rollback()
-		utils.JSONError(c, http.StatusInternalServerError, "failed to delete event: "+err.Error())
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	tx.Commit()
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "event deleted successfully",
 	})
 }
 
 // GetEventDetails returns detailed information about a specific event.
-func GetEventDetails(c *gin.Context) {
+func (s *Service) GetEventDetails(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
 		return
 	}
 
-	eventID := c.Param("id")
-	if eventID == "" {
-		utils.JSONError(c, http.StatusBadRequest, "event ID required")
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
 		return
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	eventID, ok := idParam(c, "id", "event")
+	if !ok || !s.available(c) {
 		return
 	}
 
-	var event models.Event
-	if err := config.DB.Preload("Organizer").
-		Preload("Attendees", func(db *gorm.DB) *gorm.DB {
-			return db.Preload("User")
-		}).
-		First(&event, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	event, err := s.store.Events().FindWithDetails(eventID)
+	if err != nil {
+		if err == repository.ErrNotFound {
 			utils.JSONError(c, http.StatusNotFound, "event not found")
 			return
 		}
@@ -419,7 +987,7 @@ func GetEventDetails(c *gin.Context) {
 		return
 	}
 
-	response := formatEventResponse(event)
+	response := formatEventResponse(event, viewerLoc)
 	response["organizer"] = gin.H{
 		"id":    event.Organizer.ID,
 		"name":  event.Organizer.Name,
@@ -439,11 +1007,41 @@ func GetEventDetails(c *gin.Context) {
 		response["myStatus"] = "going"
 	}
 
+	// Places and waitlist
+	var going, waitlisted int
+	for _, att := range event.Attendees {
+		switch att.Status {
+		case "going":
+			going++
+		case "waitlisted":
+			waitlisted++
+			if att.UserID == userID && att.WaitlistedAt != nil {
+				position := 1
+				for _, other := range event.Attendees {
+					if other.Status == "waitlisted" && other.WaitlistedAt != nil && other.WaitlistedAt.Before(*att.WaitlistedAt) {
+						position++
+					}
+				}
+				response["waitlistPosition"] = position
+			}
+		}
+	}
+	response["goingCount"] = going
+	response["waitlistCount"] = waitlisted
+	if event.Capacity != nil {
+		spotsLeft := int(*event.Capacity) - going
+		if spotsLeft < 0 {
+			spotsLeft = 0
+		}
+		response["spotsLeft"] = spotsLeft
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
-// formatEventResponse formats an event for JSON response.
-func formatEventResponse(event models.Event) gin.H {
+// formatEventResponse formats an event for JSON response. Times are rendered
+// in viewerLoc, or in the event's own time zone when viewerLoc is nil.
+func formatEventResponse(event models.Event, viewerLoc *time.Location) gin.H {
 	attendees := make([]gin.H, len(event.Attendees))
 	for i, att := range event.Attendees {
 		attendees[i] = gin.H{
@@ -456,16 +1054,46 @@ func formatEventResponse(event models.Event) gin.H {
 		}
 	}
 
-	return gin.H{
+	loc := viewerLoc
+	if loc == nil {
+		loc = eventLocation(event)
+	}
+	startsAt, endsAt := event.StartsAt.In(loc), event.EndsAt.In(loc)
+
+	response := gin.H{
 		"id":          event.ID,
 		"title":       event.Title,
 		"description": event.Description,
 		"location":    event.Location,
-		"eventDate":   event.EventDate.Format("2006-01-02"),
-		"eventTime":   event.EventTime,
+		"startsAt":    startsAt.Format(time.RFC3339),
+		"endsAt":      endsAt.Format(time.RFC3339),
+		"timezone":    event.Timezone,
+		"eventDate":   startsAt.Format("2006-01-02"),
+		"eventTime":   startsAt.Format("15:04:05"),
+		"recurring":   event.IsRecurring(),
 		"createdBy":   event.CreatedBy,
 		"createdAt":   event.CreatedAt,
 		"attendees":   attendees,
+		"tags":        tagNames(event.Tags),
+	}
+	if event.Capacity != nil {
+		response["capacity"] = *event.Capacity
+	}
+	if event.IsRecurring() {
+		response["rrule"] = event.RRule
+		response["exdates"] = formatDateList(event.ExDates, loc)
+		response["rdates"] = formatDateList(event.RDates, loc)
+	}
+	return response
+}
+
+// formatDateList renders a stored exdates/rdates list as RFC 3339 times in loc.
+func formatDateList(value string, loc *time.Location) []string {
+	times, _ := utils.ParseICalDateList(value, time.UTC)
+	result := make([]string, len(times))
+	for i, t := range times {
+		result[i] = t.In(loc).Format(time.RFC3339)
 	}
+	return result
 }
 
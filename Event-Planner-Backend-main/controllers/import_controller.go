@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// maxImportSize bounds the size of an uploaded iCalendar file.
+const maxImportSize = 2 << 20
+
+// maxRecurrenceAgeYears bounds how long ago an imported recurring event may
+// start, as every read of a series walks its rule from the first occurrence.
+const maxRecurrenceAgeYears = 10
+
+// importItem is the outcome of importing one event (a VEVENT together with
+// the VEVENTs overriding its occurrences).
+type importItem struct {
+	UID         string     `json:"uid,omitempty"`
+	Title       string     `json:"title"`
+	StartsAt    *time.Time `json:"startsAt,omitempty"`
+	EndsAt      *time.Time `json:"endsAt,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	RRule       string     `json:"rrule,omitempty"`
+	Exceptions  int        `json:"modifiedOccurrences,omitempty"`
+	Action      string     `json:"action"` // create, created, skip or error
+	Reason      string     `json:"reason,omitempty"`
+	EventID     uint       `json:"eventId,omitempty"`
+	Warnings    []string   `json:"warnings,omitempty"`
+	event       *models.Event
+	occurrences []models.EventOccurrence
+}
+
+// ImportEvents imports the events of an iCalendar file, with the uploader as
+// organizer. The file is sent as the multipart field "file" or as the raw
+// request body. With dryRun=true nothing is stored and the response previews
+// what would be created. Events already imported by the user, or exported
+// from here, are recognised by their UID and skipped.
+func (s *Service) ImportEvents(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+64<<10)
+
+	// Floating and all-day times are read in this zone
+	timezone := strings.TrimSpace(c.DefaultQuery("timezone", c.PostForm("timezone")))
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	defaultLoc, err := utils.LoadLocation(timezone)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid timezone. Use an IANA name such as Europe/Berlin: "+err.Error())
+		return
+	}
+
+	dryRun := false
+	if value := c.DefaultQuery("dryRun", c.PostForm("dryRun")); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid dryRun. Use true or false")
+			return
+		}
+	}
+
+	body, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	events, err := utils.ParseICalEvents(strings.NewReader(body), defaultLoc)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid iCalendar file: "+err.Error())
+		return
+	}
+	if len(events) == 0 {
+		utils.JSONError(c, http.StatusBadRequest, "no events found in file")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var items []*importItem
+	if dryRun {
+		items, err = planImport(s.store, userID, events)
+	} else {
+		err = s.store.Transaction(func(store repository.Store) error {
+			items, err = planImport(store, userID, events)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				if item.Action != "create" {
+					continue
+				}
+				if err := createEvent(store, item.event); err != nil {
+					return err
+				}
+				for i := range item.occurrences {
+					item.occurrences[i].EventID = item.event.ID
+				}
+				if len(item.occurrences) > 0 {
+					if err := store.Occurrences().ReplaceOverrides(item.event.ID, item.occurrences); err != nil {
+						return fmt.Errorf("failed to create occurrences: %w", err)
+					}
+				}
+				item.Action, item.EventID = "created", item.event.ID
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to import events: "+err.Error())
+		return
+	}
+
+	counts := map[string]int{"create": 0, "created": 0, "skip": 0, "error": 0}
+	for _, item := range items {
+		counts[item.Action]++
+	}
+	status := http.StatusOK
+	if counts["created"] > 0 {
+		status = http.StatusCreated
+	}
+	response := gin.H{
+		"dryRun":  dryRun,
+		"events":  items,
+		"skipped": counts["skip"],
+		"failed":  counts["error"],
+	}
+	if dryRun {
+		response["toCreate"] = counts["create"]
+	} else {
+		response["created"] = counts["created"]
+	}
+	c.JSON(status, response)
+}
+
+// readImportFile reads the uploaded file from the "file" form field or the
+// request body. It writes the error response and returns ok=false on failure.
+func readImportFile(c *gin.Context) (string, bool) {
+	var r io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "file is required: "+err.Error())
+			return "", false
+		}
+		if header.Size > maxImportSize {
+			utils.JSONError(c, http.StatusRequestEntityTooLarge, "file too large")
+			return "", false
+		}
+		file, err := header.Open()
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "failed to read file: "+err.Error())
+			return "", false
+		}
+		defer file.Close()
+		r = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxImportSize+1))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "failed to read file: "+err.Error())
+		return "", false
+	}
+	if len(data) > maxImportSize {
+		utils.JSONError(c, http.StatusRequestEntityTooLarge, "file too large")
+		return "", false
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		utils.JSONError(c, http.StatusBadRequest, "file is required")
+		return "", false
+	}
+	return string(data), true
+}
+
+// planImport turns parsed VEVENTs into events to create, matching the
+// VEVENTs that override single occurrences to their series and skipping
+// events the user already has.
+func planImport(store repository.Store, userID uint, events []utils.ICalEvent) ([]*importItem, error) {
+	var items []*importItem
+	series := map[string]*importItem{}
+	var exceptions []utils.ICalEvent
+
+	for _, ev := range events {
+		if ev.RecurrenceID != nil {
+			exceptions = append(exceptions, ev)
+			continue
+		}
+		item := newImportItem(ev, userID)
+		items = append(items, item)
+		if ev.UID == "" {
+			continue
+		}
+		if _, ok := series[ev.UID]; ok {
+			item.skip("duplicate UID in file")
+			continue
+		}
+		series[ev.UID] = item
+	}
+
+	for _, ev := range exceptions {
+		item, ok := series[ev.UID]
+		if ok && item.Action != "create" {
+			continue
+		}
+		if !ok || !item.event.IsRecurring() {
+			orphan := &importItem{UID: ev.UID, Title: ev.Summary, Warnings: ev.Warnings}
+			orphan.skip("modified occurrence without its recurring event in the file")
+			items = append(items, orphan)
+			continue
+		}
+		item.addException(ev)
+	}
+
+	for _, item := range items {
+		if item.Action != "create" || item.UID == "" {
+			continue
+		}
+		existing, err := findImportedEvent(store, userID, item.UID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != 0 {
+			item.skip("already imported")
+			item.EventID = existing
+		}
+	}
+	return items, nil
+}
+
+// newImportItem validates a VEVENT and builds the event it imports as.
+func newImportItem(ev utils.ICalEvent, userID uint) *importItem {
+	item := &importItem{
+		UID:      ev.UID,
+		Title:    strings.TrimSpace(ev.Summary),
+		Timezone: ev.Timezone,
+		Action:   "create",
+		Warnings: ev.Warnings,
+	}
+	if item.Title == "" {
+		item.Title = "Untitled event"
+		item.warn("no SUMMARY, titled \"Untitled event\"")
+	}
+	if len(item.UID) > 255 {
+		item.UID = ""
+		item.warn("UID too long, cannot be deduplicated")
+	} else if item.UID == "" {
+		item.warn("no UID, cannot be deduplicated")
+	}
+	if ev.Cancelled {
+		item.skip("event is cancelled")
+		return item
+	}
+
+	loc, err := utils.LoadLocation(ev.Timezone)
+	if err != nil {
+		loc = time.UTC
+		item.Timezone = "UTC"
+	}
+	startsAt := ev.Start.In(loc)
+	endsAt := ev.End
+	if endsAt.IsZero() {
+		endsAt = startsAt.Add(defaultEventDuration)
+	}
+	endsAt = endsAt.In(loc)
+	if !endsAt.After(startsAt) {
+		if !endsAt.Equal(startsAt) {
+			item.fail("event ends before it starts")
+			return item
+		}
+		endsAt = startsAt.Add(defaultEventDuration)
+		item.warn("event has no duration, imported as one hour long")
+	}
+	item.StartsAt, item.EndsAt = &startsAt, &endsAt
+
+	event := &models.Event{
+		Title:       truncateRunes(item.Title, 255),
+		Description: ev.Description,
+		Location:    truncateRunes(strings.TrimSpace(ev.Location), 255),
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		Timezone:    item.Timezone,
+		CreatedBy:   userID,
+	}
+	if item.UID != "" {
+		uid := item.UID
+		event.UID = &uid
+	}
+
+	if ev.RRule != "" {
+		rule, err := utils.ParseRRule(ev.RRule, loc)
+		if err != nil {
+			item.fail("unsupported RRULE: " + err.Error())
+			return item
+		}
+		event.RRule = rule.String()
+		if len(event.RRule) > 500 {
+			item.fail("RRULE too long")
+			return item
+		}
+		item.RRule = event.RRule
+	}
+	if event.RRule != "" && startsAt.Before(time.Now().AddDate(-maxRecurrenceAgeYears, 0, 0)) {
+		item.fail("recurring events starting more than " + strconv.Itoa(maxRecurrenceAgeYears) + " years ago cannot be imported")
+		return item
+	}
+	event.ExDates = utils.FormatICalDateList(ev.ExDates)
+	event.RDates = utils.FormatICalDateList(ev.RDates)
+	if event.RRule == "" && event.RDates == "" && event.ExDates != "" {
+		event.ExDates = ""
+		item.warn("EXDATE ignored on an event without recurrence")
+	}
+
+	item.event = event
+	return item
+}
+
+// addException applies a VEVENT overriding one occurrence of the series:
+// cancelled occurrences become excluded dates, others are stored as
+// occurrence overrides.
+func (item *importItem) addException(ev utils.ICalEvent) {
+	set, err := loadRecurrence(*item.event)
+	if err != nil || !set.includes(*ev.RecurrenceID) {
+		item.warn("modified occurrence " + occurrenceID(*ev.RecurrenceID) + " does not belong to the series, ignored")
+		return
+	}
+	for _, w := range ev.Warnings {
+		item.warn(w)
+	}
+	start := ev.RecurrenceID.UTC()
+
+	if ev.Cancelled {
+		if !containsInstant(set.exdates, start) {
+			set.exdates = append(set.exdates, start)
+			item.event.ExDates = utils.FormatICalDateList(set.exdates)
+		}
+		item.Exceptions++
+		return
+	}
+
+	occ := newOccurrence(*item.event, start.In(eventLocation(*item.event)), nil)
+	override := models.EventOccurrence{OccurrenceStart: start, UpdatedAt: time.Now()}
+	if title := strings.TrimSpace(ev.Summary); title != "" && title != occ.Title {
+		title = truncateRunes(title, 255)
+		override.Title = &title
+	}
+	if ev.Description != occ.Description {
+		description := ev.Description
+		override.Description = &description
+	}
+	if location := truncateRunes(strings.TrimSpace(ev.Location), 255); location != occ.Location {
+		override.Location = &location
+	}
+	endsAt := ev.End
+	if endsAt.IsZero() || !endsAt.After(ev.Start) {
+		endsAt = ev.Start.Add(occ.EndsAt.Sub(occ.StartsAt))
+	}
+	if !ev.Start.Equal(occ.StartsAt) || !endsAt.Equal(occ.EndsAt) {
+		startsAt := ev.Start.UTC()
+		endsAt = endsAt.UTC()
+		override.StartsAt, override.EndsAt = &startsAt, &endsAt
+	}
+	if override.Title == nil && override.Description == nil && override.Location == nil && override.StartsAt == nil {
+		return
+	}
+	for i := range item.occurrences {
+		if item.occurrences[i].OccurrenceStart.Equal(start) {
+			item.occurrences[i] = override
+			return
+		}
+	}
+	item.occurrences = append(item.occurrences, override)
+	item.Exceptions++
+}
+
+// findImportedEvent returns the ID of the user's event with the given UID:
+// one imported earlier, or one created here and exported. It returns 0 when
+// there is none.
+func findImportedEvent(store repository.Store, userID uint, uid string) (uint, error) {
+	id, err := store.Events().FindByICalUID(userID, uid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up UID %q: %w", uid, err)
+	}
+	if id != 0 {
+		return id, nil
+	}
+
+	if n, _ := fmt.Sscanf(uid, icalUIDFormat, &id); n != 1 || fmt.Sprintf(icalUIDFormat, id) != uid {
+		return 0, nil
+	}
+	if id, err = store.Events().FindVisible(id, userID); err != nil {
+		return 0, fmt.Errorf("failed to look up UID %q: %w", uid, err)
+	}
+	return id, nil
+}
+
+func (item *importItem) warn(message string) {
+	item.Warnings = append(item.Warnings, message)
+}
+
+func (item *importItem) skip(reason string) {
+	item.Action, item.Reason, item.event = "skip", reason, nil
+}
+
+func (item *importItem) fail(reason string) {
+	item.Action, item.Reason, item.event = "error", reason, nil
+}
+
+// truncateRunes shortens s to at most n characters.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"event_planner_backend/config"
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// defaultInvitationTTL is how long invitations of unregistered emails stay
+// valid when INVITATION_TTL_HOURS is not set.
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+// errInvitationInvalid is returned for unknown, expired, revoked or already
+// accepted invitation tokens.
+var errInvitationInvalid = errors.New("invitation is invalid or has expired")
+
+// AcceptInvitationRequest represents the payload for accepting an invitation.
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// invitationTTL returns the validity of new invitations.
+func invitationTTL() time.Duration {
+	if hours, err := strconv.Atoi(config.GetEnv("INVITATION_TTL_HOURS", "")); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultInvitationTTL
+}
+
+// jwtKeys returns the keys tokens are signed and verified with.
+func jwtKeys() *utils.JWTKeySet {
+	return config.JWTKeys
+}
+
+// inviteEmail creates or renews the invitation of an unregistered email in
+// store and returns it with its signed token. An invitation that is still
+// pending is not renewed: ok is false.
+func inviteEmail(store repository.Store, event models.Event, email, name, role string, invitedBy uint) (inv models.EventInvitation, token string, ok bool, err error) {
+	now := time.Now().Truncate(time.Second) // Compared with the token's iat
+	inv, err = store.Invitations().FindForUpdate(event.ID, email)
+	switch {
+	case err == nil:
+		if inv.Pending(now) {
+			return inv, "", false, nil
+		}
+	case err == repository.ErrNotFound:
+		inv = models.EventInvitation{EventID: event.ID, Email: email}
+	default:
+		return inv, "", false, err
+	}
+
+	inv.Name = name
+	inv.Role = role
+	inv.InvitedBy = invitedBy
+	inv.InvitedAt = now
+	inv.ExpiresAt = now.Add(invitationTTL())
+	inv.AcceptedBy = nil
+	inv.AcceptedAt = nil
+	if err := store.Invitations().Save(&inv); err != nil {
+		return inv, "", false, err
+	}
+
+	token, err = utils.GenerateScopedJWT(jwtKeys(), utils.PurposeInvite, jwt.MapClaims{"inv": inv.ID}, time.Until(inv.ExpiresAt))
+	if err != nil {
+		return inv, "", false, err
+	}
+	return inv, token, true, nil
+}
+
+// invitationURL returns the link that opens an invitation.
+func invitationURL(c *gin.Context, token string) string {
+	return publicBaseURL(c) + "/api/invitations/" + token
+}
+
+// findInvitation returns the pending invitation a token was issued for,
+// locking it when store is bound to a transaction.
+func findInvitation(store repository.Store, token string) (models.EventInvitation, error) {
+	var inv models.EventInvitation
+	claims, err := utils.ParseScopedJWT(jwtKeys(), utils.PurposeInvite, token)
+	if err != nil {
+		return inv, errInvitationInvalid
+	}
+	id, ok := utils.ClaimUint(claims, "inv")
+	if !ok {
+		return inv, errInvitationInvalid
+	}
+	if inv, err = store.Invitations().FindByIDForUpdate(id); err != nil {
+		if err == repository.ErrNotFound {
+			return inv, errInvitationInvalid
+		}
+		return inv, err
+	}
+	// Tokens of an earlier, since renewed invitation are no longer valid
+	if iat, ok := utils.ClaimUint(claims, "iat"); !ok || int64(iat) < inv.InvitedAt.Unix() {
+		return inv, errInvitationInvalid
+	}
+	if !inv.Pending(time.Now()) {
+		return inv, errInvitationInvalid
+	}
+	return inv, nil
+}
+
+// acceptInvitation attaches an invitation to a user, making them an attendee
+// of the event with the invited role. It reports whether an attendee row was
+// created; users who already were attendees keep their role and status.
+func acceptInvitation(store repository.Store, inv *models.EventInvitation, userID uint) (bool, error) {
+	now := time.Now()
+	inv.AcceptedBy = &userID
+	inv.AcceptedAt = &now
+	if err := store.Invitations().MarkAccepted(inv.ID, userID, now); err != nil {
+		return false, err
+	}
+
+	_, err := store.Attendees().Find(inv.EventID, userID)
+	if err == nil {
+		return false, nil
+	}
+	if err != repository.ErrNotFound {
+		return false, err
+	}
+	attendee := models.EventAttendee{
+		EventID:   inv.EventID,
+		UserID:    userID,
+		Role:      inv.Role,
+		Status:    "pending",
+		InvitedAt: inv.InvitedAt,
+	}
+	if err := store.Attendees().Create(&attendee); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// attachInvitations accepts, for a user, the pending invitations of their
+// email once it is verified, and the one of inviteToken when given. It
+// returns the IDs of the events the user was added to.
+func attachInvitations(store repository.Store, user models.User, inviteToken string) ([]uint, error) {
+	var invitations []models.EventInvitation
+	if user.EmailVerifiedAt != nil {
+		var err error
+		if invitations, err = store.Invitations().PendingForEmailForUpdate(user.Email, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if inviteToken != "" {
+		inv, err := findInvitation(store, inviteToken)
+		if err != nil {
+			return nil, err
+		}
+		if inv.Email != user.Email || user.EmailVerifiedAt == nil {
+			invitations = append(invitations, inv)
+		}
+	}
+
+	eventIDs := []uint{}
+	for i := range invitations {
+		created, err := acceptInvitation(store, &invitations[i], user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			eventIDs = append(eventIDs, invitations[i].EventID)
+		}
+	}
+	return eventIDs, nil
+}
+
+// GetInvitation shows an invitation from its link, so the recipient can sign
+// up or log in and accept it. It is public: the token authenticates it.
+func (s *Service) GetInvitation(c *gin.Context) {
+	if !s.available(c) {
+		return
+	}
+
+	inv, err := findInvitation(s.store, c.Param("token"))
+	if err != nil {
+		if err == errInvitationInvalid {
+			utils.JSONError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch invitation: "+err.Error())
+		return
+	}
+
+	event, err := s.store.Events().FindByID(inv.EventID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return
+	}
+	inviter, err := s.store.Users().FindByID(inv.InvitedBy)
+	if err != nil && err != repository.ErrNotFound {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch inviter: "+err.Error())
+		return
+	}
+
+	loc := eventLocation(event)
+	c.JSON(http.StatusOK, gin.H{
+		"email":     inv.Email,
+		"name":      inv.Name,
+		"role":      inv.Role,
+		"expiresAt": inv.ExpiresAt,
+		"invitedBy": gin.H{"id": inviter.ID, "name": inviter.Name},
+		"event": gin.H{
+			"id":        event.ID,
+			"title":     event.Title,
+			"location":  event.Location,
+			"startsAt":  event.StartsAt.In(loc).Format(time.RFC3339),
+			"endsAt":    event.EndsAt.In(loc).Format(time.RFC3339),
+			"timezone":  event.Timezone,
+			"recurring": event.IsRecurring(),
+		},
+	})
+}
+
+// AcceptInvitation attaches the invitation of a token to the current user,
+// whatever email it was sent to.
+func (s *Service) AcceptInvitation(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var inv models.EventInvitation
+	var created bool
+	err := s.store.Transaction(func(store repository.Store) error {
+		var err error
+		if inv, err = findInvitation(store, req.Token); err != nil {
+			return err
+		}
+		created, err = acceptInvitation(store, &inv, userID)
+		return err
+	})
+	if err != nil {
+		if err == errInvitationInvalid {
+			utils.JSONError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to accept invitation: "+err.Error())
+		return
+	}
+
+	message := "invitation accepted successfully"
+	if !created {
+		message = "you are already invited to this event"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"eventId": inv.EventID,
+		"role":    inv.Role,
+	})
+}
+
+// GetEventInvitations lists the invitations of unregistered emails that are
+// still pending (organizers only).
+func (s *Service) GetEventInvitations(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, ok := s.loadOrganizedEvent(c, userID, "only organizers can view invitations")
+	if !ok {
+		return
+	}
+
+	invitations, err := s.store.Invitations().ListPending(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch invitations: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	result := make([]gin.H, len(invitations))
+	for i, inv := range invitations {
+		result[i] = gin.H{
+			"id":        inv.ID,
+			"email":     inv.Email,
+			"name":      inv.Name,
+			"role":      inv.Role,
+			"invitedBy": inv.InvitedBy,
+			"invitedAt": inv.InvitedAt,
+			"expiresAt": inv.ExpiresAt,
+			"expired":   !inv.Pending(now),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":     event.ID,
+		"invitations": result,
+		"count":       len(result),
+	})
+}
+
+// RevokeEventInvitation deletes a pending invitation, invalidating its link
+// (organizers only).
+func (s *Service) RevokeEventInvitation(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	invitationID, ok := idParam(c, "invitationId", "invitation")
+	if !ok {
+		return
+	}
+
+	event, ok := s.loadOrganizedEvent(c, userID, "only organizers can revoke invitations")
+	if !ok {
+		return
+	}
+
+	revoked, err := s.store.Invitations().Revoke(invitationID, event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to revoke invitation: "+err.Error())
+		return
+	}
+	if !revoked {
+		utils.JSONError(c, http.StatusNotFound, "invitation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "invitation revoked successfully",
+	})
+}
+
+// loadOrganizedEvent loads the event of the request and checks that the user
+// organizes it. It writes the error response and returns ok=false on failure.
+func (s *Service) loadOrganizedEvent(c *gin.Context, userID uint, forbidden string) (models.Event, bool) {
+	event, ok := s.findEvent(c)
+	if !ok {
+		return event, false
+	}
+
+	role, err := s.store.Attendees().Role(event, userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+		return event, false
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, forbidden)
+		return event, false
+	}
+	return event, true
+}
@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/config"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// defaultLoginMaxFailures is the number of failed logins of an email
+	// that locks it out when LOGIN_MAX_FAILURES is not set.
+	defaultLoginMaxFailures = 5
+	// defaultLoginIPMaxFailures is the number of failed logins from a client
+	// IP that locks it out when LOGIN_IP_MAX_FAILURES is not set.
+	defaultLoginIPMaxFailures = 20
+	// defaultLoginLockout is the first lockout duration when
+	// LOGIN_LOCKOUT_MINUTES is not set. It doubles with each new lockout.
+	defaultLoginLockout = 15 * time.Minute
+	// maxLoginLockout caps lockout durations.
+	maxLoginLockout = 24 * time.Hour
+	// maxLoginBackoff caps the delay between two failed attempts.
+	maxLoginBackoff = time.Minute
+	// loginThrottleRetention is how long idle throttles are kept, so that
+	// repeated lockouts keep doubling.
+	loginThrottleRetention = 24 * time.Hour
+)
+
+// loginSubject identifies a LoginThrottle.
+type loginSubject struct {
+	Kind    string
+	Subject string
+}
+
+// loginSubjects returns the throttles a login attempt counts against.
+func loginSubjects(email, ip string) []loginSubject {
+	return []loginSubject{
+		{Kind: models.LoginThrottleAccount, Subject: email},
+		{Kind: models.LoginThrottleIP, Subject: ip},
+	}
+}
+
+// loginMaxFailures returns the number of failures locking out a subject.
+func loginMaxFailures(kind string) int {
+	if kind == models.LoginThrottleIP {
+		if n, err := strconv.Atoi(config.GetEnv("LOGIN_IP_MAX_FAILURES", "")); err == nil && n > 0 {
+			return n
+		}
+		return defaultLoginIPMaxFailures
+	}
+	if n, err := strconv.Atoi(config.GetEnv("LOGIN_MAX_FAILURES", "")); err == nil && n > 0 {
+		return n
+	}
+	return defaultLoginMaxFailures
+}
+
+// loginLockout returns the first lockout duration. Failures older than it
+// are forgotten.
+func loginLockout() time.Duration {
+	if minutes, err := strconv.Atoi(config.GetEnv("LOGIN_LOCKOUT_MINUTES", "")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultLoginLockout
+}
+
+// loginBackoff returns the delay before the attempt following the given
+// number of consecutive failures: 1s, 2s, 4s... up to maxLoginBackoff.
+func loginBackoff(failures int) time.Duration {
+	if failures > 6 {
+		return maxLoginBackoff
+	}
+	delay := time.Duration(1<<(failures-1)) * time.Second
+	if delay > maxLoginBackoff {
+		return maxLoginBackoff
+	}
+	return delay
+}
+
+// errLoginThrottled aborts the reservation of a login attempt that must wait.
+var errLoginThrottled = errors.New("login throttled")
+
+// loginReservation is a login attempt counted as failed before the password
+// or code is checked, so that parallel attempts cannot all pass the throttle.
+type loginReservation struct {
+	before []models.LoginThrottle // The throttles of the subjects as found
+	after  []models.LoginThrottle // and as left by the reservation
+}
+
+// countLoginAttempt counts a login attempt as failed against the subjects,
+// delaying their next attempt and locking out those reaching their failure
+// limit. When a subject must wait, nothing is counted and the wait is
+// returned instead.
+func countLoginAttempt(store repository.Store, subjects []loginSubject) (*loginReservation, time.Duration, error) {
+	res := &loginReservation{}
+	var wait time.Duration
+	err := store.Transaction(func(store repository.Store) error {
+		// Stored times have no fractions of a second
+		now := time.Now().Truncate(time.Second)
+		throttles := make([]models.LoginThrottle, len(subjects))
+		for i, s := range subjects {
+			var err error
+			if throttles[i], err = store.LoginThrottles().Acquire(s.Kind, s.Subject); err != nil {
+				return err
+			}
+			for _, until := range []*time.Time{throttles[i].BlockedUntil, throttles[i].LockedUntil} {
+				if until != nil && until.Sub(now) > wait {
+					wait = until.Sub(now)
+				}
+			}
+		}
+		if wait > 0 {
+			return errLoginThrottled
+		}
+
+		lockout := loginLockout()
+		for i, s := range subjects {
+			throttle := throttles[i]
+			res.before = append(res.before, throttle)
+			if throttle.LastFailureAt == nil || now.Sub(*throttle.LastFailureAt) > lockout {
+				throttle.Failures = 0
+			}
+			throttle.Failures++
+			throttle.LastFailureAt = &now
+			blocked := now.Add(loginBackoff(throttle.Failures))
+			throttle.BlockedUntil = &blocked
+			if throttle.Failures >= loginMaxFailures(s.Kind) {
+				duration := lockout << uint(min(throttle.Lockouts, 10))
+				if duration > maxLoginLockout {
+					duration = maxLoginLockout
+				}
+				locked := now.Add(duration)
+				throttle.LockedUntil = &locked
+				throttle.Lockouts++
+				throttle.Failures = 0
+			}
+			if err := store.LoginThrottles().Save(&throttle); err != nil {
+				return err
+			}
+			res.after = append(res.after, throttle)
+		}
+		return nil
+	})
+	if err == errLoginThrottled {
+		return nil, wait, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return res, 0, nil
+}
+
+// reserveLoginAttempt counts a login attempt against the subjects before its
+// password or code is checked. It responds with 429 and returns false when
+// the subjects must wait before a new attempt. Attempts that turn out right
+// give the reservation back with releaseLoginAttempt or clearLoginFailures;
+// wrong ones keep it as their failure.
+func reserveLoginAttempt(c *gin.Context, store repository.Store, subjects []loginSubject) (*loginReservation, bool) {
+	res, wait, err := countLoginAttempt(store, subjects)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to check login attempts")
+		return nil, false
+	}
+	if wait > 0 {
+		seconds := retryAfterSeconds(wait)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		utils.JSONError(c, http.StatusTooManyRequests, fmt.Sprintf("too many failed login attempts; try again in %d seconds", seconds))
+		return nil, false
+	}
+	return res, true
+}
+
+// releaseLoginAttempt undoes the counting of a reserved attempt. Failures
+// counted by other attempts in the meantime are kept.
+func releaseLoginAttempt(store repository.Store, res *loginReservation) error {
+	if res == nil {
+		return nil
+	}
+	return store.Transaction(func(store repository.Store) error {
+		for i, after := range res.after {
+			before := res.before[i]
+			throttle, err := store.LoginThrottles().FindByIDForUpdate(after.ID)
+			if err == repository.ErrNotFound {
+				continue // Cleared meanwhile
+			}
+			if err != nil {
+				return err
+			}
+
+			lockedByUs := after.Lockouts > before.Lockouts && sameTime(throttle.LockedUntil, after.LockedUntil)
+			if lockedByUs {
+				// Attempts refused by the lockout were not counted
+				throttle.Failures = before.Failures
+				throttle.LockedUntil = before.LockedUntil
+				throttle.Lockouts = before.Lockouts
+			} else if throttle.Failures > 0 {
+				throttle.Failures--
+			}
+			if lockedByUs || sameTime(throttle.BlockedUntil, after.BlockedUntil) {
+				throttle.BlockedUntil = before.BlockedUntil
+				throttle.LastFailureAt = before.LastFailureAt
+			}
+
+			if throttle.Failures == 0 && throttle.Lockouts == 0 && throttle.LockedUntil == nil {
+				err = store.LoginThrottles().Delete(throttle.ID)
+			} else {
+				err = store.LoginThrottles().Save(&throttle)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sameTime reports whether two optional times are the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// clearLoginFailures gives back the reservation of a successful login,
+// forgets the failures of its email, and drops idle throttles. Those of IPs
+// stay, so that logging into one account does not reset the count of
+// attempts against others.
+func clearLoginFailures(store repository.Store, res *loginReservation, email string) error {
+	if err := releaseLoginAttempt(store, res); err != nil {
+		return err
+	}
+	if err := store.LoginThrottles().DeleteSubject(models.LoginThrottleAccount, email); err != nil {
+		return err
+	}
+	now := time.Now()
+	return store.LoginThrottles().DeleteIdle(now.Add(-loginThrottleRetention), now)
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, at least 1, for the
+// Retry-After header.
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// formatLoginThrottle renders a throttle for admins.
+func formatLoginThrottle(t models.LoginThrottle, now time.Time) gin.H {
+	locked := t.LockedUntil != nil && t.LockedUntil.After(now)
+	result := gin.H{
+		"id":            t.ID,
+		"kind":          t.Kind,
+		"subject":       t.Subject,
+		"failures":      t.Failures,
+		"lockouts":      t.Lockouts,
+		"lastFailureAt": t.LastFailureAt,
+		"locked":        locked,
+	}
+	if locked {
+		result["lockedUntil"] = t.LockedUntil
+	}
+	if t.BlockedUntil != nil && t.BlockedUntil.After(now) {
+		result["blockedUntil"] = t.BlockedUntil
+	}
+	return result
+}
+
+// GetLoginThrottles lists the emails and client IPs with recent failed
+// logins, locked out ones first. With locked=true, only those locked out.
+// Admin only.
+func (s *Service) GetLoginThrottles(c *gin.Context) {
+	if !s.available(c) {
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && kind != models.LoginThrottleAccount && kind != models.LoginThrottleIP {
+		utils.JSONError(c, http.StatusBadRequest, "kind must be account or ip")
+		return
+	}
+
+	now := time.Now()
+	throttles, err := s.store.LoginThrottles().List(c.Query("locked") == "true", kind, now, now.Add(-loginLockout()))
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch login throttles: "+err.Error())
+		return
+	}
+
+	result := make([]gin.H, 0, len(throttles))
+	lockedCount := 0
+	for _, t := range throttles {
+		formatted := formatLoginThrottle(t, now)
+		if formatted["locked"] == true {
+			lockedCount++
+		}
+		result = append(result, formatted)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"throttles": result,
+		"total":     len(result),
+		"locked":    lockedCount,
+	})
+}
+
+// ClearLoginThrottle lifts the lockout and forgets the failures of an email
+// or client IP. Admin only.
+func (s *Service) ClearLoginThrottle(c *gin.Context) {
+	throttleID, ok := idParam(c, "throttleId", "throttle")
+	if !ok || !s.available(c) {
+		return
+	}
+
+	throttle, err := s.store.LoginThrottles().FindByID(throttleID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "login throttle not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch login throttle: "+err.Error())
+		return
+	}
+	if err := s.store.LoginThrottles().Delete(throttle.ID); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to clear login throttle: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("login attempts of %s %s are no longer restricted", throttle.Kind, throttle.Subject),
+		"id":      throttle.ID,
+	})
+}
@@ -0,0 +1,628 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+)
+
+// memoryStore implements repository.Store in memory for the handler tests.
+// Only the methods the tests reach are implemented; the others come from the
+// nil embedded interfaces and panic. Transactions run on the store itself,
+// without rollback.
+type memoryStore struct {
+	nextID        uint
+	users         []models.User
+	events        []models.Event
+	attendees     []models.EventAttendee
+	responses     []models.EventAttendeeOccurrence
+	tags          []models.EventTag
+	tasks         []models.Task
+	notifications []models.Notification
+	invitations   []models.EventInvitation
+	identities    []models.UserIdentity
+	recoveryCodes []models.RecoveryCode
+	refreshTokens []models.RefreshToken
+	accessTokens  []models.PersonalAccessToken
+	savedSearches []models.SavedSearch
+}
+
+// id returns a new record ID.
+func (s *memoryStore) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *memoryStore) Users() repository.UserRepository         { return memoryUsers{s: s} }
+func (s *memoryStore) Events() repository.EventRepository       { return memoryEvents{s: s} }
+func (s *memoryStore) Attendees() repository.AttendeeRepository { return memoryAttendees{s: s} }
+func (s *memoryStore) Tasks() repository.TaskRepository         { return memoryTasks{s: s} }
+func (s *memoryStore) Changes() repository.ChangeRepository     { return nil }
+func (s *memoryStore) Occurrences() repository.OccurrenceRepository {
+	return memoryOccurrences{s: s}
+}
+func (s *memoryStore) Invitations() repository.InvitationRepository { return memoryInvitations{s: s} }
+func (s *memoryStore) Transfers() repository.TransferRepository     { return nil }
+func (s *memoryStore) Notifications() repository.NotificationRepository {
+	return memoryNotifications{s: s}
+}
+func (s *memoryStore) CalendarFeeds() repository.CalendarFeedRepository { return nil }
+func (s *memoryStore) Sessions() repository.SessionRepository           { return memorySessions{s: s} }
+func (s *memoryStore) AccessTokens() repository.AccessTokenRepository {
+	return memoryAccessTokens{s: s}
+}
+func (s *memoryStore) PasswordResets() repository.PasswordResetRepository { return nil }
+func (s *memoryStore) RecoveryCodes() repository.RecoveryCodeRepository {
+	return memoryRecoveryCodes{s: s}
+}
+func (s *memoryStore) LoginThrottles() repository.LoginThrottleRepository { return nil }
+func (s *memoryStore) Identities() repository.IdentityRepository          { return memoryIdentities{s: s} }
+func (s *memoryStore) SavedSearches() repository.SavedSearchRepository {
+	return memorySavedSearches{s: s}
+}
+func (s *memoryStore) Search() repository.SearchRepository { return nil }
+
+func (s *memoryStore) Transaction(fn func(repository.Store) error) error { return fn(s) }
+
+// addUser adds a verified user.
+func (s *memoryStore) addUser(name, email string) models.User {
+	now := time.Now()
+	user := models.User{ID: s.id(), Name: name, Email: email, CreatedAt: now, EmailVerifiedAt: &now}
+	s.users = append(s.users, user)
+	return user
+}
+
+// addEvent adds an event created by organizer.
+func (s *memoryStore) addEvent(title string, organizer models.User) models.Event {
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	event := models.Event{
+		ID: s.id(), Title: title, Location: "Hall", Timezone: "UTC",
+		StartsAt: start, EndsAt: start.Add(time.Hour), CreatedBy: organizer.ID, CreatedAt: time.Now(),
+	}
+	s.events = append(s.events, event)
+	s.attendees = append(s.attendees, models.EventAttendee{EventID: event.ID, UserID: organizer.ID, Role: "organizer", Status: "going", InvitedAt: time.Now()})
+	return event
+}
+
+// addAttendee adds user to an event with role.
+func (s *memoryStore) addAttendee(event models.Event, user models.User, role string) {
+	s.attendees = append(s.attendees, models.EventAttendee{EventID: event.ID, UserID: user.ID, Role: role, Status: "pending", InvitedAt: time.Now()})
+}
+
+// user returns a user by ID, or nil.
+func (s *memoryStore) user(id uint) *models.User {
+	for i := range s.users {
+		if s.users[i].ID == id {
+			return &s.users[i]
+		}
+	}
+	return nil
+}
+
+// timeValue converts a column value of an update to a time pointer.
+func timeValue(v interface{}) *time.Time {
+	if t, ok := v.(time.Time); ok {
+		return &t
+	}
+	return nil
+}
+
+type memoryUsers struct {
+	repository.UserRepository
+	s *memoryStore
+}
+
+func (r memoryUsers) FindByID(id uint) (models.User, error) {
+	if user := r.s.user(id); user != nil {
+		return *user, nil
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r memoryUsers) FindByEmail(email string) (models.User, error) {
+	for _, user := range r.s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r memoryUsers) FindByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	for _, id := range ids {
+		if user := r.s.user(id); user != nil {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
+func (r memoryUsers) Create(user *models.User) error {
+	if _, err := r.FindByEmail(user.Email); err == nil {
+		return fmt.Errorf("duplicate entry %q for key ux_users_email", user.Email)
+	}
+	user.ID = r.s.id()
+	user.CreatedAt = time.Now()
+	r.s.users = append(r.s.users, *user)
+	return nil
+}
+
+func (r memoryUsers) Update(userID uint, updates map[string]interface{}) error {
+	user := r.s.user(userID)
+	if user == nil {
+		return nil
+	}
+	for column, v := range updates {
+		switch column {
+		case "name":
+			user.Name = v.(string)
+		case "email":
+			user.Email = v.(string)
+		case "password_hash":
+			user.PasswordHash = v.(string)
+		case "email_verified_at":
+			user.EmailVerifiedAt = timeValue(v)
+		case "verification_sent_at":
+			user.VerificationSentAt = timeValue(v)
+		case "totp_secret":
+			user.TOTPSecret = nil
+			if secret, ok := v.(string); ok {
+				user.TOTPSecret = &secret
+			}
+		case "totp_enabled_at":
+			user.TOTPEnabledAt = timeValue(v)
+		case "totp_last_step":
+			user.TOTPLastStep = nil
+			if step, ok := v.(int64); ok {
+				user.TOTPLastStep = &step
+			}
+		default:
+			return fmt.Errorf("memory store: unknown user column %s", column)
+		}
+	}
+	return nil
+}
+
+type memoryEvents struct {
+	repository.EventRepository
+	s *memoryStore
+}
+
+func (r memoryEvents) FindByID(id uint) (models.Event, error) {
+	for _, event := range r.s.events {
+		if event.ID == id {
+			return event, nil
+		}
+	}
+	return models.Event{}, repository.ErrNotFound
+}
+
+func (r memoryEvents) FindByIDForUpdate(id uint) (models.Event, error) { return r.FindByID(id) }
+
+func (r memoryEvents) Tags(eventID uint) ([]string, error) {
+	tags := []string{}
+	for _, tag := range r.s.tags {
+		if tag.EventID == eventID {
+			tags = append(tags, tag.Tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (r memoryEvents) AddTags(eventID uint, tags []string) error {
+	for _, tag := range tags {
+		r.s.tags = append(r.s.tags, models.EventTag{ID: r.s.id(), EventID: eventID, Tag: tag})
+	}
+	return nil
+}
+
+func (r memoryEvents) RemoveTag(eventID uint, tag string) (bool, error) {
+	for i, t := range r.s.tags {
+		if t.EventID == eventID && t.Tag == tag {
+			r.s.tags = append(r.s.tags[:i], r.s.tags[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryEvents) TagSuggestions(userID uint, prefix string, limit int) ([]repository.TagCount, error) {
+	counts := map[string]int64{}
+	for _, att := range r.s.attendees {
+		if att.UserID != userID {
+			continue
+		}
+		for _, tag := range r.s.tags {
+			if tag.EventID == att.EventID && strings.HasPrefix(tag.Tag, prefix) {
+				counts[tag.Tag]++
+			}
+		}
+	}
+	suggestions := []repository.TagCount{}
+	for tag, count := range counts {
+		suggestions = append(suggestions, repository.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+type memoryAttendees struct {
+	repository.AttendeeRepository
+	s *memoryStore
+}
+
+func (r memoryAttendees) Role(event models.Event, userID uint) (string, error) {
+	if event.CreatedBy == userID {
+		return "organizer", nil
+	}
+	attendee, err := r.Find(event.ID, userID)
+	if err == repository.ErrNotFound {
+		return "", nil
+	}
+	return attendee.Role, err
+}
+
+func (r memoryAttendees) Find(eventID, userID uint) (models.EventAttendee, error) {
+	for _, att := range r.s.attendees {
+		if att.EventID == eventID && att.UserID == userID {
+			return att, nil
+		}
+	}
+	return models.EventAttendee{}, repository.ErrNotFound
+}
+
+func (r memoryAttendees) Create(attendee *models.EventAttendee) error {
+	r.s.attendees = append(r.s.attendees, *attendee)
+	return nil
+}
+
+func (r memoryAttendees) GoingToOccurrence(eventID uint, start time.Time) ([]uint, error) {
+	var going []uint
+	for _, att := range r.s.attendees {
+		if att.EventID != eventID {
+			continue
+		}
+		status := att.Status
+		for _, response := range r.s.responses {
+			if response.EventID == eventID && response.UserID == att.UserID && response.OccurrenceStart.Equal(start) {
+				status = response.Status
+			}
+		}
+		if status == "going" {
+			going = append(going, att.UserID)
+		}
+	}
+	return going, nil
+}
+
+type memoryOccurrences struct {
+	repository.OccurrenceRepository
+	s *memoryStore
+}
+
+func (r memoryOccurrences) SaveResponse(response *models.EventAttendeeOccurrence) error {
+	for i, existing := range r.s.responses {
+		if existing.EventID == response.EventID && existing.UserID == response.UserID && existing.OccurrenceStart.Equal(response.OccurrenceStart) {
+			r.s.responses[i] = *response
+			return nil
+		}
+	}
+	r.s.responses = append(r.s.responses, *response)
+	return nil
+}
+
+type memoryTasks struct {
+	repository.TaskRepository
+	s *memoryStore
+}
+
+// withPeople sets the assignee and creator of a task.
+func (r memoryTasks) withPeople(task models.Task) models.Task {
+	if task.AssignedTo != nil {
+		task.Assignee = r.s.user(*task.AssignedTo)
+	}
+	if creator := r.s.user(task.CreatedBy); creator != nil {
+		task.Creator = *creator
+	}
+	return task
+}
+
+func (r memoryTasks) ListByEvent(eventID uint, status string) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range r.s.tasks {
+		if task.EventID == eventID && (status == "" || task.Status == status) {
+			tasks = append(tasks, r.withPeople(task))
+		}
+	}
+	return tasks, nil
+}
+
+func (r memoryTasks) FindInEvent(eventID, taskID uint) (models.Task, error) {
+	for _, task := range r.s.tasks {
+		if task.ID == taskID && task.EventID == eventID {
+			return r.withPeople(task), nil
+		}
+	}
+	return models.Task{}, repository.ErrNotFound
+}
+
+func (r memoryTasks) Create(task *models.Task) error {
+	task.ID = r.s.id()
+	task.CreatedAt, task.UpdatedAt = time.Now(), time.Now()
+	r.s.tasks = append(r.s.tasks, *task)
+	return nil
+}
+
+type memoryNotifications struct {
+	repository.NotificationRepository
+	s *memoryStore
+}
+
+func (r memoryNotifications) Create(notification *models.Notification) error {
+	notification.ID = r.s.id()
+	r.s.notifications = append(r.s.notifications, *notification)
+	return nil
+}
+
+func (r memoryNotifications) List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	for i := len(r.s.notifications) - 1; i >= 0 && len(notifications) < limit; i-- {
+		n := r.s.notifications[i]
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+func (r memoryNotifications) CountUnread(userID uint) (int64, error) {
+	var unread int64
+	for _, n := range r.s.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			unread++
+		}
+	}
+	return unread, nil
+}
+
+func (r memoryNotifications) Find(id, userID uint) (models.Notification, error) {
+	for _, n := range r.s.notifications {
+		if n.ID == id && n.UserID == userID {
+			return n, nil
+		}
+	}
+	return models.Notification{}, repository.ErrNotFound
+}
+
+func (r memoryNotifications) MarkRead(id uint, at time.Time) error {
+	for i := range r.s.notifications {
+		if r.s.notifications[i].ID == id {
+			r.s.notifications[i].ReadAt = &at
+		}
+	}
+	return nil
+}
+
+func (r memoryNotifications) MarkAllRead(userID uint, at time.Time) (int64, error) {
+	var updated int64
+	for i := range r.s.notifications {
+		if n := &r.s.notifications[i]; n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &at
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+type memoryInvitations struct {
+	repository.InvitationRepository
+	s *memoryStore
+}
+
+func (r memoryInvitations) PendingForEmailForUpdate(email string, now time.Time) ([]models.EventInvitation, error) {
+	var invitations []models.EventInvitation
+	for _, inv := range r.s.invitations {
+		if inv.Email == email && inv.AcceptedAt == nil && inv.ExpiresAt.After(now) {
+			invitations = append(invitations, inv)
+		}
+	}
+	return invitations, nil
+}
+
+func (r memoryInvitations) MarkAccepted(id, userID uint, at time.Time) error {
+	for i := range r.s.invitations {
+		if r.s.invitations[i].ID == id {
+			r.s.invitations[i].AcceptedBy = &userID
+			r.s.invitations[i].AcceptedAt = &at
+		}
+	}
+	return nil
+}
+
+type memoryIdentities struct {
+	repository.IdentityRepository
+	s *memoryStore
+}
+
+func (r memoryIdentities) Find(provider, subject string) (models.UserIdentity, error) {
+	for _, identity := range r.s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return models.UserIdentity{}, repository.ErrNotFound
+}
+
+func (r memoryIdentities) Create(identity *models.UserIdentity) error {
+	identity.ID = r.s.id()
+	r.s.identities = append(r.s.identities, *identity)
+	return nil
+}
+
+func (r memoryIdentities) Touch(identityID uint, email string, at time.Time) error {
+	for i := range r.s.identities {
+		if identity := &r.s.identities[i]; identity.ID == identityID {
+			identity.LastLoginAt = &at
+			if email != "" {
+				identity.Email = email
+			}
+		}
+	}
+	return nil
+}
+
+type memoryRecoveryCodes struct {
+	repository.RecoveryCodeRepository
+	s *memoryStore
+}
+
+func (r memoryRecoveryCodes) DeleteAll(userID uint) error {
+	codes := r.s.recoveryCodes[:0]
+	for _, code := range r.s.recoveryCodes {
+		if code.UserID != userID {
+			codes = append(codes, code)
+		}
+	}
+	r.s.recoveryCodes = codes
+	return nil
+}
+
+type memorySessions struct {
+	repository.SessionRepository
+	s *memoryStore
+}
+
+func (r memorySessions) RevokeUser(userID uint, at time.Time) error {
+	for i := range r.s.refreshTokens {
+		if token := &r.s.refreshTokens[i]; token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+type memoryAccessTokens struct {
+	repository.AccessTokenRepository
+	s *memoryStore
+}
+
+func (r memoryAccessTokens) RevokeAll(userID uint, at time.Time) error {
+	for i := range r.s.accessTokens {
+		if token := &r.s.accessTokens[i]; token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+type memorySavedSearches struct {
+	repository.SavedSearchRepository
+	s *memoryStore
+}
+
+func (r memorySavedSearches) ListDue(since time.Time, limit int) ([]models.SavedSearch, error) {
+	var due []models.SavedSearch
+	for _, search := range r.s.savedSearches {
+		if search.Notify && (search.CheckedAt == nil || !search.CheckedAt.After(since)) && len(due) < limit {
+			due = append(due, search)
+		}
+	}
+	return due, nil
+}
+
+func (r memorySavedSearches) ClaimCheck(id uint, checkedAt *time.Time, at time.Time) (bool, error) {
+	for i := range r.s.savedSearches {
+		if search := &r.s.savedSearches[i]; search.ID == id && sameTime(search.CheckedAt, checkedAt) {
+			search.CheckedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memorySavedSearches) ReleaseCheck(id uint, at time.Time, checkedAt *time.Time) error {
+	for i := range r.s.savedSearches {
+		if search := &r.s.savedSearches[i]; search.ID == id && sameTime(search.CheckedAt, &at) {
+			search.CheckedAt = checkedAt
+		}
+	}
+	return nil
+}
+
+// serve runs handler, registered for route, on a request as userID (0 for
+// none) with body encoded as JSON, and returns the recorded response.
+func serve(t *testing.T, handler gin.HandlerFunc, method, route, path string, userID uint, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("userID", userID)
+		}
+		handler(c)
+	})
+
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decode decodes the JSON body of a response into a map.
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+// expectStatus fails the test unless the response has the status.
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, status, w.Body.String())
+	}
+}
+
+func TestServiceWithoutStore(t *testing.T) {
+	svc := NewService(nil)
+	w := serve(t, svc.GetNotifications, http.MethodGet, "/notifications", "/notifications", 1, nil)
+	expectStatus(t, w, http.StatusInternalServerError)
+	if msg := decode(t, w)["message"]; msg != "database not available" {
+		t.Errorf("message = %v", msg)
+	}
+}
@@ -0,0 +1,437 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"event_planner_backend/config"
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes generated at once.
+	recoveryCodeCount = 10
+	// mfaChallengeTTL is how long a password check waits for the second factor.
+	mfaChallengeTTL = 5 * time.Minute
+)
+
+// errMFACodeInvalid is returned for wrong, replayed or used codes.
+var errMFACodeInvalid = errors.New("invalid authentication code")
+
+// MFACodeRequest carries a code of the authenticator app.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest defines the payload to turn two-factor login off. Either
+// code or recoveryCode is required.
+type DisableMFARequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// MFALoginRequest defines the second step of a two-factor login. Either code
+// or recoveryCode is required.
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfaToken" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// mfaIssuer names the service in authenticator apps, from MFA_ISSUER.
+func mfaIssuer() string {
+	return config.GetEnv("MFA_ISSUER", "Event Planner")
+}
+
+// normalizeRecoveryCode ignores the case, dashes and spaces of a recovery code.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generateRecoveryCodes replaces the recovery codes of a user with new ones,
+// and returns them in clear, formatted as xxxxx-xxxxx.
+func generateRecoveryCodes(store repository.Store, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(secret[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code), CreatedAt: time.Now()})
+	}
+	if err := store.RecoveryCodes().Replace(userID, rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkSecondFactor validates a TOTP code or, when code is empty, a recovery
+// code of user, and uses it up so that it cannot be replayed.
+func checkSecondFactor(store repository.Store, user models.User, code, recoveryCode string) error {
+	if user.TOTPSecret == nil {
+		return errMFACodeInvalid
+	}
+	var used bool
+	var err error
+	if code != "" {
+		step, ok := utils.ValidateTOTP(*user.TOTPSecret, code, time.Now())
+		if !ok {
+			return errMFACodeInvalid
+		}
+		used, err = store.Users().UseTOTPStep(user.ID, step)
+	} else {
+		used, err = store.RecoveryCodes().Use(user.ID, utils.HashToken(normalizeRecoveryCode(recoveryCode)), time.Now())
+	}
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errMFACodeInvalid
+	}
+	return nil
+}
+
+// issueMFAChallenge returns the response to a correct password of a user
+// with two-factor login: a short-lived token to exchange, with a code, for
+// the access token at /api/login/mfa.
+func issueMFAChallenge(user models.User) (gin.H, error) {
+	token, err := utils.GenerateScopedJWT(jwtKeys(), utils.PurposeMFA, jwt.MapClaims{"uid": user.ID, "email": user.Email}, mfaChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"mfaRequired": true,
+		"mfaToken":    token,
+		"expiresIn":   int(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// loadCurrentUser fetches the authenticated user, or responds with an error.
+func (s *Service) loadCurrentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return user, false
+	}
+	if !s.available(c) {
+		return user, false
+	}
+	user, err := s.store.Users().FindByID(userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "user not found")
+			return user, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch user: "+err.Error())
+		return user, false
+	}
+	return user, true
+}
+
+// GetMFAStatus tells whether two-factor login is enabled for the current user.
+func (s *Service) GetMFAStatus(c *gin.Context) {
+	user, ok := s.loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	result := gin.H{
+		"enabled":           user.TOTPEnabledAt != nil,
+		"pendingEnrollment": user.TOTPEnabledAt == nil && user.TOTPSecret != nil,
+	}
+	if user.TOTPEnabledAt != nil {
+		left, err := s.store.RecoveryCodes().CountUnused(user.ID)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to count recovery codes: "+err.Error())
+			return
+		}
+		result["enabledAt"] = user.TOTPEnabledAt
+		result["recoveryCodesLeft"] = left
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// EnrollMFA creates a TOTP secret for the current user. It is pending until
+// confirmed with a code, so a failed setup never locks the user out.
+// Enrolling again replaces a pending secret.
+func (s *Service) EnrollMFA(c *gin.Context) {
+	user, ok := s.loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		utils.JSONError(c, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate secret")
+		return
+	}
+	if err := s.store.Users().Update(user.ID, map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": nil,
+	}); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update user: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "add this account to your authenticator app, then confirm with a code",
+		"secret":     secret,
+		"otpauthUri": utils.TOTPURI(mfaIssuer(), user.Email, secret),
+	})
+}
+
+// ConfirmMFA enables two-factor login once the user proves, with a code, that
+// their authenticator app holds the pending secret. The response holds the
+// recovery codes, shown only this once.
+func (s *Service) ConfirmMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	user, ok := s.loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		utils.JSONError(c, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	if user.TOTPSecret == nil {
+		utils.JSONError(c, http.StatusConflict, "no pending enrollment; enroll first")
+		return
+	}
+
+	var codes []string
+	err := s.store.Transaction(func(store repository.Store) error {
+		if err := checkSecondFactor(store, user, req.Code, ""); err != nil {
+			return err
+		}
+		if err := store.Users().Update(user.ID, map[string]interface{}{"totp_enabled_at": time.Now()}); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		var err error
+		if codes, err = generateRecoveryCodes(store, user.ID); err != nil {
+			return fmt.Errorf("failed to create recovery codes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errMFACodeInvalid {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "two-factor authentication enabled; store these recovery codes somewhere safe, they are shown only once",
+		"recoveryCodes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user,
+// given a code of their authenticator app.
+func (s *Service) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	user, ok := s.loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		utils.JSONError(c, http.StatusConflict, "two-factor authentication is not enabled")
+		return
+	}
+	reservation, ok := reserveLoginAttempt(c, s.store, loginSubjects(user.Email, c.ClientIP()))
+	if !ok {
+		return
+	}
+
+	var codes []string
+	err := s.store.Transaction(func(store repository.Store) error {
+		if err := checkSecondFactor(store, user, req.Code, ""); err != nil {
+			return err
+		}
+		var err error
+		codes, err = generateRecoveryCodes(store, user.ID)
+		return err
+	})
+	if err == errMFACodeInvalid {
+		// Wrong codes keep the reservation as a failed login, so that codes
+		// cannot be guessed with a stolen access token either
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	releaseLogin(s.store, reservation, user.Email)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create recovery codes: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "new recovery codes created; the previous ones no longer work",
+		"recoveryCodes": codes,
+	})
+}
+
+// DisableMFA turns two-factor login off, given the password and a code or a
+// recovery code.
+func (s *Service) DisableMFA(c *gin.Context) {
+	var req DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		utils.JSONError(c, http.StatusBadRequest, "code or recoveryCode is required")
+		return
+	}
+
+	user, ok := s.loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		utils.JSONError(c, http.StatusConflict, "two-factor authentication is not enabled")
+		return
+	}
+	reservation, ok := reserveLoginAttempt(c, s.store, loginSubjects(user.Email, c.ClientIP()))
+	if !ok {
+		return
+	}
+	if !utils.CheckPassword(user.PasswordHash, req.Password) {
+		utils.JSONError(c, http.StatusUnauthorized, "invalid password")
+		return
+	}
+
+	err := s.store.Transaction(func(store repository.Store) error {
+		if err := checkSecondFactor(store, user, req.Code, req.RecoveryCode); err != nil {
+			return err
+		}
+		if err := store.Users().Update(user.ID, map[string]interface{}{
+			"totp_secret":     nil,
+			"totp_enabled_at": nil,
+			"totp_last_step":  nil,
+		}); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return store.RecoveryCodes().DeleteAll(user.ID)
+	})
+	if err == errMFACodeInvalid {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	releaseLogin(s.store, reservation, user.Email)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// LoginMFA completes a two-factor login: it exchanges the token returned by
+// Login for the password, with a code or a recovery code, for an access
+// token. Wrong codes count as failed logins.
+func (s *Service) LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		utils.JSONError(c, http.StatusBadRequest, "code or recoveryCode is required")
+		return
+	}
+
+	claims, err := utils.ParseScopedJWT(jwtKeys(), utils.PurposeMFA, req.MFAToken)
+	if err != nil {
+		utils.JSONError(c, http.StatusUnauthorized, "login expired; please log in again")
+		return
+	}
+	userID, ok := utils.ClaimUint(claims, "uid")
+	email, _ := claims["email"].(string)
+	if !ok || email == "" {
+		utils.JSONError(c, http.StatusUnauthorized, "login expired; please log in again")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	reservation, ok := reserveLoginAttempt(c, s.store, loginSubjects(email, c.ClientIP()))
+	if !ok {
+		return
+	}
+
+	user, err := s.store.Users().FindByID(userID)
+	if err != nil {
+		releaseLogin(s.store, reservation, email)
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusUnauthorized, "login expired; please log in again")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch user: "+err.Error())
+		return
+	}
+	if user.Email != email || user.TOTPEnabledAt == nil {
+		releaseLogin(s.store, reservation, email)
+		utils.JSONError(c, http.StatusUnauthorized, "login expired; please log in again")
+		return
+	}
+
+	if err := checkSecondFactor(s.store, user, req.Code, req.RecoveryCode); err != nil {
+		if err == errMFACodeInvalid {
+			utils.JSONError(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		releaseLogin(s.store, reservation, email)
+		utils.JSONError(c, http.StatusInternalServerError, "failed to check code: "+err.Error())
+		return
+	}
+	if err := clearLoginFailures(s.store, reservation, email); err != nil {
+		log.Printf("failed to clear failed logins of %s: %v", email, err)
+	}
+
+	tokens, err := issueTokens(s.store, user, "")
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	tokens["user"] = loginUserPayload(user)
+	if req.Code == "" {
+		left, err := s.store.RecoveryCodes().CountUnused(user.ID)
+		if err == nil {
+			tokens["recoveryCodesLeft"] = left
+		}
+	}
+	c.JSON(http.StatusOK, tokens)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// maxNotifications bounds the notifications returned at once.
+const maxNotifications = 100
+
+// notify stores a notification for a user.
+func notify(store repository.Store, userID uint, eventID *uint, kind, message string) error {
+	notification := models.Notification{
+		UserID:    userID,
+		EventID:   eventID,
+		Type:      kind,
+		Message:   truncateRunes(message, 500),
+		CreatedAt: time.Now(),
+	}
+	return store.Notifications().Create(&notification)
+}
+
+// GetNotifications returns the user's notifications, newest first.
+func (s *Service) GetNotifications(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxNotifications {
+			utils.JSONError(c, http.StatusBadRequest, "invalid limit. Use 1 to "+strconv.Itoa(maxNotifications))
+			return
+		}
+		limit = n
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	notifications, err := s.store.Notifications().List(userID, c.Query("unread") == "true", limit)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch notifications: "+err.Error())
+		return
+	}
+
+	unread, err := s.store.Notifications().CountUnread(userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to count notifications: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"count":         len(notifications),
+		"unread":        unread,
+	})
+}
+
+// MarkNotificationRead marks one of the user's notifications as read.
+func (s *Service) MarkNotificationRead(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	notificationID, ok := idParam(c, "id", "notification")
+	if !ok || !s.available(c) {
+		return
+	}
+
+	notification, err := s.store.Notifications().Find(notificationID, userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "notification not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch notification: "+err.Error())
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := s.store.Notifications().MarkRead(notification.ID, now); err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to update notification: "+err.Error())
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead marks all of the user's notifications as read.
+func (s *Service) MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	updated, err := s.store.Notifications().MarkAllRead(userID, time.Now())
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update notifications: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "notifications marked as read",
+		"updated": updated,
+	})
+}
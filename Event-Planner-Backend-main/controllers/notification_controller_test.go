@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNotifications(t *testing.T) {
+	store := &memoryStore{}
+	user := store.addUser("Olivia", "olivia@example.com")
+	other := store.addUser("Gus", "gus@example.com")
+	for _, message := range []string{"first", "second", "third"} {
+		if err := notify(store, user.ID, nil, "event_updated", message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := notify(store, other.ID, nil, "event_updated", "not yours"); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(store)
+
+	w := serve(t, svc.GetNotifications, http.MethodGet, "/notifications", "/notifications?limit=2", user.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	body := decode(t, w)
+	if body["count"] != 2.0 || body["unread"] != 3.0 {
+		t.Fatalf("count = %v, unread = %v, want 2 and 3", body["count"], body["unread"])
+	}
+	if first := body["notifications"].([]interface{})[0].(map[string]interface{}); first["message"] != "third" {
+		t.Errorf("first notification = %v, want the newest", first)
+	}
+
+	w = serve(t, svc.GetNotifications, http.MethodGet, "/notifications", "/notifications?limit=0", user.ID, nil)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	first := store.notifications[0]
+	path := fmt.Sprintf("/notifications/%d/read", first.ID)
+	w = serve(t, svc.MarkNotificationRead, http.MethodPatch, "/notifications/:id/read", path, user.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	if decode(t, w)["readAt"] == nil {
+		t.Errorf("readAt not set")
+	}
+	w = serve(t, svc.MarkNotificationRead, http.MethodPatch, "/notifications/:id/read", path, other.ID, nil)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = serve(t, svc.MarkAllNotificationsRead, http.MethodPatch, "/notifications/read", "/notifications/read", user.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	if updated := decode(t, w)["updated"]; updated != 2.0 {
+		t.Errorf("updated = %v, want 2", updated)
+	}
+
+	w = serve(t, svc.GetNotifications, http.MethodGet, "/notifications", "/notifications?unread=true", user.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := decode(t, w); body["count"] != 0.0 || body["unread"] != 0.0 {
+		t.Errorf("count = %v, unread = %v, want 0 and 0", body["count"], body["unread"])
+	}
+	if unread, _ := store.Notifications().CountUnread(other.ID); unread != 1 {
+		t.Errorf("other user has %d unread notifications, want 1", unread)
+	}
+}
@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/models"
+	"event_planner_backend/oidc"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// oidcLoginTTL is how long a login may stay at the provider before the
+// callback.
+const oidcLoginTTL = 10 * time.Minute
+
+var (
+	// errOIDCStateInvalid is returned for unknown, used or expired states.
+	errOIDCStateInvalid = errors.New("login expired or was already completed; please start again")
+	// errOIDCEmailUnverified is returned when the provider does not vouch
+	// for the email of a new identity.
+	errOIDCEmailUnverified = errors.New("the provider did not report a verified email address")
+)
+
+// OIDCCallbackRequest defines the payload of the callback page, which posts
+// the parameters the provider redirected the browser with.
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// loadOIDCProvider returns the provider of the :provider parameter, or
+// responds with an error.
+func (s *Service) loadOIDCProvider(c *gin.Context) (*oidc.Provider, bool) {
+	provider, ok := oidc.Get(c.Param("provider"))
+	if !ok {
+		utils.JSONError(c, http.StatusNotFound, "unknown login provider")
+		return nil, false
+	}
+	if !s.available(c) {
+		return nil, false
+	}
+	return provider, true
+}
+
+// GetOIDCProviders lists the providers users can log in with.
+func (s *Service) GetOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oidc.Names()})
+}
+
+// StartOIDCLogin begins a login with a provider: it returns the URL of the
+// provider to send the browser to. The state, nonce and PKCE verifier are
+// kept until the callback.
+func (s *Service) StartOIDCLogin(c *gin.Context) {
+	provider, ok := s.loadOIDCProvider(c)
+	if !ok {
+		return
+	}
+
+	state, err := utils.GenerateToken(32)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate state")
+		return
+	}
+	nonce, err := utils.GenerateToken(32)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate nonce")
+		return
+	}
+	verifier, err := utils.GenerateToken(48)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate code verifier")
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		log.Printf("OIDC provider %s: %v", provider.Name, err)
+		utils.JSONError(c, http.StatusBadGateway, "login provider is not available")
+		return
+	}
+
+	now := time.Now()
+	stored := models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(oidcLoginTTL),
+	}
+	if err := s.store.Identities().CreateLoginState(&stored); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to start login: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authorizationUrl": authURL,
+		"state":            state,
+		"expiresIn":        int(oidcLoginTTL.Seconds()),
+	})
+}
+
+// claimOIDCState uses up the login state of a provider, and returns it.
+func claimOIDCState(store repository.Store, provider, state string) (models.OIDCLoginState, error) {
+	stored, err := store.Identities().ClaimLoginState(utils.HashToken(state), provider)
+	if err == repository.ErrNotFound {
+		return stored, errOIDCStateInvalid
+	}
+	if err != nil {
+		return stored, err
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		return stored, errOIDCStateInvalid
+	}
+	return stored, nil
+}
+
+// linkOIDCIdentity returns the user of an external identity. A new identity
+// is linked to the user with its email, or to a new user, only when the
+// provider verified the email. The email of the user is verified on the way;
+// an unverified account is adopted with adoptUnverifiedAccount.
+func linkOIDCIdentity(store repository.Store, provider string, claims *oidc.Claims) (models.User, []uint, error) {
+	var user models.User
+	now := time.Now()
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	identity, err := store.Identities().Find(provider, claims.Subject)
+	if err == nil {
+		if user, err = store.Users().FindByID(identity.UserID); err != nil {
+			return user, nil, err
+		}
+		if err := store.Identities().Touch(identity.ID, email, now); err != nil {
+			return user, nil, err
+		}
+		return user, []uint{}, nil
+	}
+	if err != repository.ErrNotFound {
+		return user, nil, err
+	}
+
+	if email == "" || !claims.EmailVerified {
+		return user, nil, errOIDCEmailUnverified
+	}
+	// Invitations of the email are attached once it is verified, so only for
+	// new or unverified accounts
+	newlyVerified := true
+	user, err = store.Users().FindByEmail(email)
+	switch {
+	case err == repository.ErrNotFound:
+		// Accounts created here have no password; one can be set with a
+		// password reset
+		name := strings.TrimSpace(claims.Name)
+		if name == "" {
+			name = strings.SplitN(email, "@", 2)[0]
+		}
+		if runes := []rune(name); len(runes) > 100 {
+			name = string(runes[:100])
+		}
+		user = models.User{Name: name, Email: email, EmailVerifiedAt: &now}
+		if err := store.Users().Create(&user); err != nil {
+			return user, nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	case err != nil:
+		return user, nil, err
+	case user.EmailVerifiedAt == nil:
+		// Anyone could have signed up with the email without verifying it:
+		// the account is taken over with nothing its creator could still
+		// log in with
+		if err := adoptUnverifiedAccount(store, &user, now); err != nil {
+			return user, nil, err
+		}
+	default:
+		newlyVerified = false
+	}
+
+	identity = models.UserIdentity{UserID: user.ID, Provider: provider, Subject: claims.Subject, Email: email, CreatedAt: now, LastLoginAt: &now}
+	if err := store.Identities().Create(&identity); err != nil {
+		return user, nil, err
+	}
+	if !newlyVerified {
+		return user, []uint{}, nil
+	}
+	invitedEvents, err := attachInvitations(store, user, "")
+	if err != nil {
+		return user, nil, fmt.Errorf("failed to attach invitations: %w", err)
+	}
+	return user, invitedEvents, nil
+}
+
+// adoptUnverifiedAccount verifies the email of an account that never proved
+// it, for the owner of the email. The password and two-factor login set by
+// whoever signed up are removed, and their sessions and personal access
+// tokens revoked; a password can be set again with a password reset.
+func adoptUnverifiedAccount(store repository.Store, user *models.User, now time.Time) error {
+	if err := store.Users().Update(user.ID, map[string]interface{}{
+		"email_verified_at": now,
+		"password_hash":     "",
+		"totp_secret":       nil,
+		"totp_enabled_at":   nil,
+		"totp_last_step":    nil,
+	}); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if err := store.RecoveryCodes().DeleteAll(user.ID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if err := logOutEverywhere(store, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	user.EmailVerifiedAt = &now
+	user.PasswordHash = ""
+	user.TOTPSecret, user.TOTPEnabledAt, user.TOTPLastStep = nil, nil, nil
+	return nil
+}
+
+// CompleteOIDCLogin finishes a login with a provider: it exchanges the code
+// for an ID token, and logs in the user of that identity. Identities are
+// linked to accounts by verified email, and new emails get a new account.
+// Users with two-factor login get a challenge token, as with Login.
+func (s *Service) CompleteOIDCLogin(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	provider, ok := s.loadOIDCProvider(c)
+	if !ok {
+		return
+	}
+
+	state, err := claimOIDCState(s.store, provider.Name, req.State)
+	if err != nil {
+		if err == errOIDCStateInvalid {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to check state: "+err.Error())
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("OIDC provider %s: %v", provider.Name, err)
+		utils.JSONError(c, http.StatusUnauthorized, "login with the provider failed")
+		return
+	}
+
+	var user models.User
+	var invitedEvents []uint
+	err = s.store.Transaction(func(store repository.Store) error {
+		var err error
+		user, invitedEvents, err = linkOIDCIdentity(store, provider.Name, claims)
+		return err
+	})
+	if err != nil {
+		if err == errOIDCEmailUnverified {
+			utils.JSONError(c, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to log in: "+err.Error())
+		return
+	}
+
+	if user.TOTPEnabledAt != nil {
+		challenge, err := issueMFAChallenge(user)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokens, err := issueTokens(s.store, user, "")
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	tokens["user"] = loginUserPayload(user)
+	tokens["invitedEvents"] = invitedEvents
+	c.JSON(http.StatusOK, tokens)
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"event_planner_backend/models"
+	"event_planner_backend/oidc"
+)
+
+// addInvitation adds a pending invitation of email to an event.
+func (s *memoryStore) addInvitation(event models.Event, email string) {
+	s.invitations = append(s.invitations, models.EventInvitation{
+		ID: s.id(), EventID: event.ID, Email: email, Role: "attendee", InvitedBy: event.CreatedBy,
+		InvitedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	})
+}
+
+func TestLinkOIDCIdentityKnownIdentity(t *testing.T) {
+	store := &memoryStore{}
+	user := store.addUser("Olivia", "olivia@example.com")
+	store.identities = append(store.identities, models.UserIdentity{ID: store.id(), UserID: user.ID, Provider: "google", Subject: "sub-1", Email: "old@example.com"})
+
+	// The email of a known identity need not be verified
+	got, invited, err := linkOIDCIdentity(store, "google", &oidc.Claims{Subject: "sub-1", Email: "New@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || len(invited) != 0 {
+		t.Errorf("user %d, invited to %v; want user %d and no events", got.ID, invited, user.ID)
+	}
+	if identity := store.identities[0]; identity.Email != "new@example.com" || identity.LastLoginAt == nil {
+		t.Errorf("identity = %+v, want it touched", identity)
+	}
+	if len(store.users) != 1 || len(store.identities) != 1 {
+		t.Errorf("%d users and %d identities, want 1 of each", len(store.users), len(store.identities))
+	}
+}
+
+func TestLinkOIDCIdentityUnverifiedEmail(t *testing.T) {
+	for _, claims := range []oidc.Claims{
+		{Subject: "sub-1", Email: "olivia@example.com"},
+		{Subject: "sub-1", EmailVerified: true},
+	} {
+		store := &memoryStore{}
+		store.addUser("Olivia", "olivia@example.com")
+		if _, _, err := linkOIDCIdentity(store, "google", &claims); err != errOIDCEmailUnverified {
+			t.Errorf("claims %+v: err = %v, want errOIDCEmailUnverified", claims, err)
+		}
+		if len(store.identities) != 0 {
+			t.Errorf("claims %+v: identity linked", claims)
+		}
+	}
+}
+
+func TestLinkOIDCIdentityNewUser(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	event := store.addEvent("Launch", organizer)
+	store.addInvitation(event, "gus@example.com")
+
+	user, invited, err := linkOIDCIdentity(store, "google", &oidc.Claims{Subject: "sub-1", Email: " Gus@Example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.Email != "gus@example.com" || user.Name != "gus" || user.EmailVerifiedAt == nil || user.PasswordHash != "" {
+		t.Errorf("user = %+v, want a verified user without password named after the email", user)
+	}
+	if len(invited) != 1 || invited[0] != event.ID {
+		t.Errorf("invited to %v, want [%d]", invited, event.ID)
+	}
+	if len(store.identities) != 1 || store.identities[0].UserID != user.ID || store.identities[0].Subject != "sub-1" {
+		t.Errorf("identities = %+v", store.identities)
+	}
+	if store.invitations[0].AcceptedBy == nil || *store.invitations[0].AcceptedBy != user.ID {
+		t.Errorf("invitation not accepted")
+	}
+}
+
+func TestLinkOIDCIdentityVerifiedUser(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	user := store.addUser("Gus", "gus@example.com")
+	store.users[1].PasswordHash = "hash"
+	event := store.addEvent("Launch", organizer)
+	store.addInvitation(event, user.Email)
+
+	got, invited, err := linkOIDCIdentity(store, "google", &oidc.Claims{Subject: "sub-1", Email: user.Email, EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || got.PasswordHash != "hash" {
+		t.Errorf("user = %+v, want %d with its password", got, user.ID)
+	}
+	// The invitations of a verified account were already offered to it
+	if len(invited) != 0 || store.invitations[0].AcceptedAt != nil {
+		t.Errorf("invited to %v, want no invitation attached", invited)
+	}
+	if len(store.identities) != 1 || store.identities[0].UserID != user.ID {
+		t.Errorf("identities = %+v", store.identities)
+	}
+}
+
+func TestLinkOIDCIdentityAdoptsUnverifiedAccount(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	squatter := store.addUser("Mallory", "gus@example.com")
+	secret, step, now := "SECRET", int64(42), time.Now()
+	u := store.user(squatter.ID)
+	u.EmailVerifiedAt, u.PasswordHash = nil, "hash"
+	u.TOTPSecret, u.TOTPEnabledAt, u.TOTPLastStep = &secret, &now, &step
+	store.recoveryCodes = []models.RecoveryCode{{ID: store.id(), UserID: squatter.ID}, {ID: store.id(), UserID: organizer.ID}}
+	store.refreshTokens = []models.RefreshToken{{UserID: squatter.ID}, {UserID: organizer.ID}}
+	store.accessTokens = []models.PersonalAccessToken{{UserID: squatter.ID}, {UserID: organizer.ID}}
+	event := store.addEvent("Launch", organizer)
+	store.addInvitation(event, "gus@example.com")
+
+	user, invited, err := linkOIDCIdentity(store, "google", &oidc.Claims{Subject: "sub-1", Email: "gus@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != squatter.ID || len(store.users) != 2 {
+		t.Fatalf("user %d, %d users; want the existing account", user.ID, len(store.users))
+	}
+	for _, u := range []models.User{user, *store.user(squatter.ID)} {
+		if u.EmailVerifiedAt == nil || u.PasswordHash != "" || u.TOTPSecret != nil || u.TOTPEnabledAt != nil || u.TOTPLastStep != nil {
+			t.Errorf("user = %+v, want verified without password or second factor", u)
+		}
+	}
+	if len(store.recoveryCodes) != 1 || store.recoveryCodes[0].UserID != organizer.ID {
+		t.Errorf("recovery codes = %+v, want only those of other users", store.recoveryCodes)
+	}
+	for i, want := range []bool{true, false} {
+		if revoked := store.refreshTokens[i].RevokedAt != nil; revoked != want {
+			t.Errorf("refresh token of user %d revoked = %v", store.refreshTokens[i].UserID, revoked)
+		}
+		if revoked := store.accessTokens[i].RevokedAt != nil; revoked != want {
+			t.Errorf("access token of user %d revoked = %v", store.accessTokens[i].UserID, revoked)
+		}
+	}
+	if len(invited) != 1 || invited[0] != event.ID {
+		t.Errorf("invited to %v, want [%d]", invited, event.ID)
+	}
+}
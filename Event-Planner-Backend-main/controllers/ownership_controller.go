@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// TransferOwnershipRequest defines the payload to nominate a new owner.
+type TransferOwnershipRequest struct {
+	UserID uint `json:"userId" binding:"required"`
+}
+
+// RequestOwnershipTransfer nominates another organizer as the creator of an
+// event (creator only). The nominee becomes the creator once they accept. A
+// new nomination cancels the pending one.
+func (s *Service) RequestOwnershipTransfer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var transfer models.EventOwnershipTransfer
+	err := s.store.Transaction(func(store repository.Store) error {
+		event, ok := lockOrganizedEvent(c, store, userID, "only the event creator can transfer ownership")
+		if !ok {
+			return errResponded
+		}
+		if event.CreatedBy != userID {
+			utils.JSONError(c, http.StatusForbidden, "only the event creator can transfer ownership")
+			return errResponded
+		}
+		if req.UserID == userID {
+			utils.JSONError(c, http.StatusBadRequest, "you already own this event")
+			return errResponded
+		}
+
+		nominee, ok := loadAttendee(c, store, event, req.UserID)
+		if !ok {
+			return errResponded
+		}
+		if nominee.Role != "organizer" {
+			utils.JSONError(c, http.StatusConflict, "ownership can only be transferred to an organizer")
+			return errResponded
+		}
+
+		if err := cancelOwnershipTransfers(store, event.ID, 0); err != nil {
+			return err
+		}
+
+		transfer = models.EventOwnershipTransfer{
+			EventID:     event.ID,
+			FromUserID:  userID,
+			ToUserID:    req.UserID,
+			Status:      "pending",
+			RequestedAt: time.Now(),
+		}
+		if err := store.Transfers().Create(&transfer); err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("You have been asked to take over ownership of %q.", event.Title)
+		if err := notify(store, req.UserID, &event.ID, "ownership_transfer_requested", message); err != nil {
+			return fmt.Errorf("failed to notify nominee: %w", err)
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create ownership transfer: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, formatOwnershipTransfer(transfer))
+}
+
+// GetOwnershipTransfers returns the ownership transfers of an event, newest
+// first, including the pending one if any (organizers only).
+func (s *Service) GetOwnershipTransfers(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, ok := s.loadOrganizedEvent(c, userID, "only organizers can view ownership transfers")
+	if !ok {
+		return
+	}
+
+	transfers, err := s.store.Transfers().ListByEvent(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch ownership transfers: "+err.Error())
+		return
+	}
+
+	var pending interface{}
+	result := make([]gin.H, len(transfers))
+	for i, transfer := range transfers {
+		result[i] = formatOwnershipTransfer(transfer)
+		if transfer.Status == "pending" {
+			pending = result[i]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":   event.ID,
+		"createdBy": event.CreatedBy,
+		"pending":   pending,
+		"transfers": result,
+	})
+}
+
+// CancelOwnershipTransfer withdraws the pending nomination (creator only).
+func (s *Service) CancelOwnershipTransfer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var event models.Event
+	var transfer models.EventOwnershipTransfer
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only the event creator can cancel an ownership transfer"); !ok {
+			return errResponded
+		}
+		if event.CreatedBy != userID {
+			utils.JSONError(c, http.StatusForbidden, "only the event creator can cancel an ownership transfer")
+			return errResponded
+		}
+
+		if transfer, ok = loadPendingTransfer(c, store, event, 0); !ok {
+			return errResponded
+		}
+		return cancelOwnershipTransfers(store, event.ID, 0)
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to cancel ownership transfer: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "ownership transfer cancelled",
+		"eventId":    event.ID,
+		"transferId": transfer.ID,
+	})
+}
+
+// AcceptOwnershipTransfer makes the nominee the creator of the event. The
+// event, the organizer rows of both users and the transfer are updated in one
+// transaction, and the change is recorded in the event history.
+func (s *Service) AcceptOwnershipTransfer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var event models.Event
+	var transfer models.EventOwnershipTransfer
+	now := time.Now()
+	err := s.store.Transaction(func(store repository.Store) error {
+		// The nominee must still be an organizer
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can take over an event"); !ok {
+			return errResponded
+		}
+
+		if transfer, ok = loadPendingTransfer(c, store, event, userID); !ok {
+			return errResponded
+		}
+
+		users, err := store.Users().FindByIDs([]uint{transfer.FromUserID, userID})
+		if err != nil {
+			return fmt.Errorf("failed to fetch users: %w", err)
+		}
+		names := map[uint]string{}
+		for _, u := range users {
+			names[u.ID] = u.Name
+		}
+
+		if err := store.Events().Update(event.ID, map[string]interface{}{"created_by": userID}); err != nil {
+			return err
+		}
+
+		// Both owners keep an organizer record: the former creator may have none
+		for _, id := range []uint{transfer.FromUserID, userID} {
+			if err := store.Attendees().MakeOrganizer(event.ID, id, now); err != nil {
+				return fmt.Errorf("failed to update organizers: %w", err)
+			}
+		}
+
+		if err := store.Transfers().Respond(transfer.ID, "accepted", now); err != nil {
+			return fmt.Errorf("failed to update ownership transfer: %w", err)
+		}
+
+		change := models.EventChange{
+			EventID:   event.ID,
+			ChangedBy: userID,
+			Field:     "owner",
+			OldValue:  names[transfer.FromUserID],
+			NewValue:  names[userID],
+			ChangedAt: now,
+		}
+		if err := store.Changes().Create([]models.EventChange{change}); err != nil {
+			return fmt.Errorf("failed to record event changes: %w", err)
+		}
+
+		message := fmt.Sprintf("%s accepted ownership of %q. You remain an organizer.", names[userID], event.Title)
+		if err := notify(store, transfer.FromUserID, &event.ID, "ownership_transferred", message); err != nil {
+			return fmt.Errorf("failed to notify former owner: %w", err)
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to transfer ownership: "+err.Error())
+		return
+	}
+
+	transfer.Status = "accepted"
+	transfer.RespondedAt = &now
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "ownership transferred successfully",
+		"eventId":  event.ID,
+		"transfer": formatOwnershipTransfer(transfer),
+	})
+}
+
+// DeclineOwnershipTransfer refuses a nomination; the creator is notified.
+func (s *Service) DeclineOwnershipTransfer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	eventID, ok := idParam(c, "id", "event")
+	if !ok || !s.available(c) {
+		return
+	}
+
+	var event models.Event
+	var transfer models.EventOwnershipTransfer
+	err := s.store.Transaction(func(store repository.Store) error {
+		var err error
+		if event, err = store.Events().FindByIDForUpdate(eventID); err != nil {
+			if err == repository.ErrNotFound {
+				utils.JSONError(c, http.StatusNotFound, "event not found")
+				return errResponded
+			}
+			return fmt.Errorf("failed to fetch event: %w", err)
+		}
+
+		var ok bool
+		if transfer, ok = loadPendingTransfer(c, store, event, userID); !ok {
+			return errResponded
+		}
+
+		if err := store.Transfers().Respond(transfer.ID, "declined", time.Now()); err != nil {
+			return fmt.Errorf("failed to update ownership transfer: %w", err)
+		}
+
+		message := fmt.Sprintf("Your request to transfer ownership of %q was declined.", event.Title)
+		if err := notify(store, transfer.FromUserID, &event.ID, "ownership_transfer_declined", message); err != nil {
+			return fmt.Errorf("failed to notify owner: %w", err)
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to decline ownership transfer: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "ownership transfer declined",
+		"eventId":    event.ID,
+		"transferId": transfer.ID,
+	})
+}
+
+// loadPendingTransfer loads the pending ownership transfer of event, to
+// nomineeID unless it is 0. It writes the error response and returns ok=false
+// on failure.
+func loadPendingTransfer(c *gin.Context, store repository.Store, event models.Event, nomineeID uint) (models.EventOwnershipTransfer, bool) {
+	transfer, err := store.Transfers().FindPending(event.ID, nomineeID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "no pending ownership transfer")
+			return transfer, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch ownership transfer: "+err.Error())
+		return transfer, false
+	}
+	return transfer, true
+}
+
+// cancelOwnershipTransfers cancels the pending ownership transfer of an
+// event, only if it nominates nomineeID unless that is 0.
+func cancelOwnershipTransfers(store repository.Store, eventID, nomineeID uint) error {
+	if err := store.Transfers().CancelPending(eventID, nomineeID, time.Now()); err != nil {
+		return fmt.Errorf("failed to cancel ownership transfer: %w", err)
+	}
+	return nil
+}
+
+// formatOwnershipTransfer formats a transfer for JSON response.
+func formatOwnershipTransfer(transfer models.EventOwnershipTransfer) gin.H {
+	result := gin.H{
+		"id":          transfer.ID,
+		"eventId":     transfer.EventID,
+		"fromUserId":  transfer.FromUserID,
+		"toUserId":    transfer.ToUserID,
+		"status":      transfer.Status,
+		"requestedAt": transfer.RequestedAt,
+		"respondedAt": transfer.RespondedAt,
+	}
+	if transfer.FromUser.ID != 0 {
+		result["fromUserName"] = transfer.FromUser.Name
+	}
+	if transfer.ToUser.ID != 0 {
+		result["toUserName"] = transfer.ToUser.Name
+	}
+	return result
+}
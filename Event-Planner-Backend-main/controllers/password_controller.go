@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/config"
+	"event_planner_backend/mailer"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// defaultPasswordResetTTL is how long reset tokens stay valid when
+// PASSWORD_RESET_TTL_MINUTES is not set.
+const defaultPasswordResetTTL = time.Hour
+
+// errResetTokenInvalid is returned for unknown, used or expired reset tokens.
+var errResetTokenInvalid = errors.New("reset token is invalid or has expired")
+
+// ForgotPasswordRequest defines the payload to request a password reset.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest defines the payload to set a new password.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// passwordResetTTL returns the validity of new reset tokens.
+func passwordResetTTL() time.Duration {
+	if minutes, err := strconv.Atoi(config.GetEnv("PASSWORD_RESET_TTL_MINUTES", "")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultPasswordResetTTL
+}
+
+// passwordResetURL returns the link of the page where the user chooses a new
+// password, from PASSWORD_RESET_URL.
+func passwordResetURL(token string) string {
+	return linkWithToken(config.GetEnv("PASSWORD_RESET_URL", "http://localhost:4200/reset-password"), token)
+}
+
+// linkWithToken adds token to the query of a frontend page URL.
+func linkWithToken(base, token string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+// sendMail delivers msg in the background, so that the response time does
+// not reveal whether an email was sent. Failures are logged.
+func sendMail(msg mailer.Message) {
+	go func() {
+		if err := mailer.Send(msg); err != nil {
+			log.Printf("failed to send email to %s: %v", msg.To, err)
+		}
+	}()
+}
+
+// ForgotPassword mails a password reset link to the user of an email. The
+// response is the same whether the email has an account or not. Earlier
+// reset links of the user stop working.
+func (s *Service) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	var user models.User
+	var token string
+	ttl := passwordResetTTL()
+	err := s.store.Transaction(func(store repository.Store) error {
+		var err error
+		if user, err = store.Users().FindByEmail(email); err != nil {
+			if err == repository.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+
+		now := time.Now()
+		if err := store.PasswordResets().UseAll(user.ID, now); err != nil {
+			return err
+		}
+		if token, err = utils.GenerateToken(32); err != nil {
+			return err
+		}
+		reset := models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+		return store.PasswordResets().Create(&reset)
+	})
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create reset token: "+err.Error())
+		return
+	}
+
+	if token != "" {
+		sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your Event Planner password",
+			Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your Event Planner account. "+
+				"To choose a new password, open this link within %d minutes:\n\n%s\n\n"+
+				"If you did not ask for it, ignore this email: your password stays the same.\n",
+				user.Name, int(ttl.Minutes()), passwordResetURL(token)),
+		})
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "if an account uses this email, a password reset link has been sent to it",
+	})
+}
+
+// ResetPassword sets a new password with a reset token. The token is used up,
+// every session and personal access token of the user is revoked, and their
+// email counts as verified.
+func (s *Service) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	var user models.User
+	err = s.store.Transaction(func(store repository.Store) error {
+		reset, err := store.PasswordResets().FindByHashForUpdate(utils.HashToken(strings.TrimSpace(req.Token)))
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+		if reset.UsedAt != nil || !time.Now().Before(reset.ExpiresAt) {
+			return errResetTokenInvalid
+		}
+		if user, err = store.Users().FindByID(reset.UserID); err != nil {
+			if err == repository.ErrNotFound {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if err := store.Users().Update(user.ID, map[string]interface{}{"password_hash": hash}); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		if user.EmailVerifiedAt == nil {
+			// The reset link proved access to the email
+			if err := store.Users().Update(user.ID, map[string]interface{}{"email_verified_at": now}); err != nil {
+				return fmt.Errorf("failed to verify email: %w", err)
+			}
+		}
+		if err := store.PasswordResets().UseAll(user.ID, now); err != nil {
+			return fmt.Errorf("failed to update reset token: %w", err)
+		}
+		if err := logOutEverywhere(store, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errResetTokenInvalid {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your Event Planner password was changed",
+		Body: fmt.Sprintf("Hello %s,\n\nThe password of your Event Planner account was just reset, "+
+			"and you were logged out everywhere. If you did not do it, reset your password again right away.\n",
+			user.Name),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password has been reset; please log in again",
+	})
+}
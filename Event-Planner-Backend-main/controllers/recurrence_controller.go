@@ -0,0 +1,1174 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// defaultOccurrenceWindow is expanded when `to` is not given.
+	defaultOccurrenceWindow = 90 * 24 * time.Hour
+	// maxOccurrenceWindow bounds the date range expanded in one request.
+	maxOccurrenceWindow = 400 * 24 * time.Hour
+	// maxOccurrencesPerEvent bounds the occurrences returned for one event.
+	maxOccurrencesPerEvent = 1000
+)
+
+// UpdateOccurrenceRequest represents the payload for editing occurrences of
+// a recurring event. Omitted fields are left untouched.
+type UpdateOccurrenceRequest struct {
+	Scope       string  `json:"scope" binding:"omitempty,oneof=this following all"` // Default: this
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+	Location    *string `json:"location" binding:"omitempty,min=1,max=255"`
+	StartsAt    *string `json:"startsAt"` // New start of the addressed occurrence
+	EndsAt      *string `json:"endsAt"`
+	Duration    *int    `json:"durationMinutes" binding:"omitempty,min=1"`
+}
+
+// recurrenceSet is the parsed recurrence of an event: the occurrences of the
+// rule starting at dtstart, plus rdates, minus exdates.
+type recurrenceSet struct {
+	dtstart time.Time    // First occurrence, in the event time zone
+	rule    *utils.RRule // Nil when the event has no RRULE
+	exdates []time.Time
+	rdates  []time.Time
+}
+
+// occurrence is a single expanded occurrence of an event.
+type occurrence struct {
+	Start       time.Time // Start given by the recurrence, identifies the occurrence
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Title       string
+	Description string
+	Location    string
+	Modified    bool
+}
+
+// loadRecurrence parses the recurrence stored on an event.
+func loadRecurrence(event models.Event) (recurrenceSet, error) {
+	loc := eventLocation(event)
+	set := recurrenceSet{dtstart: event.StartsAt.In(loc)}
+	if event.RRule != "" {
+		rule, err := utils.ParseRRule(event.RRule, loc)
+		if err != nil {
+			return set, fmt.Errorf("invalid rrule: %w", err)
+		}
+		set.rule = rule
+	}
+	var err error
+	if set.exdates, err = utils.ParseICalDateList(event.ExDates, time.UTC); err != nil {
+		return set, fmt.Errorf("invalid exdates: %w", err)
+	}
+	if set.rdates, err = utils.ParseICalDateList(event.RDates, time.UTC); err != nil {
+		return set, fmt.Errorf("invalid rdates: %w", err)
+	}
+	return set, nil
+}
+
+// includes reports whether t is the start of an occurrence of the set.
+func (s recurrenceSet) includes(t time.Time) bool {
+	if containsInstant(s.exdates, t) {
+		return false
+	}
+	if t.Equal(s.dtstart) || containsInstant(s.rdates, t) {
+		return true
+	}
+	return s.rule != nil && s.rule.Includes(s.dtstart, t)
+}
+
+// between returns the sorted occurrence starts in [from, to).
+func (s recurrenceSet) between(from, to time.Time) []time.Time {
+	var candidates []time.Time
+	if s.rule != nil {
+		candidates = s.rule.Between(s.dtstart, from, to, maxOccurrencesPerEvent)
+	} else if !s.dtstart.Before(from) && s.dtstart.Before(to) {
+		candidates = append(candidates, s.dtstart)
+	}
+	for _, t := range s.rdates {
+		if !t.Before(from) && t.Before(to) && !containsInstant(candidates, t) {
+			candidates = append(candidates, t.In(s.dtstart.Location()))
+		}
+	}
+
+	var starts []time.Time
+	for _, t := range candidates {
+		if !containsInstant(s.exdates, t) {
+			starts = append(starts, t)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// truncate returns the stored recurrence fields of the set ending right
+// before start, e.g. when the following occurrences are cancelled.
+func (s recurrenceSet) truncate(start time.Time) (rrule, exdates, rdates string) {
+	if s.rule != nil {
+		head := *s.rule
+		if head.Count > 0 {
+			head.Count = s.rule.CountBefore(s.dtstart, start)
+		} else {
+			head.Until = start.Add(-time.Second)
+		}
+		rrule = head.String()
+	}
+	exBefore, _ := splitInstants(s.exdates, start)
+	rBefore, _ := splitInstants(s.rdates, start)
+	return rrule, utils.FormatICalDateList(exBefore), utils.FormatICalDateList(rBefore)
+}
+
+// containsInstant reports whether times contains t.
+func containsInstant(times []time.Time, t time.Time) bool {
+	for _, value := range times {
+		if value.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitInstants splits times into those before t and those at or after it.
+func splitInstants(times []time.Time, t time.Time) (before, after []time.Time) {
+	for _, value := range times {
+		if value.Before(t) {
+			before = append(before, value)
+		} else {
+			after = append(after, value)
+		}
+	}
+	return before, after
+}
+
+// newOccurrence builds the occurrence of event starting at start, applying
+// the override when there is one.
+func newOccurrence(event models.Event, start time.Time, override *models.EventOccurrence) occurrence {
+	occ := occurrence{
+		Start:       start,
+		StartsAt:    start,
+		EndsAt:      start.Add(event.EndsAt.Sub(event.StartsAt)),
+		Title:       event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+	}
+	if override == nil {
+		return occ
+	}
+	if override.Title != nil {
+		occ.Title, occ.Modified = *override.Title, true
+	}
+	if override.Description != nil {
+		occ.Description, occ.Modified = *override.Description, true
+	}
+	if override.Location != nil {
+		occ.Location, occ.Modified = *override.Location, true
+	}
+	if override.StartsAt != nil {
+		occ.StartsAt, occ.Modified = *override.StartsAt, true
+		occ.EndsAt = occ.StartsAt.Add(event.EndsAt.Sub(event.StartsAt))
+	}
+	if override.EndsAt != nil {
+		occ.EndsAt, occ.Modified = *override.EndsAt, true
+	}
+	return occ
+}
+
+// expandOccurrences returns the occurrences of an event that overlap
+// [from, to), sorted by start. Occurrences moved into the window by an
+// override are included, those moved out of it are not.
+func expandOccurrences(event models.Event, set recurrenceSet, overrides []models.EventOccurrence, from, to time.Time) []occurrence {
+	byStart := make(map[int64]*models.EventOccurrence, len(overrides))
+	for i := range overrides {
+		byStart[overrides[i].OccurrenceStart.Unix()] = &overrides[i]
+	}
+
+	var result []occurrence
+	seen := map[int64]bool{}
+	duration := event.EndsAt.Sub(event.StartsAt)
+	for _, start := range set.between(from.Add(-duration), to) {
+		seen[start.Unix()] = true
+		occ := newOccurrence(event, start, byStart[start.Unix()])
+		if occ.StartsAt.Before(to) && occ.EndsAt.After(from) {
+			result = append(result, occ)
+		}
+	}
+	for i := range overrides {
+		override := &overrides[i]
+		if override.StartsAt == nil || seen[override.OccurrenceStart.Unix()] {
+			continue
+		}
+		occ := newOccurrence(event, override.OccurrenceStart.In(set.dtstart.Location()), override)
+		if occ.StartsAt.Before(to) && occ.EndsAt.After(from) && set.includes(override.OccurrenceStart) {
+			result = append(result, occ)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].StartsAt.Before(result[j].StartsAt) })
+	if len(result) > maxOccurrencesPerEvent {
+		result = result[:maxOccurrencesPerEvent]
+	}
+	return result
+}
+
+// occurrenceID formats the identifier of an occurrence, its recurrence start
+// as an iCalendar UTC date-time (e.g. "20240715T160000Z").
+func occurrenceID(start time.Time) string {
+	return utils.FormatICalUTC(start)
+}
+
+// parseOccurrenceID parses an occurrence identifier; RFC 3339 is accepted too.
+func parseOccurrenceID(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return utils.ParseICalDateTime(value, time.UTC)
+}
+
+// findOccurrence resolves the :occurrenceId parameter against the recurrence
+// of an event. It writes the error response and returns ok=false when the
+// event is not recurring or has no such occurrence.
+func findOccurrence(c *gin.Context, event models.Event, set recurrenceSet) (time.Time, bool) {
+	if !event.IsRecurring() {
+		utils.JSONError(c, http.StatusBadRequest, "event is not recurring")
+		return time.Time{}, false
+	}
+	start, err := parseOccurrenceID(c.Param("occurrenceId"))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid occurrence ID. Use the occurrenceId of a listed occurrence, e.g. 20240715T160000Z")
+		return time.Time{}, false
+	}
+	if !set.includes(start) {
+		utils.JSONError(c, http.StatusNotFound, "occurrence not found")
+		return time.Time{}, false
+	}
+	return start.In(set.dtstart.Location()), true
+}
+
+// hasOccurrenceWindow reports whether the request asks for occurrences in a
+// date window.
+func hasOccurrenceWindow(c *gin.Context) bool {
+	return c.Query("from") != "" || c.Query("to") != ""
+}
+
+// occurrenceWindow reads the `from` and `to` query parameters, as RFC 3339
+// instants or dates (YYYY-MM-DD) in the viewer's time zone. `from` defaults
+// to now and `to` to 90 days after `from`. It writes a 400 response and
+// returns ok=false for invalid or too large windows.
+func occurrenceWindow(c *gin.Context, viewerLoc *time.Location) (from, to time.Time, ok bool) {
+	loc := viewerLoc
+	if loc == nil {
+		loc = time.UTC
+	}
+	parse := func(value string) (time.Time, error) {
+		if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+			return t, nil
+		}
+		return utils.ParseInstant(value, loc)
+	}
+
+	from = time.Now()
+	if value := c.Query("from"); value != "" {
+		t, err := parse(value)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid from: "+err.Error())
+			return from, to, false
+		}
+		from = t
+	}
+	to = from.Add(defaultOccurrenceWindow)
+	if value := c.Query("to"); value != "" {
+		t, err := parse(value)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid to: "+err.Error())
+			return from, to, false
+		}
+		to = t
+	}
+	if !to.After(from) {
+		utils.JSONError(c, http.StatusBadRequest, "to must be after from")
+		return from, to, false
+	}
+	if to.Sub(from) > maxOccurrenceWindow {
+		utils.JSONError(c, http.StatusBadRequest, "date window cannot exceed 400 days")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// loadOccurrenceData fetches the overrides of the given events and, when
+// userID is set, that user's per-occurrence responses keyed by event and
+// occurrence start.
+func loadOccurrenceData(store repository.Store, eventIDs []uint, userID uint) (map[uint][]models.EventOccurrence, map[uint]map[int64]string, error) {
+	overrides := map[uint][]models.EventOccurrence{}
+	statuses := map[uint]map[int64]string{}
+	if len(eventIDs) == 0 {
+		return overrides, statuses, nil
+	}
+
+	rows, err := store.Occurrences().Overrides(eventIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		overrides[row.EventID] = append(overrides[row.EventID], row)
+	}
+
+	if userID != 0 {
+		responses, err := store.Occurrences().Responses(eventIDs, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range responses {
+			if statuses[r.EventID] == nil {
+				statuses[r.EventID] = map[int64]string{}
+			}
+			statuses[r.EventID][r.OccurrenceStart.Unix()] = r.Status
+		}
+	}
+	return overrides, statuses, nil
+}
+
+// expandEventOccurrences lists the occurrences of events in [from, to),
+// sorted by start. When membership holds the user's attendee record for an
+// event, its occurrences carry the role and the response to each occurrence.
+func expandEventOccurrences(store repository.Store, events []models.Event, from, to time.Time, viewerLoc *time.Location, membership map[uint]models.EventAttendee) ([]gin.H, error) {
+	eventIDs := make([]uint, len(events))
+	for i, event := range events {
+		eventIDs[i] = event.ID
+	}
+	var userID uint
+	for _, attendee := range membership {
+		userID = attendee.UserID
+		break
+	}
+	overrides, statuses, err := loadOccurrenceData(store, eventIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	type item struct {
+		startsAt time.Time
+		response gin.H
+	}
+	var items []item
+	for _, event := range events {
+		set, err := loadRecurrence(event)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.ID, err)
+		}
+		for _, occ := range expandOccurrences(event, set, overrides[event.ID], from, to) {
+			response := formatOccurrenceResponse(event, occ, viewerLoc)
+			if attendee, ok := membership[event.ID]; ok {
+				status := attendee.Status
+				if s, ok := statuses[event.ID][occ.Start.Unix()]; ok {
+					status = s
+				}
+				response["role"] = attendee.Role
+				response["status"] = status
+				response["invitedAt"] = attendee.InvitedAt
+			}
+			items = append(items, item{occ.StartsAt, response})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].startsAt.Before(items[j].startsAt) })
+	result := make([]gin.H, len(items))
+	for i, it := range items {
+		result[i] = it.response
+	}
+	return result, nil
+}
+
+// formatOccurrenceResponse formats an occurrence like formatEventResponse,
+// with the occurrence's own schedule and details.
+func formatOccurrenceResponse(event models.Event, occ occurrence, viewerLoc *time.Location) gin.H {
+	loc := viewerLoc
+	if loc == nil {
+		loc = eventLocation(event)
+	}
+	startsAt, endsAt := occ.StartsAt.In(loc), occ.EndsAt.In(loc)
+
+	response := formatEventResponse(event, viewerLoc)
+	response["title"] = occ.Title
+	response["description"] = occ.Description
+	response["location"] = occ.Location
+	response["startsAt"] = startsAt.Format(time.RFC3339)
+	response["endsAt"] = endsAt.Format(time.RFC3339)
+	response["eventDate"] = startsAt.Format("2006-01-02")
+	response["eventTime"] = startsAt.Format("15:04:05")
+	if event.IsRecurring() {
+		response["occurrenceId"] = occurrenceID(occ.Start)
+		response["originalStartsAt"] = occ.Start.In(loc).Format(time.RFC3339)
+		response["modified"] = occ.Modified
+	}
+	return response
+}
+
+// shiftOccurrenceRecords re-keys the overrides and per-occurrence responses
+// of an event after its start moved. Rows are recreated rather than updated
+// in place, as shifted keys may collide with not yet shifted ones.
+func shiftOccurrenceRecords(store repository.Store, eventID uint, shift func(time.Time) time.Time) error {
+	overrides, err := store.Occurrences().Overrides([]uint{eventID})
+	if err != nil {
+		return fmt.Errorf("failed to fetch occurrence overrides: %w", err)
+	}
+	responses, err := store.Occurrences().Responses([]uint{eventID}, 0)
+	if err != nil {
+		return fmt.Errorf("failed to fetch occurrence responses: %w", err)
+	}
+
+	if len(responses) > 0 {
+		for i := range responses {
+			responses[i].OccurrenceStart = shift(responses[i].OccurrenceStart)
+		}
+		if err := store.Occurrences().ReplaceResponses(eventID, responses); err != nil {
+			return fmt.Errorf("failed to move occurrence responses: %w", err)
+		}
+	}
+	if len(overrides) > 0 {
+		for i := range overrides {
+			overrides[i].OccurrenceStart = shift(overrides[i].OccurrenceStart)
+		}
+		if err := store.Occurrences().ReplaceOverrides(eventID, overrides); err != nil {
+			return fmt.Errorf("failed to move occurrence overrides: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteOccurrenceRecords deletes the overrides and responses of the
+// occurrences of an event starting at start, or at or after it when
+// following is set.
+func deleteOccurrenceRecords(store repository.Store, eventID uint, start time.Time, following bool) error {
+	if err := store.Occurrences().Delete(eventID, start, following); err != nil {
+		return fmt.Errorf("failed to delete occurrence records: %w", err)
+	}
+	return nil
+}
+
+// updateRecurrence stores new recurrence fields on an event and records the
+// changes made.
+func updateRecurrence(store repository.Store, event models.Event, userID uint, rrule, exdates, rdates string) error {
+	updates := map[string]interface{}{}
+	var changes []models.EventChange
+	for _, f := range []struct{ field, oldValue, newValue string }{
+		{"rrule", event.RRule, rrule},
+		{"exdates", event.ExDates, exdates},
+		{"rdates", event.RDates, rdates},
+	} {
+		if f.oldValue == f.newValue {
+			continue
+		}
+		updates[f.field] = f.newValue
+		changes = append(changes, models.EventChange{
+			EventID:   event.ID,
+			ChangedBy: userID,
+			Field:     f.field,
+			OldValue:  f.oldValue,
+			NewValue:  f.newValue,
+			ChangedAt: time.Now(),
+		})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := store.Events().Update(event.ID, updates); err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+	if err := store.Changes().Create(changes); err != nil {
+		return fmt.Errorf("failed to record event changes: %w", err)
+	}
+	return nil
+}
+
+// lockOrganizedEvent loads and locks the event of the request in store and
+// checks that the user organizes it. It writes the error response and
+// returns ok=false on failure; the caller rolls back.
+func lockOrganizedEvent(c *gin.Context, store repository.Store, userID uint, forbidden string) (models.Event, bool) {
+	eventID, ok := idParam(c, "id", "event")
+	if !ok {
+		return models.Event{}, false
+	}
+	event, err := store.Events().FindByIDForUpdate(eventID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "event not found")
+			return event, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return event, false
+	}
+
+	role, err := store.Attendees().Role(event, userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+		return event, false
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, forbidden)
+		return event, false
+	}
+	return event, true
+}
+
+// GetEventOccurrences lists the occurrences of an event within the `from`/`to`
+// window (event members only), with the user's response to each.
+func (s *Service) GetEventOccurrences(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := occurrenceWindow(c, viewerLoc)
+	if !ok {
+		return
+	}
+
+	event, ok := s.findEvent(c)
+	if !ok {
+		return
+	}
+
+	attendee, err := s.store.Attendees().Find(event.ID, userID)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+			return
+		}
+		if event.CreatedBy != userID {
+			utils.JSONError(c, http.StatusForbidden, "you are not authorized to view this event")
+			return
+		}
+		attendee = models.EventAttendee{EventID: event.ID, UserID: userID, Role: "organizer", Status: "going"}
+	}
+
+	occurrences, err := expandEventOccurrences(s.store, []models.Event{event}, from, to, viewerLoc,
+		map[uint]models.EventAttendee{event.ID: attendee})
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to expand occurrences: "+err.Error())
+		return
+	}
+	for _, occ := range occurrences {
+		occ["myRole"], occ["myStatus"] = occ["role"], occ["status"]
+		delete(occ, "role")
+		delete(occ, "status")
+		delete(occ, "invitedAt")
+		delete(occ, "attendees")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":     event.ID,
+		"from":        from.Format(time.RFC3339),
+		"to":          to.Format(time.RFC3339),
+		"occurrences": occurrences,
+	})
+}
+
+// UpdateEventOccurrence edits an occurrence of a recurring event (organizer
+// only). The scope selects what is edited: "this" occurrence only, "this
+// and following" occurrences, which splits the series into a new event from
+// this occurrence on, or "all" occurrences. Moving the start applies the same
+// shift to every occurrence in scope.
+func (s *Service) UpdateEventOccurrence(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req UpdateOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var event, target models.Event
+	var start time.Time
+	var scope string
+	var changes []models.EventChange
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can change occurrences"); !ok {
+			return errResponded
+		}
+
+		set, err := loadRecurrence(event)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return errResponded
+		}
+		if start, ok = findOccurrence(c, event, set); !ok {
+			return errResponded
+		}
+
+		scope = req.Scope
+		if scope == "" {
+			scope = "this"
+		}
+		if scope == "following" && start.Equal(set.dtstart) {
+			scope = "all"
+		}
+		if scope == "following" && (set.rule == nil || !set.rule.Includes(set.dtstart, start)) {
+			// A new series must start on an occurrence of the rule to keep its pattern
+			utils.JSONError(c, http.StatusBadRequest, "scope following is only supported for occurrences generated by the rrule")
+			return errResponded
+		}
+
+		if scope == "this" {
+			if changes, ok = updateSingleOccurrence(c, store, event, start, userID, req); !ok {
+				return errResponded
+			}
+			return nil
+		}
+
+		target = event
+		update := UpdateEventRequest{
+			Title:       req.Title,
+			Description: req.Description,
+			Location:    req.Location,
+			StartsAt:    req.StartsAt,
+			EndsAt:      req.EndsAt,
+			Duration:    req.Duration,
+		}
+		if scope == "following" {
+			// The new series starts at this occurrence, so the request applies to it directly
+			if target, ok = splitSeries(c, store, event, set, start, userID); !ok {
+				return errResponded
+			}
+		} else if update, err = seriesUpdate(event, start, req); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return errResponded
+		}
+
+		if changes, ok = applyEventUpdate(c, store, target, userID, update); !ok {
+			return errResponded
+		}
+		return nil
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update occurrences: "+err.Error())
+		return
+	}
+
+	if scope == "this" {
+		occ := newOccurrence(event, start, nil)
+		if override, err := s.store.Occurrences().FindOverride(event.ID, start); err == nil {
+			occ = newOccurrence(event, start, &override)
+		}
+		response := formatOccurrenceResponse(event, occ, viewerLoc)
+		delete(response, "attendees")
+		response["changes"] = formatEventChanges(changes)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	updated, err := s.store.Events().FindWithDetails(target.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return
+	}
+
+	response := formatEventResponse(updated, viewerLoc)
+	response["changes"] = formatEventChanges(changes)
+	if target.ID != event.ID {
+		response["splitFrom"] = event.ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// updateSingleOccurrence stores an override for one occurrence and records
+// the changes. It writes the error response and returns ok=false on failure.
+func updateSingleOccurrence(c *gin.Context, store repository.Store, event models.Event, start time.Time, userID uint, req UpdateOccurrenceRequest) ([]models.EventChange, bool) {
+	override, err := store.Occurrences().FindOverride(event.ID, start)
+	if err == repository.ErrNotFound {
+		override = models.EventOccurrence{EventID: event.ID, OccurrenceStart: start}
+	} else if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch occurrence: "+err.Error())
+		return nil, false
+	}
+	current := newOccurrence(event, start, &override)
+
+	var changes []models.EventChange
+	record := func(field, oldValue, newValue string) {
+		changes = append(changes, models.EventChange{
+			EventID:    event.ID,
+			ChangedBy:  userID,
+			Field:      field,
+			OldValue:   oldValue,
+			NewValue:   newValue,
+			Occurrence: &start,
+			ChangedAt:  time.Now(),
+		})
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			utils.JSONError(c, http.StatusBadRequest, "title cannot be empty")
+			return nil, false
+		}
+		if title != current.Title {
+			override.Title = &title
+			record("title", current.Title, title)
+		}
+	}
+	if req.Description != nil && *req.Description != current.Description {
+		override.Description = req.Description
+		record("description", current.Description, *req.Description)
+	}
+	if req.Location != nil {
+		location := strings.TrimSpace(*req.Location)
+		if location == "" {
+			utils.JSONError(c, http.StatusBadRequest, "location cannot be empty")
+			return nil, false
+		}
+		if location != current.Location {
+			override.Location = &location
+			record("location", current.Location, location)
+		}
+	}
+
+	loc := eventLocation(event)
+	startsAt, endsAt := current.StartsAt, current.EndsAt
+	if req.StartsAt != nil {
+		if startsAt, err = utils.ParseInstant(*req.StartsAt, loc); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid startsAt: "+err.Error())
+			return nil, false
+		}
+	}
+	switch {
+	case req.EndsAt != nil:
+		if endsAt, err = utils.ParseInstant(*req.EndsAt, loc); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid endsAt: "+err.Error())
+			return nil, false
+		}
+	case req.Duration != nil:
+		endsAt = startsAt.Add(time.Duration(*req.Duration) * time.Minute)
+	default:
+		endsAt = startsAt.Add(current.EndsAt.Sub(current.StartsAt))
+	}
+	if !endsAt.After(startsAt) {
+		utils.JSONError(c, http.StatusBadRequest, "endsAt must be after startsAt")
+		return nil, false
+	}
+	if !startsAt.Equal(current.StartsAt) {
+		override.StartsAt = &startsAt
+		record("startsAt", current.StartsAt.In(loc).Format(time.RFC3339), startsAt.In(loc).Format(time.RFC3339))
+	}
+	if !endsAt.Equal(current.EndsAt) {
+		override.EndsAt = &endsAt
+		record("endsAt", current.EndsAt.In(loc).Format(time.RFC3339), endsAt.In(loc).Format(time.RFC3339))
+	}
+
+	if len(changes) == 0 {
+		return changes, true
+	}
+	override.UpdatedAt = time.Now()
+	if err := store.Occurrences().SaveOverride(&override); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update occurrence: "+err.Error())
+		return nil, false
+	}
+	if err := store.Changes().Create(changes); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to record event changes: "+err.Error())
+		return nil, false
+	}
+	return changes, true
+}
+
+// seriesUpdate converts an edit of one occurrence into an edit of the whole
+// series: a new start or end of the occurrence moves every occurrence the
+// same way.
+func seriesUpdate(event models.Event, start time.Time, req UpdateOccurrenceRequest) (UpdateEventRequest, error) {
+	update := UpdateEventRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Location:    req.Location,
+		Duration:    req.Duration,
+	}
+	if req.StartsAt == nil && req.EndsAt == nil {
+		return update, nil
+	}
+
+	loc := eventLocation(event)
+	occStart := start
+	if req.StartsAt != nil {
+		t, err := utils.ParseInstant(*req.StartsAt, loc)
+		if err != nil {
+			return update, fmt.Errorf("invalid startsAt: %w", err)
+		}
+		occStart = t.In(loc)
+	}
+	seriesStart := shiftWallClock(event.StartsAt, start, occStart)
+	startsAt := seriesStart.Format(time.RFC3339)
+	update.StartsAt = &startsAt
+
+	if req.EndsAt != nil {
+		occEnd, err := utils.ParseInstant(*req.EndsAt, loc)
+		if err != nil {
+			return update, fmt.Errorf("invalid endsAt: %w", err)
+		}
+		if !occEnd.After(occStart) {
+			return update, fmt.Errorf("endsAt must be after startsAt")
+		}
+		endsAt := seriesStart.Add(occEnd.Sub(occStart)).Format(time.RFC3339)
+		update.EndsAt = &endsAt
+	}
+	return update, nil
+}
+
+// splitSeries ends a recurring event before start and continues it as a new
+// event from start on, with the same attendees and tags. Overrides and responses of
+// the later occurrences move to the new event. It writes the error response
+// and returns ok=false on failure.
+func splitSeries(c *gin.Context, store repository.Store, event models.Event, set recurrenceSet, start time.Time, userID uint) (models.Event, bool) {
+	var rrule string
+	if set.rule != nil {
+		tail := *set.rule
+		if tail.Count > 0 {
+			tail.Count -= set.rule.CountBefore(set.dtstart, start)
+		}
+		// A counted rule with no occurrences left would otherwise become unbounded
+		if set.rule.Count == 0 || tail.Count > 0 {
+			rrule = tail.String()
+		}
+	}
+	_, exAfter := splitInstants(set.exdates, start)
+	_, rAfter := splitInstants(set.rdates, start)
+	var rdates []time.Time
+	for _, t := range rAfter {
+		if !t.Equal(start) {
+			rdates = append(rdates, t)
+		}
+	}
+
+	next := models.Event{
+		Title:       event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		StartsAt:    start,
+		EndsAt:      start.Add(event.EndsAt.Sub(event.StartsAt)),
+		Timezone:    event.Timezone,
+		RRule:       rrule,
+		ExDates:     utils.FormatICalDateList(exAfter),
+		RDates:      utils.FormatICalDateList(rdates),
+		CreatedBy:   event.CreatedBy,
+	}
+	if err := store.Events().Create(&next); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create event: "+err.Error())
+		return next, false
+	}
+
+	if err := store.Attendees().CopyToEvent(event.ID, next.ID); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to copy attendees: "+err.Error())
+		return next, false
+	}
+
+	tags, err := store.Events().Tags(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch tags: "+err.Error())
+		return next, false
+	}
+	if err := store.Events().AddTags(next.ID, tags); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to copy tags: "+err.Error())
+		return next, false
+	}
+
+	if err := store.Occurrences().MoveFollowing(event.ID, next.ID, start); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to move occurrences: "+err.Error())
+		return next, false
+	}
+
+	headRule, headEx, headR := set.truncate(start)
+	if err := updateRecurrence(store, event, userID, headRule, headEx, headR); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return next, false
+	}
+	return next, true
+}
+
+// DeleteEventOccurrence cancels occurrences of a recurring event. The
+// `scope` query parameter selects "this" occurrence (default), "this and
+// following" occurrences or "all" of them, which deletes the event.
+func (s *Service) DeleteEventOccurrence(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	scope := c.DefaultQuery("scope", "this")
+	if scope != "this" && scope != "following" && scope != "all" {
+		utils.JSONError(c, http.StatusBadRequest, "invalid scope. Use this, following or all")
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var event models.Event
+	var start time.Time
+	err := s.store.Transaction(func(store repository.Store) error {
+		var ok bool
+		if event, ok = lockOrganizedEvent(c, store, userID, "only organizers can change occurrences"); !ok {
+			return errResponded
+		}
+
+		set, err := loadRecurrence(event)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return errResponded
+		}
+		if start, ok = findOccurrence(c, event, set); !ok {
+			return errResponded
+		}
+		if scope == "following" && start.Equal(set.dtstart) {
+			scope = "all"
+		}
+
+		switch scope {
+		case "all":
+			// Same rule as DeleteEvent
+			if event.CreatedBy != userID {
+				utils.JSONError(c, http.StatusForbidden, "only the event creator can delete all occurrences")
+				return errResponded
+			}
+			return store.Events().DeleteCascade(event)
+		case "following":
+			rrule, exdates, rdates := set.truncate(start)
+			if err := deleteOccurrenceRecords(store, event.ID, start, true); err != nil {
+				return err
+			}
+			return updateRecurrence(store, event, userID, rrule, exdates, rdates)
+		default:
+			excluded := append(set.exdates, start)
+			sort.Slice(excluded, func(i, j int) bool { return excluded[i].Before(excluded[j]) })
+			exdates := utils.FormatICalDateList(excluded)
+			if err := deleteOccurrenceRecords(store, event.ID, start, false); err != nil {
+				return err
+			}
+			if err := store.Events().Update(event.ID, map[string]interface{}{"exdates": exdates}); err != nil {
+				return err
+			}
+			return store.Changes().Create([]models.EventChange{{
+				EventID:    event.ID,
+				ChangedBy:  userID,
+				Field:      "occurrence",
+				OldValue:   "scheduled",
+				NewValue:   "cancelled",
+				Occurrence: &start,
+				ChangedAt:  time.Now(),
+			}})
+		}
+	})
+	if err == errResponded {
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to cancel occurrences: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "occurrences cancelled successfully",
+		"eventId":      event.ID,
+		"occurrenceId": occurrenceID(start),
+		"scope":        scope,
+	})
+}
+
+// UpdateOccurrenceAttendance sets the user's response to a single occurrence
+// of a recurring event. Other occurrences keep the status set through
+// UpdateAttendanceStatus.
+func (s *Service) UpdateOccurrenceAttendance(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req UpdateAttendanceStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	event, ok := s.findEvent(c)
+	if !ok {
+		return
+	}
+
+	set, err := loadRecurrence(event)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	start, ok := findOccurrence(c, event, set)
+	if !ok {
+		return
+	}
+
+	var attendee models.EventAttendee
+	response := models.EventAttendeeOccurrence{
+		EventID:         event.ID,
+		UserID:          userID,
+		OccurrenceStart: start,
+		Status:          req.Status,
+		UpdatedAt:       time.Now(),
+	}
+	err = s.store.Transaction(func(store repository.Store) error {
+		// Lock the event so that concurrent answers see the same free places
+		locked, err := store.Events().FindByIDForUpdate(event.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch event: %w", err)
+		}
+
+		// Responses are tracked for invited users only
+		if attendee, err = store.Attendees().Find(locked.ID, userID); err != nil {
+			if err != repository.ErrNotFound {
+				return fmt.Errorf("failed to fetch attendee record: %w", err)
+			}
+			if locked.CreatedBy != userID {
+				return errNotInvited
+			}
+			// Pending, so that the series status does not count the creator
+			// as going to occurrences that may be full
+			attendee = models.EventAttendee{EventID: locked.ID, UserID: userID, Role: "organizer", Status: "pending"}
+			if err := store.Attendees().Create(&attendee); err != nil {
+				return fmt.Errorf("failed to create attendee record: %w", err)
+			}
+		}
+
+		if req.Status == "going" {
+			if attendee.Status == "waitlisted" {
+				return errOnWaitlist
+			}
+			room, err := hasOccurrenceRoom(store, locked, userID, start)
+			if err != nil {
+				return err
+			}
+			if !room {
+				return errOccurrenceFull
+			}
+		}
+
+		if err := store.Occurrences().SaveResponse(&response); err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		switch err {
+		case errNotInvited:
+			utils.JSONError(c, http.StatusForbidden, err.Error())
+		case errOnWaitlist, errOccurrenceFull:
+			utils.JSONError(c, http.StatusConflict, err.Error())
+		default:
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "attendance status updated successfully",
+		"eventId":      event.ID,
+		"occurrenceId": occurrenceID(start),
+		"userId":       userID,
+		"status":       response.Status,
+		"role":         attendee.Role,
+	})
+}
+
+// GetOccurrenceAttendees returns the attendees of a single occurrence with
+// their response to it (organizer only).
+func (s *Service) GetOccurrenceAttendees(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, ok := s.loadOrganizedEvent(c, userID, "only organizers can view attendees list")
+	if !ok {
+		return
+	}
+
+	set, err := loadRecurrence(event)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	start, ok := findOccurrence(c, event, set)
+	if !ok {
+		return
+	}
+
+	attendees, err := s.store.Attendees().ListByEvent(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendees: "+err.Error())
+		return
+	}
+
+	responses, err := s.store.Occurrences().ResponsesAt(event.ID, start)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch occurrence responses: "+err.Error())
+		return
+	}
+	statuses := make(map[uint]string, len(responses))
+	for _, r := range responses {
+		statuses[r.UserID] = r.Status
+	}
+
+	response := make([]gin.H, len(attendees))
+	for i, att := range attendees {
+		status, ok := statuses[att.UserID]
+		if !ok {
+			status = att.Status
+		}
+		response[i] = gin.H{
+			"userId":       att.UserID,
+			"userName":     att.User.Name,
+			"userEmail":    att.User.Email,
+			"role":         att.Role,
+			"status":       status,
+			"seriesStatus": att.Status,
+			"invitedAt":    att.InvitedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":      event.ID,
+		"eventTitle":   event.Title,
+		"occurrenceId": occurrenceID(start),
+		"attendees":    response,
+	})
+}
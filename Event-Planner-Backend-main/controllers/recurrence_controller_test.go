@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"event_planner_backend/models"
+)
+
+// addRecurringEvent adds a daily event of organizer limited to capacity
+// attendees going, without an attendee record for organizer.
+func (s *memoryStore) addRecurringEvent(organizer models.User, capacity uint) models.Event {
+	s.addEvent("Standup", organizer)
+	s.attendees = s.attendees[:len(s.attendees)-1]
+	s.events[len(s.events)-1].RRule = "FREQ=DAILY;COUNT=5"
+	s.events[len(s.events)-1].Capacity = &capacity
+	return s.events[len(s.events)-1]
+}
+
+func TestUpdateOccurrenceAttendanceCreatorWithoutRecord(t *testing.T) {
+	tests := []struct {
+		status       string
+		code         int
+		wantResponse bool
+	}{
+		{"going", http.StatusConflict, false},
+		{"maybe", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			store := &memoryStore{}
+			organizer := store.addUser("Olivia", "olivia@example.com")
+			guest := store.addUser("Gus", "gus@example.com")
+			event := store.addRecurringEvent(organizer, 1)
+			store.addAttendee(event, guest, "attendee")
+			store.attendees[0].Status = "going"
+
+			path := fmt.Sprintf("/events/%d/occurrences/%s/attendance", event.ID, occurrenceID(event.StartsAt.AddDate(0, 0, 1)))
+			w := serve(t, NewService(store).UpdateOccurrenceAttendance, http.MethodPut,
+				"/events/:id/occurrences/:occurrenceId/attendance", path, organizer.ID, map[string]string{"status": tt.status})
+			expectStatus(t, w, tt.code)
+
+			attendee, err := store.Attendees().Find(event.ID, organizer.ID)
+			if err != nil || attendee.Role != "organizer" || attendee.Status != "pending" {
+				t.Errorf("attendee = %+v, %v; want a pending organizer record", attendee, err)
+			}
+			if got := len(store.responses) == 1; got != tt.wantResponse {
+				t.Errorf("responses = %+v", store.responses)
+			}
+			// No occurrence of the series is over capacity
+			for day := 0; day < 5; day++ {
+				going, _ := store.Attendees().GoingToOccurrence(event.ID, event.StartsAt.AddDate(0, 0, day))
+				if len(going) > 1 {
+					t.Errorf("day %d: %d going, want at most 1", day, len(going))
+				}
+			}
+		})
+	}
+}
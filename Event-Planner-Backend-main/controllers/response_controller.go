@@ -1,14 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
-	"event_planner_backend/config"
 	"event_planner_backend/middleware"
 	"event_planner_backend/models"
+	"event_planner_backend/repository"
 	"event_planner_backend/utils"
 )
 
@@ -18,16 +20,15 @@ type UpdateAttendanceStatusRequest struct {
 }
 
 // UpdateAttendanceStatus allows an attendee to update their attendance status.
-func UpdateAttendanceStatus(c *gin.Context) {
+func (s *Service) UpdateAttendanceStatus(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
 		return
 	}
 
-	eventID := c.Param("id")
-	if eventID == "" {
-		utils.JSONError(c, http.StatusBadRequest, "event ID required")
+	eventID, ok := idParam(c, "id", "event")
+	if !ok {
 		return
 	}
 
@@ -37,15 +38,14 @@ func UpdateAttendanceStatus(c *gin.Context) {
 		return
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	if !s.available(c) {
 		return
 	}
 
 	// Verify event exists
-	var event models.Event
-	if err := config.DB.First(&event, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	event, err := s.store.Events().FindByID(eventID)
+	if err != nil {
+		if err == repository.ErrNotFound {
 			utils.JSONError(c, http.StatusNotFound, "event not found")
 			return
 		}
@@ -53,95 +53,214 @@ func UpdateAttendanceStatus(c *gin.Context) {
 		return
 	}
 
-	// Find or create attendee record
 	var attendee models.EventAttendee
-	if err := config.DB.Where("event_id = ? AND user_id = ?", eventID, userID).
-		First(&attendee).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// User is not invited, but they can still set status if they're the creator
-			if event.CreatedBy != userID {
-				utils.JSONError(c, http.StatusForbidden, "you are not invited to this event")
-				return
-			}
-			// Create attendee record for creator
-			attendee = models.EventAttendee{
-				EventID: event.ID,
-				UserID:  userID,
-				Role:    "organizer",
-				Status:  req.Status,
-			}
-			if err := config.DB.Create(&attendee).Error; err != nil {
-				utils.JSONError(c, http.StatusInternalServerError, "failed to create attendee record: "+err.Error())
-				return
-			}
-		} else {
-			utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
-			return
-		}
-	} else {
-		// Update existing record
-		attendee.Status = req.Status
-		if err := config.DB.Save(&attendee).Error; err != nil {
-			utils.JSONError(c, http.StatusInternalServerError, "failed to update status: "+err.Error())
+	err = s.store.Transaction(func(store repository.Store) error {
+		var err error
+		attendee, err = setAttendanceStatus(store, event, userID, req.Status)
+		return err
+	})
+	if err != nil {
+		if err == errNotInvited {
+			utils.JSONError(c, http.StatusForbidden, err.Error())
 			return
 		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, attendanceResponse(s.store, attendee))
+}
+
+// attendanceResponse describes the outcome of an attendance update, with the
+// waitlist position when the event was full.
+func attendanceResponse(store repository.Store, attendee models.EventAttendee) gin.H {
+	response := gin.H{
 		"message": "attendance status updated successfully",
-		"eventId": event.ID,
-		"userId":  userID,
+		"eventId": attendee.EventID,
+		"userId":  attendee.UserID,
 		"status":  attendee.Status,
 		"role":    attendee.Role,
-	})
+	}
+	if attendee.Status == "waitlisted" {
+		response["message"] = "event is full; you have been added to the waitlist"
+		if position, err := waitlistPosition(store, attendee); err == nil {
+			response["waitlistPosition"] = position
+		}
+	}
+	return response
 }
 
-// GetEventAttendees returns the list of attendees and their statuses for an event (organizer only).
-func GetEventAttendees(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
-	if !ok {
-		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
-		return
+var (
+	// errNotInvited is returned when a user answers an event they are not invited to.
+	errNotInvited = errors.New("you are not invited to this event")
+	// errOnWaitlist is returned when a waitlisted user answers "going" to an occurrence.
+	errOnWaitlist = errors.New("you are on the waitlist of this event")
+	// errOccurrenceFull is returned when "going" is answered to a full occurrence.
+	errOccurrenceFull = errors.New("this occurrence is full")
+)
+
+// setAttendanceStatus sets the status of a user for an event. The creator
+// gets an organizer attendee record if they have none. Answering "going" to
+// a full event puts the user on the waitlist, and places freed by users no
+// longer going are given to the waitlist.
+//
+// It must run in a transaction: the event row is locked so that concurrent
+// answers see the same number of free places.
+func setAttendanceStatus(store repository.Store, event models.Event, userID uint, status string) (models.EventAttendee, error) {
+	event, err := store.Events().FindByIDForUpdate(event.ID)
+	if err != nil {
+		return models.EventAttendee{}, fmt.Errorf("failed to fetch event: %w", err)
 	}
 
-	eventID := c.Param("id")
-	if eventID == "" {
-		utils.JSONError(c, http.StatusBadRequest, "event ID required")
-		return
+	// Find or create attendee record
+	exists := true
+	attendee, err := store.Attendees().Find(event.ID, userID)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			return attendee, fmt.Errorf("failed to fetch attendee record: %w", err)
+		}
+		// User is not invited, but they can still set status if they're the creator
+		if event.CreatedBy != userID {
+			return attendee, errNotInvited
+		}
+		exists = false
+		attendee = models.EventAttendee{
+			EventID: event.ID,
+			UserID:  userID,
+			Role:    "organizer",
+		}
 	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
-		return
+	previous := attendee.Status
+	if status == "going" && previous != "going" {
+		room, err := hasRoom(store, event)
+		if err != nil {
+			return attendee, err
+		}
+		if !room {
+			status = "waitlisted"
+		}
+	}
+	attendee.Status = status
+	if status != "waitlisted" {
+		attendee.WaitlistedAt = nil
+	} else if attendee.WaitlistedAt == nil {
+		now := time.Now()
+		attendee.WaitlistedAt = &now
 	}
 
-	// Verify event exists
-	var event models.Event
-	if err := config.DB.First(&event, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.JSONError(c, http.StatusNotFound, "event not found")
-			return
+	if !exists {
+		// Create attendee record for creator
+		if err := store.Attendees().Create(&attendee); err != nil {
+			return attendee, fmt.Errorf("failed to create attendee record: %w", err)
 		}
-		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
-		return
+	} else if err := store.Attendees().Save(&attendee); err != nil {
+		// Update existing record
+		return attendee, fmt.Errorf("failed to update status: %w", err)
 	}
 
-	// Check if user is organizer
-	var userAttendee models.EventAttendee
-	if err := config.DB.Where("event_id = ? AND user_id = ? AND role = ?", eventID, userID, "organizer").
-		First(&userAttendee).Error; err != nil {
-		if event.CreatedBy != userID {
-			utils.JSONError(c, http.StatusForbidden, "only organizers can view attendees list")
-			return
+	if previous == "going" && status != "going" {
+		if _, err := promoteWaitlisted(store, event); err != nil {
+			return attendee, err
 		}
 	}
+	return attendee, nil
+}
+
+// hasRoom reports whether one more attendee can go to event. The event row
+// should be locked by the caller.
+func hasRoom(store repository.Store, event models.Event) (bool, error) {
+	if event.Capacity == nil {
+		return true, nil
+	}
+	going, err := store.Attendees().CountGoing(event.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to count attendees: %w", err)
+	}
+	return going < int64(*event.Capacity), nil
+}
+
+// hasOccurrenceRoom reports whether userID can go to the occurrence of event
+// at start: they already go to it, the event has no capacity, or fewer
+// attendees than its capacity go to that occurrence, counting their series
+// status unless they answered the occurrence itself. The event row should be
+// locked by the caller.
+func hasOccurrenceRoom(store repository.Store, event models.Event, userID uint, start time.Time) (bool, error) {
+	if event.Capacity == nil {
+		return true, nil
+	}
+	going, err := store.Attendees().GoingToOccurrence(event.ID, start)
+	if err != nil {
+		return false, fmt.Errorf("failed to count attendees: %w", err)
+	}
+	for _, id := range going {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return len(going) < int(*event.Capacity), nil
+}
+
+// promoteWaitlisted gives the free places of event to the waitlist, earliest
+// first, and notifies the promoted users. The event row should be locked by
+// the caller.
+func promoteWaitlisted(store repository.Store, event models.Event) ([]models.EventAttendee, error) {
+	limit := -1
+	if event.Capacity != nil {
+		going, err := store.Attendees().CountGoing(event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count attendees: %w", err)
+		}
+		free := int64(*event.Capacity) - going
+		if free <= 0 {
+			return nil, nil
+		}
+		limit = int(free)
+	}
+
+	promoted, err := store.Attendees().ListWaitlisted(event.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch waitlist: %w", err)
+	}
+	for i := range promoted {
+		promoted[i].Status = "going"
+		promoted[i].WaitlistedAt = nil
+		if err := store.Attendees().Promote(event.ID, promoted[i].UserID); err != nil {
+			return nil, fmt.Errorf("failed to promote waitlisted attendee: %w", err)
+		}
+		message := fmt.Sprintf("A place opened up for %q: you have been moved from the waitlist to going.", event.Title)
+		if err := notify(store, promoted[i].UserID, &event.ID, "waitlist_promoted", message); err != nil {
+			return nil, fmt.Errorf("failed to notify promoted attendee: %w", err)
+		}
+	}
+	return promoted, nil
+}
+
+// waitlistPosition returns the 1-based position of a waitlisted attendee.
+func waitlistPosition(store repository.Store, attendee models.EventAttendee) (int64, error) {
+	if attendee.Status != "waitlisted" || attendee.WaitlistedAt == nil {
+		return 0, nil
+	}
+	ahead, err := store.Attendees().CountWaitlistedBefore(attendee.EventID, *attendee.WaitlistedAt)
+	return ahead + 1, err
+}
+
+// GetEventAttendees returns the list of attendees and their statuses for an event (organizer only).
+func (s *Service) GetEventAttendees(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, ok := s.loadOrganizedEvent(c, userID, "only organizers can view attendees list")
+	if !ok {
+		return
+	}
 
 	// Get all attendees
-	var attendees []models.EventAttendee
-	if err := config.DB.Where("event_id = ?", eventID).
-		Preload("User").
-		Order("role DESC, invited_at ASC"). // Organizers first
-		Find(&attendees).Error; err != nil {
+	attendees, err := s.store.Attendees().ListByEvent(event.ID)
+	if err != nil {
 		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendees: "+err.Error())
 		return
 	}
@@ -157,6 +276,9 @@ func GetEventAttendees(c *gin.Context) {
 			"status":    att.Status,
 			"invitedAt": att.InvitedAt,
 		}
+		if att.WaitlistedAt != nil {
+			response[i]["waitlistedAt"] = att.WaitlistedAt
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"event_planner_backend/config"
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// defaultRSVPLinkTTL is how long RSVP links stay valid when
+// RSVP_LINK_TTL_HOURS is not set.
+const defaultRSVPLinkTTL = 30 * 24 * time.Hour
+
+// errRSVPLinkInvalid is returned for malformed, expired or foreign RSVP tokens.
+var errRSVPLinkInvalid = errors.New("RSVP link is invalid or has expired")
+
+// rsvpLinkTTL returns the validity of new RSVP links.
+func rsvpLinkTTL() time.Duration {
+	if hours, err := strconv.Atoi(config.GetEnv("RSVP_LINK_TTL_HOURS", "")); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultRSVPLinkTTL
+}
+
+// rsvpLink creates the signed link letting userID answer event without
+// logging in.
+func rsvpLink(c *gin.Context, eventID, userID uint, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateScopedJWT(jwtKeys(), utils.PurposeRSVP, jwt.MapClaims{"event": eventID, "uid": userID}, ttl)
+	if err != nil {
+		return "", err
+	}
+	return publicBaseURL(c) + "/api/rsvp/" + token, nil
+}
+
+// parseRSVPToken returns the event and user an RSVP token was issued for.
+func parseRSVPToken(token string) (eventID, userID uint, err error) {
+	claims, err := utils.ParseScopedJWT(jwtKeys(), utils.PurposeRSVP, token)
+	if err != nil {
+		return 0, 0, errRSVPLinkInvalid
+	}
+	eventID, ok := utils.ClaimUint(claims, "event")
+	if !ok {
+		return 0, 0, errRSVPLinkInvalid
+	}
+	userID, ok = utils.ClaimUint(claims, "uid")
+	if !ok {
+		return 0, 0, errRSVPLinkInvalid
+	}
+	return eventID, userID, nil
+}
+
+// loadRSVP loads the event and attendee record of the RSVP token of the
+// request. It writes the error response and returns ok=false on failure.
+func (s *Service) loadRSVP(c *gin.Context) (models.Event, models.EventAttendee, bool) {
+	var event models.Event
+	var attendee models.EventAttendee
+
+	eventID, userID, err := parseRSVPToken(c.Param("token"))
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return event, attendee, false
+	}
+
+	if !s.available(c) {
+		return event, attendee, false
+	}
+
+	if event, err = s.store.Events().FindByID(eventID); err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "event not found")
+			return event, attendee, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return event, attendee, false
+	}
+
+	if attendee, err = s.store.Attendees().FindWithUser(eventID, userID); err != nil {
+		if err == repository.ErrNotFound {
+			// Invitations withdrawn since the link was sent
+			utils.JSONError(c, http.StatusForbidden, errNotInvited.Error())
+			return event, attendee, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+		return event, attendee, false
+	}
+	return event, attendee, true
+}
+
+// GetEventRSVPLinks returns a signed RSVP link per attendee, to send with
+// invitations (organizers only). Each link answers this event only, for its
+// attendee, until it expires.
+func (s *Service) GetEventRSVPLinks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, ok := s.loadOrganizedEvent(c, userID, "only organizers can create RSVP links")
+	if !ok {
+		return
+	}
+
+	attendees, err := s.store.Attendees().ListByEvent(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendees: "+err.Error())
+		return
+	}
+
+	ttl := rsvpLinkTTL()
+	expiresAt := time.Now().Add(ttl)
+	links := make([]gin.H, len(attendees))
+	for i, att := range attendees {
+		url, err := rsvpLink(c, event.ID, att.UserID, ttl)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to create RSVP link")
+			return
+		}
+		links[i] = gin.H{
+			"userId":    att.UserID,
+			"userName":  att.User.Name,
+			"userEmail": att.User.Email,
+			"role":      att.Role,
+			"status":    att.Status,
+			"rsvpUrl":   url,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":   event.ID,
+		"expiresAt": expiresAt,
+		"links":     links,
+	})
+}
+
+// GetRSVP shows the event and current answer of an RSVP link. It is public:
+// the signed token authenticates it.
+func (s *Service) GetRSVP(c *gin.Context) {
+	event, attendee, ok := s.loadRSVP(c)
+	if !ok {
+		return
+	}
+
+	loc := eventLocation(event)
+	c.JSON(http.StatusOK, gin.H{
+		"eventId":  event.ID,
+		"title":    event.Title,
+		"location": event.Location,
+		"startsAt": event.StartsAt.In(loc).Format(time.RFC3339),
+		"endsAt":   event.EndsAt.In(loc).Format(time.RFC3339),
+		"timezone": event.Timezone,
+		"userName": attendee.User.Name,
+		"status":   attendee.Status,
+	})
+}
+
+// RespondRSVP sets the attendance status of the attendee of an RSVP link,
+// like UpdateAttendanceStatus, without logging in.
+func (s *Service) RespondRSVP(c *gin.Context) {
+	var req UpdateAttendanceStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	event, attendee, ok := s.loadRSVP(c)
+	if !ok {
+		return
+	}
+
+	err := s.store.Transaction(func(store repository.Store) error {
+		var err error
+		attendee, err = setAttendanceStatus(store, event, attendee.UserID, req.Status)
+		return err
+	})
+	if err != nil {
+		if err == errNotInvited {
+			utils.JSONError(c, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, attendanceResponse(s.store, attendee))
+}
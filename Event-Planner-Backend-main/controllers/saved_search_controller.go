@@ -0,0 +1,579 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/config"
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// maxSavedSearches bounds the saved searches of a user.
+	maxSavedSearches = 50
+	// maxSavedSearchMatches bounds the newest events and tasks compared at
+	// each check of a subscribed saved search.
+	maxSavedSearchMatches = 1000
+	// savedSearchBatch bounds the saved searches checked at once.
+	savedSearchBatch = 100
+	// defaultSavedSearchInterval separates the checks of a saved search.
+	defaultSavedSearchInterval = 15 * time.Minute
+)
+
+var (
+	errDuplicateSavedSearch = errors.New("a saved search with this name already exists")
+	errTooManySavedSearches = errors.New("too many saved searches")
+)
+
+// SavedSearchRequest defines the payload to save a search. Search holds the
+// query parameters of GET /api/search.
+type SavedSearchRequest struct {
+	Name   string        `json:"name" binding:"required,max=100"`
+	Search SearchRequest `json:"search"`
+	Notify bool          `json:"notify"`
+}
+
+// UpdateSavedSearchRequest defines the payload to update a saved search.
+// Only provided fields are changed.
+type UpdateSavedSearchRequest struct {
+	Name   *string        `json:"name" binding:"omitempty,max=100"`
+	Search *SearchRequest `json:"search"`
+	Notify *bool          `json:"notify"`
+}
+
+// savedSearchInterval returns the time between two checks of a subscribed
+// saved search.
+func savedSearchInterval() time.Duration {
+	if minutes, err := strconv.Atoi(config.GetEnv("SAVED_SEARCH_INTERVAL_MINUTES", "")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultSavedSearchInterval
+}
+
+// encodeSavedSearch validates req for userID, with dates read in loc, and
+// returns the JSON stored for it.
+func encodeSavedSearch(req SearchRequest, userID uint, loc *time.Location) (string, error) {
+	if err := normalizeSearchRequest(&req); err != nil {
+		return "", err
+	}
+	if _, err := parseSearchFilters(req, userID, loc); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(req)
+	return string(b), err
+}
+
+// decodeSavedSearch returns the normalized search of a saved search and its
+// filters.
+func decodeSavedSearch(search models.SavedSearch) (SearchRequest, repository.SearchFilters, error) {
+	var req SearchRequest
+	if err := json.Unmarshal([]byte(search.Query), &req); err != nil {
+		return req, repository.SearchFilters{}, fmt.Errorf("invalid saved search %d: %w", search.ID, err)
+	}
+	if err := normalizeSearchRequest(&req); err != nil {
+		return req, repository.SearchFilters{}, err
+	}
+	var loc *time.Location
+	if search.Timezone != "" {
+		var err error
+		if loc, err = utils.LoadLocation(search.Timezone); err != nil {
+			return req, repository.SearchFilters{}, err
+		}
+	}
+	filters, err := parseSearchFilters(req, search.UserID, loc)
+	return req, filters, err
+}
+
+// formatSavedSearch formats a saved search for JSON responses.
+func formatSavedSearch(search models.SavedSearch) gin.H {
+	var req SearchRequest
+	_ = json.Unmarshal([]byte(search.Query), &req)
+	return gin.H{
+		"id":        search.ID,
+		"name":      search.Name,
+		"search":    req,
+		"timezone":  search.Timezone,
+		"notify":    search.Notify,
+		"checkedAt": search.CheckedAt,
+		"createdAt": search.CreatedAt,
+		"updatedAt": search.UpdatedAt,
+	}
+}
+
+// loadSavedSearch fetches the saved search from the :searchId param, if it
+// belongs to userID. It writes the error response itself and returns
+// ok=false when the request cannot continue.
+func (s *Service) loadSavedSearch(c *gin.Context, userID uint) (models.SavedSearch, bool) {
+	var search models.SavedSearch
+	searchID, ok := idParam(c, "searchId", "saved search")
+	if !ok {
+		return search, false
+	}
+	if !s.available(c) {
+		return search, false
+	}
+	search, err := s.store.SavedSearches().Find(searchID, userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "saved search not found")
+			return search, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch saved search: "+err.Error())
+		return search, false
+	}
+	return search, true
+}
+
+// GetSavedSearches lists the saved searches of the current user by name.
+func (s *Service) GetSavedSearches(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+	if !s.available(c) {
+		return
+	}
+
+	searches, err := s.store.SavedSearches().List(userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch saved searches: "+err.Error())
+		return
+	}
+
+	result := make([]gin.H, len(searches))
+	for i, search := range searches {
+		result[i] = formatSavedSearch(search)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"savedSearches": result,
+		"count":         len(result),
+	})
+}
+
+// CreateSavedSearch saves a search of the current user. Dates of its filters
+// are read in the viewer time zone of the request. With notify, the current
+// matches are recorded so that only later ones are notified.
+func (s *Service) CreateSavedSearch(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.JSONError(c, http.StatusBadRequest, "name is required")
+		return
+	}
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+	query, err := encodeSavedSearch(req.Search, userID, viewerLoc)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid search: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	search := models.SavedSearch{
+		UserID: userID,
+		Name:   name,
+		Query:  query,
+		Notify: req.Notify,
+	}
+	if viewerLoc != nil {
+		search.Timezone = viewerLoc.String()
+	}
+	err = s.store.Transaction(func(store repository.Store) error {
+		count, err := store.SavedSearches().Count(userID)
+		if err != nil {
+			return err
+		}
+		if count >= maxSavedSearches {
+			return errTooManySavedSearches
+		}
+		taken, err := store.SavedSearches().NameTaken(userID, name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errDuplicateSavedSearch
+		}
+		return store.SavedSearches().Create(&search)
+	})
+	switch {
+	case errors.Is(err, errTooManySavedSearches):
+		utils.JSONError(c, http.StatusConflict, "at most "+strconv.Itoa(maxSavedSearches)+" saved searches; delete unused ones first")
+		return
+	case errors.Is(err, errDuplicateSavedSearch):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		utils.JSONError(c, http.StatusInternalServerError, "failed to save search: "+err.Error())
+		return
+	}
+
+	if search.Notify {
+		s.recordSavedSearchBaseline(&search)
+	}
+	c.JSON(http.StatusCreated, formatSavedSearch(search))
+}
+
+// UpdateSavedSearch renames a saved search of the current user, replaces its
+// search, or turns its notifications on or off.
+func (s *Service) UpdateSavedSearch(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req UpdateSavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	search, ok := s.loadSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			utils.JSONError(c, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		taken, err := s.store.SavedSearches().NameTaken(userID, name, search.ID)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to check name: "+err.Error())
+			return
+		}
+		if taken {
+			utils.JSONError(c, http.StatusConflict, errDuplicateSavedSearch.Error())
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Search != nil {
+		query, err := encodeSavedSearch(*req.Search, userID, viewerLoc)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid search: "+err.Error())
+			return
+		}
+		updates["query"] = query
+		updates["timezone"] = ""
+		if viewerLoc != nil {
+			updates["timezone"] = viewerLoc.String()
+		}
+	}
+	if req.Notify != nil {
+		updates["notify"] = *req.Notify
+	}
+	if len(updates) == 0 {
+		utils.JSONError(c, http.StatusBadRequest, "nothing to update")
+		return
+	}
+	// The recorded matches only hold while the search and subscription are unchanged
+	resetMatches := req.Search != nil || req.Notify != nil
+	if resetMatches {
+		updates["checked_at"] = nil
+	}
+	updates["updated_at"] = time.Now()
+
+	err := s.store.Transaction(func(store repository.Store) error {
+		if err := store.SavedSearches().Update(search.ID, updates); err != nil {
+			return err
+		}
+		if resetMatches {
+			return store.SavedSearches().ClearMatches(search.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update saved search: "+err.Error())
+		return
+	}
+	if search, err = s.store.SavedSearches().FindByID(search.ID); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch saved search: "+err.Error())
+		return
+	}
+	if resetMatches && search.Notify {
+		s.recordSavedSearchBaseline(&search)
+	}
+
+	c.JSON(http.StatusOK, formatSavedSearch(search))
+}
+
+// DeleteSavedSearch deletes a saved search of the current user.
+func (s *Service) DeleteSavedSearch(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	search, ok := s.loadSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := s.store.SavedSearches().Delete(search.ID); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to delete saved search: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "saved search deleted"})
+}
+
+// RunSavedSearch runs a saved search of the current user and responds like
+// GET /api/search. The `limit`, `cursor` and `taskCursor` query parameters
+// page through the results.
+func (s *Service) RunSavedSearch(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var page struct {
+		Limit      int    `form:"limit" binding:"omitempty,min=1,max=100"`
+		Cursor     string `form:"cursor"`
+		TaskCursor string `form:"taskCursor"`
+	}
+	if err := c.ShouldBindQuery(&page); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid query parameters: "+err.Error())
+		return
+	}
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+
+	search, ok := s.loadSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+	req, filters, err := decodeSavedSearch(search)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to read saved search: "+err.Error())
+		return
+	}
+	if page.Limit != 0 {
+		req.Limit = page.Limit
+	}
+	req.Cursor, req.TaskCursor = page.Cursor, page.TaskCursor
+
+	response, err := s.searchResponse(userID, req, filters, viewerLoc)
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+	response["savedSearch"] = formatSavedSearch(search)
+	c.JSON(http.StatusOK, response)
+}
+
+// savedSearchMatchIDs returns the newest events and tasks matching a search,
+// up to maxSavedSearchMatches of each.
+func (s *Service) savedSearchMatchIDs(userID uint, req SearchRequest, filters repository.SearchFilters) (map[string][]uint, error) {
+	lists := map[string]searchList{}
+	if req.Type == "events" || req.Type == "all" {
+		lists["event"] = eventSearchList(s.store)
+	}
+	if req.Type == "tasks" || req.Type == "all" {
+		lists["task"] = taskSearchList(s.store)
+	}
+
+	ids := map[string][]uint{}
+	for kind, list := range lists {
+		q := searchQuery(userID, req, filters, "-createdAt", maxSavedSearchMatches)
+		page, err := s.findSearchPage(list, q, "", "")
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range page.Hits {
+			ids[kind] = append(ids[kind], hit.ID)
+		}
+	}
+	return ids, nil
+}
+
+// refreshSavedSearchMatches records the current matches of a saved search
+// and, with notifyNew, notifies its user of those never recorded before. The
+// recorded matches are kept, so that an item leaving and re-entering the
+// results is not notified twice. It sets the check time of the search to
+// checkedAt, unless its query or check time changed since it was read: an
+// update or another check then took over, and nothing is written.
+func (s *Service) refreshSavedSearchMatches(search models.SavedSearch, notifyNew bool, checkedAt time.Time) error {
+	req, filters, err := decodeSavedSearch(search)
+	if err != nil {
+		return err
+	}
+	current, err := s.savedSearchMatchIDs(search.UserID, req, filters)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Transaction(func(store repository.Store) error {
+		locked, err := store.SavedSearches().FindByIDForUpdate(search.ID)
+		if err == repository.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if locked.Query != search.Query || !sameTime(locked.CheckedAt, search.CheckedAt) {
+			return nil
+		}
+
+		known, err := store.SavedSearches().Matches(search.ID)
+		if err != nil {
+			return err
+		}
+		recorded := map[string]map[uint]bool{"event": {}, "task": {}}
+		for _, match := range known {
+			recorded[match.Kind][match.ItemID] = true
+		}
+		var added []models.SavedSearchMatch
+		newCount := map[string]int{}
+		for kind, ids := range current {
+			for _, id := range ids {
+				if !recorded[kind][id] {
+					added = append(added, models.SavedSearchMatch{SavedSearchID: search.ID, Kind: kind, ItemID: id})
+					newCount[kind]++
+				}
+			}
+		}
+
+		if err := store.SavedSearches().SetCheckedAt(search.ID, checkedAt); err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		if err := store.SavedSearches().AddMatches(added); err != nil {
+			return err
+		}
+		if !notifyNew {
+			return nil
+		}
+
+		var parts []string
+		if n := newCount["event"]; n > 0 {
+			parts = append(parts, pluralize(n, "new event", "new events"))
+		}
+		if n := newCount["task"]; n > 0 {
+			parts = append(parts, pluralize(n, "new task", "new tasks"))
+		}
+		var eventID *uint
+		if newCount["event"] == 1 && newCount["task"] == 0 {
+			eventID = &added[0].ItemID
+		}
+		message := fmt.Sprintf("Your saved search %q has %s.", search.Name, strings.Join(parts, " and "))
+		return notify(store, search.UserID, eventID, "saved_search_match", message)
+	})
+}
+
+// recordSavedSearchBaseline records the current matches of a subscribed
+// saved search, so that only later ones are notified. On failure, the search
+// stays unchecked, and the next check records them instead, without
+// notifying.
+func (s *Service) recordSavedSearchBaseline(search *models.SavedSearch) {
+	// The database keeps whole seconds
+	now := time.Now().Truncate(time.Second)
+	if err := s.refreshSavedSearchMatches(*search, false, now); err != nil {
+		log.Printf("saved searches: failed to record the matches of %d: %v", search.ID, err)
+		return
+	}
+	if updated, err := s.store.SavedSearches().FindByID(search.ID); err == nil {
+		search.CheckedAt = updated.CheckedAt
+	}
+}
+
+// pluralize formats a count with the singular or plural noun.
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
+
+// WatchSavedSearches checks the subscribed saved searches for new matches
+// every SAVED_SEARCH_INTERVAL_MINUTES (default 15) until the process exits.
+// Each check is claimed in the database, so that several instances can run
+// it without notifying twice.
+func (s *Service) WatchSavedSearches() {
+	interval := savedSearchInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.checkSavedSearches(interval)
+	}
+}
+
+// checkSavedSearches checks the subscribed saved searches that were not
+// checked for interval. A failed check keeps the previous check time, and is
+// retried at the next run.
+func (s *Service) checkSavedSearches(interval time.Duration) {
+	if s.store == nil {
+		return
+	}
+	// The database keeps whole seconds, and the check time is compared after
+	// the claim
+	now := time.Now().Truncate(time.Second)
+	searches, err := s.store.SavedSearches().ListDue(now.Add(-interval), savedSearchBatch)
+	if err != nil {
+		log.Printf("saved searches: failed to fetch: %v", err)
+		return
+	}
+
+	for _, search := range searches {
+		// Claim the check; another instance may have done it meanwhile
+		claimed, err := s.store.SavedSearches().ClaimCheck(search.ID, search.CheckedAt, now)
+		if err != nil {
+			log.Printf("saved searches: failed to claim %d: %v", search.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		// The first check only records the matches
+		previous := search.CheckedAt
+		search.CheckedAt = &now
+		if err := s.refreshSavedSearchMatches(search, previous != nil, now); err != nil {
+			log.Printf("saved searches: failed to check %d: %v", search.ID, err)
+			// Give the check back: the next one would otherwise notify the
+			// matches never recorded as new
+			if err := s.store.SavedSearches().ReleaseCheck(search.ID, now, previous); err != nil {
+				log.Printf("saved searches: failed to release %d: %v", search.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"event_planner_backend/models"
+)
+
+func TestCheckSavedSearchesReleasesFailedChecks(t *testing.T) {
+	checked := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, previous := range []*time.Time{nil, &checked} {
+		store := &memoryStore{}
+		user := store.addUser("Olivia", "olivia@example.com")
+		// The query cannot be decoded, so the check fails
+		store.savedSearches = []models.SavedSearch{{ID: store.id(), UserID: user.ID, Name: "Broken", Query: "{", Notify: true, CheckedAt: previous}}
+
+		NewService(store).checkSavedSearches(15 * time.Minute)
+
+		if got := store.savedSearches[0].CheckedAt; !sameTime(got, previous) {
+			t.Errorf("checked at %v, want %v kept", got, previous)
+		}
+	}
+}
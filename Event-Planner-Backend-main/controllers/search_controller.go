@@ -1,27 +1,348 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
-	"event_planner_backend/config"
 	"event_planner_backend/middleware"
 	"event_planner_backend/models"
+	"event_planner_backend/repository"
 	"event_planner_backend/utils"
 )
 
-// SearchRequest represents the query parameters for advanced search.
+// Search modes. Full-text modes use the FULLTEXT indexes; "like" is the
+// fallback when the database has none.
+const (
+	searchModeNatural = "natural"
+	searchModeBoolean = "boolean"
+	searchModeLike    = "like"
+)
+
+// fullTextRetry is how long searches use the LIKE fallback after a full-text
+// query failed for lack of support, before trying the FULLTEXT indexes again.
+const fullTextRetry = 10 * time.Minute
+
+// Page sizes of search results.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+var (
+	errInvalidEventCursor = errors.New("invalid cursor; start again without it")
+	errInvalidTaskCursor  = errors.New("invalid taskCursor; start again without it")
+
+	// attendanceStatuses are the values of the status filter.
+	attendanceStatuses = []string{"going", "maybe", "not_going", "pending", "waitlisted"}
+	// taskStatuses are the values of the taskStatus filter.
+	taskStatuses = []string{"pending", "in_progress", "completed", "cancelled"}
+)
+
+// SearchRequest represents the query parameters for advanced search. Saved
+// searches store it as JSON, without cursors.
 type SearchRequest struct {
-	Keyword  string `form:"keyword"`   // Search in event names and task descriptions
-	Role     string `form:"role"`      // Filter by user role: "organizer" or "attendee"
-	Type     string `form:"type"`      // "events" or "tasks" or "all" (default: "all")
+	Keyword    string `form:"keyword" json:"keyword,omitempty"`       // Search in event names and task descriptions
+	Role       string `form:"role" json:"role,omitempty"`             // Filter by user role: "organizer" or "attendee"
+	Type       string `form:"type" json:"type,omitempty"`             // "events" or "tasks" or "all" (default: "all")
+	Mode       string `form:"mode" json:"mode,omitempty"`             // "natural" (default) or "boolean" full-text syntax
+	From       string `form:"from" json:"from,omitempty"`             // Events taking place after this date or instant
+	To         string `form:"to" json:"to,omitempty"`                 // Events starting before this date or instant
+	Status     string `form:"status" json:"status,omitempty"`         // Comma-separated attendance statuses of the user
+	TaskStatus string `form:"taskStatus" json:"taskStatus,omitempty"` // Comma-separated task statuses
+	Assignee   string `form:"assignee" json:"assignee,omitempty"`     // "me", "none" or a user ID
+	DueBefore  string `form:"dueBefore" json:"dueBefore,omitempty"`   // Tasks due before this date (YYYY-MM-DD)
+	Location   string `form:"location" json:"location,omitempty"`     // Part of the event location
+	Organizer  string `form:"organizer" json:"organizer,omitempty"`   // "me", a user ID or an email
+	Tags       string `form:"tags" json:"tags,omitempty"`             // Comma-separated tags the events all have
+	Sort       string `form:"sort" json:"sort,omitempty"`             // Order of events
+	TaskSort   string `form:"taskSort" json:"taskSort,omitempty"`     // Order of tasks
+	Limit      int    `form:"limit" json:"limit,omitempty" binding:"omitempty,min=1,max=100"`
+	Cursor     string `form:"cursor" json:"-"`     // Next page of events
+	TaskCursor string `form:"taskCursor" json:"-"` // Next page of tasks
+}
+
+// searchList describes one kind of search results.
+type searchList struct {
+	name string
+	find func(repository.SearchQuery) (repository.SearchPage, error)
+}
+
+// fullTextFallback remembers that full-text search was found unavailable, so
+// that searches go straight to LIKE until fullTextRetry has passed. The zero
+// value tries full-text search.
+type fullTextFallback struct {
+	until atomic.Int64 // Unix nanoseconds
+}
+
+// active reports whether searches use the fallback at now.
+func (f *fullTextFallback) active(now time.Time) bool {
+	return now.UnixNano() < f.until.Load()
+}
+
+// start makes searches use the fallback from now for fullTextRetry.
+func (f *fullTextFallback) start(now time.Time) {
+	f.until.Store(now.Add(fullTextRetry).UnixNano())
+}
+
+// searchPage is a page of search results.
+type searchPage struct {
+	repository.SearchPage
+	query      repository.SearchQuery // The query of the page, with the mode used
+	mode       string
+	nextCursor string
+	facets     gin.H // Counts of the results by value, per field
+}
+
+// sortNames returns the orders of a list, for error messages.
+func sortNames(names []string) string {
+	return strings.Join(append([]string{repository.SortRelevance}, names...), ", ")
+}
+
+// validSort reports whether name is empty, relevance or one of names.
+func validSort(name string, names []string) bool {
+	if name == "" || name == repository.SortRelevance {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusList parses a comma-separated list of statuses among allowed.
+func parseStatusList(name, value string, allowed []string) ([]string, error) {
+	var statuses []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s == "" {
+			continue
+		}
+		valid := false
+		for _, a := range allowed {
+			valid = valid || a == s
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid %s %q. Use %s", name, s, strings.Join(allowed, ", "))
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// parseSearchFilters validates the filters of req. Dates without time are
+// read in loc.
+func parseSearchFilters(req SearchRequest, userID uint, loc *time.Location) (repository.SearchFilters, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	parseTime := func(value string) (time.Time, error) {
+		if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+			return t, nil
+		}
+		return utils.ParseInstant(value, loc)
+	}
+	parseUser := func(value string) (*uint, error) {
+		if value == "me" {
+			return &userID, nil
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		u := uint(id)
+		return &u, nil
+	}
+
+	f := repository.SearchFilters{Location: strings.TrimSpace(req.Location)}
+	if role := strings.ToLower(req.Role); role == "organizer" || role == "attendee" {
+		f.Role = role
+	}
+	if value := strings.TrimSpace(req.From); value != "" {
+		t, err := parseTime(value)
+		if err != nil {
+			return f, errors.New("invalid from: " + err.Error())
+		}
+		f.From = &t
+	}
+	if value := strings.TrimSpace(req.To); value != "" {
+		t, err := parseTime(value)
+		if err != nil {
+			return f, errors.New("invalid to: " + err.Error())
+		}
+		f.To = &t
+	}
+	if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+		return f, errors.New("to must be after from")
+	}
+
+	var err error
+	if f.Statuses, err = parseStatusList("status", req.Status, attendanceStatuses); err != nil {
+		return f, err
+	}
+	if f.TaskStatuses, err = parseStatusList("taskStatus", req.TaskStatus, taskStatuses); err != nil {
+		return f, err
+	}
+
+	switch assignee := strings.ToLower(strings.TrimSpace(req.Assignee)); assignee {
+	case "":
+	case "none":
+		f.Unassigned = true
+	default:
+		if f.AssigneeID, err = parseUser(assignee); err != nil {
+			return f, errors.New("invalid assignee. Use me, none or a user ID")
+		}
+	}
+
+	if dueBefore, err := parseDueDate(req.DueBefore); err != nil {
+		return f, errors.New("invalid dueBefore. Use YYYY-MM-DD")
+	} else if dueBefore != nil {
+		f.DueBefore = dueBefore.Format("2006-01-02")
+	}
+
+	switch organizer := strings.TrimSpace(req.Organizer); {
+	case organizer == "":
+	case strings.Contains(organizer, "@"):
+		f.OrganizerEmail = strings.ToLower(organizer)
+	default:
+		if f.OrganizerID, err = parseUser(strings.ToLower(organizer)); err != nil {
+			return f, errors.New("invalid organizer. Use me, a user ID or an email")
+		}
+	}
+
+	if value := strings.TrimSpace(req.Tags); value != "" {
+		if f.Tags, err = normalizeTags(strings.Split(value, ",")); err != nil {
+			return f, errors.New("invalid tags: " + err.Error())
+		}
+	}
+	return f, nil
 }
 
-// SearchEventsAndTasks performs advanced search on events and tasks.
-func SearchEventsAndTasks(c *gin.Context) {
+// findSearchPage returns a page of the results of list. With a keyword they
+// match the columns of list and are ranked by relevance by their FULLTEXT
+// index or, without one, match any of the search terms with LIKE. Cursors are
+// bound to scope, which describes the filters.
+func (s *Service) findSearchPage(list searchList, q repository.SearchQuery, cursor, scope string) (searchPage, error) {
+	if q.Keyword != "" && !s.fullText.active(time.Now()) {
+		page, err := querySearchPage(list, q, cursor, scope)
+		if !errors.Is(err, repository.ErrFullTextUnsupported) {
+			return page, err
+		}
+		s.fullText.start(time.Now())
+		log.Printf("full-text search unavailable, falling back to LIKE for %v: %v", fullTextRetry, err)
+	}
+	q.Like = q.Keyword != ""
+	return querySearchPage(list, q, cursor, scope)
+}
+
+// querySearchPage runs the query of findSearchPage, matching the keyword
+// with the FULLTEXT index or, with q.Like, with LIKE.
+func querySearchPage(list searchList, q repository.SearchQuery, cursor, scope string) (searchPage, error) {
+	page := searchPage{query: q, mode: searchModeNatural}
+	if q.Boolean {
+		page.mode = searchModeBoolean
+	}
+	if q.Like {
+		page.mode = searchModeLike
+	}
+
+	fingerprint := utils.QueryFingerprint(list.name, scope, q.Keyword, page.mode, q.Sort)
+	if cursor != "" {
+		values, err := utils.DecodeCursor(cursor, fingerprint)
+		if err != nil {
+			return page, utils.ErrInvalidCursor
+		}
+		page.query.After = values
+	}
+
+	result, err := list.find(page.query)
+	if err == repository.ErrInvalidCursor {
+		return page, utils.ErrInvalidCursor
+	}
+	if err != nil {
+		return page, err
+	}
+	page.SearchPage = result
+	if result.Next != nil {
+		page.nextCursor = utils.EncodeCursor(fingerprint, result.Next)
+	}
+	return page, nil
+}
+
+// formatSearchPage describes the pagination of a page of results.
+func formatSearchPage(page searchPage) gin.H {
+	var nextCursor interface{}
+	if page.nextCursor != "" {
+		nextCursor = page.nextCursor
+	}
+	return gin.H{"total": page.Total, "sort": page.Sort, "nextCursor": nextCursor}
+}
+
+// addHighlights sets the highlighted snippets of the matching fields of a
+// result, and its relevance.
+func addHighlights(result gin.H, hit repository.SearchHit, pattern *regexp.Regexp, fields map[string]string) {
+	if hit.Relevance != nil {
+		result["relevance"] = *hit.Relevance
+	}
+	if pattern == nil {
+		return
+	}
+	highlights := gin.H{}
+	for name, text := range fields {
+		if snippet, ok := utils.Highlight(text, pattern); ok {
+			highlights[name] = snippet
+		}
+	}
+	result["highlights"] = highlights
+}
+
+// normalizeSearchRequest validates the options of req and sets their
+// defaults.
+func normalizeSearchRequest(req *SearchRequest) error {
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	req.Type = strings.ToLower(req.Type)
+	if req.Type == "" {
+		req.Type = "all"
+	}
+	if req.Type != "all" && req.Type != "events" && req.Type != "tasks" {
+		return errors.New("invalid type. Use events, tasks or all")
+	}
+	req.Mode = strings.ToLower(req.Mode)
+	if req.Mode == "" {
+		req.Mode = searchModeNatural
+	}
+	if req.Mode != searchModeNatural && req.Mode != searchModeBoolean {
+		return errors.New("invalid mode. Use natural or boolean")
+	}
+	if !validSort(req.Sort, repository.EventSorts()) {
+		return errors.New("invalid sort. Use " + sortNames(repository.EventSorts()))
+	}
+	if !validSort(req.TaskSort, repository.TaskSorts()) {
+		return errors.New("invalid taskSort. Use " + sortNames(repository.TaskSorts()))
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultSearchLimit
+	}
+	if req.Limit < 1 || req.Limit > maxSearchLimit {
+		return errors.New("limit must be between 1 and " + strconv.Itoa(maxSearchLimit))
+	}
+	return nil
+}
+
+// SearchEventsAndTasks performs advanced search on events and tasks. With a
+// keyword, results are ranked by relevance using the FULLTEXT indexes, and
+// carry highlighted snippets of the matching fields. Events and tasks are
+// paginated separately, each with its own cursor.
+func (s *Service) SearchEventsAndTasks(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
@@ -33,170 +354,215 @@ func SearchEventsAndTasks(c *gin.Context) {
 		utils.JSONError(c, http.StatusBadRequest, "invalid query parameters: "+err.Error())
 		return
 	}
+	if err := normalizeSearchRequest(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	if config.DB == nil {
-		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+	viewerLoc, ok := viewerLocation(c)
+	if !ok {
+		return
+	}
+	filters, err := parseSearchFilters(req, userID, viewerLoc)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Determine search type
-	searchType := strings.ToLower(req.Type)
-	if searchType == "" {
-		searchType = "all"
+	if !s.available(c) {
+		return
 	}
 
+	response, err := s.searchResponse(userID, req, filters, viewerLoc)
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// writeSearchError responds with an error of searchResponse.
+func writeSearchError(c *gin.Context, err error) {
+	if errors.Is(err, errInvalidEventCursor) || errors.Is(err, errInvalidTaskCursor) {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.JSONError(c, http.StatusInternalServerError, err.Error())
+}
+
+// searchResponse runs a normalized search for userID and returns the
+// response of SearchEventsAndTasks.
+func (s *Service) searchResponse(userID uint, req SearchRequest, filters repository.SearchFilters, viewerLoc *time.Location) (gin.H, error) {
 	response := gin.H{}
+	pagination := gin.H{"limit": req.Limit}
+	mode := req.Mode
 
 	// Search events if type is "events" or "all"
-	if searchType == "events" || searchType == "all" {
-		events, err := searchEvents(userID, req)
+	if req.Type == "events" || req.Type == "all" {
+		events, page, err := s.searchEvents(userID, req, filters, viewerLoc)
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			return nil, errInvalidEventCursor
+		}
 		if err != nil {
-			utils.JSONError(c, http.StatusInternalServerError, "failed to search events: "+err.Error())
-			return
+			return nil, fmt.Errorf("failed to search events: %w", err)
 		}
 		response["events"] = events
+		response["facets"] = page.facets
+		pagination["events"] = formatSearchPage(page)
+		mode = page.mode
 	}
 
 	// Search tasks if type is "tasks" or "all"
-	if searchType == "tasks" || searchType == "all" {
-		tasks, err := searchTasks(userID, req)
+	if req.Type == "tasks" || req.Type == "all" {
+		tasks, page, err := s.searchTasks(userID, req, filters)
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			return nil, errInvalidTaskCursor
+		}
 		if err != nil {
-			utils.JSONError(c, http.StatusInternalServerError, "failed to search tasks: "+err.Error())
-			return
+			return nil, fmt.Errorf("failed to search tasks: %w", err)
 		}
 		response["tasks"] = tasks
+		pagination["tasks"] = formatSearchPage(page)
+		if page.mode == searchModeLike {
+			mode = page.mode
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	response["pagination"] = pagination
+	if req.Keyword != "" {
+		response["mode"] = mode
+	}
+	return response, nil
 }
 
-// searchEvents searches events based on filters.
-func searchEvents(userID uint, req SearchRequest) ([]gin.H, error) {
-	query := config.DB.Model(&models.Event{}).
-		Joins("INNER JOIN event_attendees ON events.event_id = event_attendees.event_id").
-		Where("event_attendees.user_id = ?", userID)
+// searchScope describes the filters of req that the results depend on, to
+// bind cursors to them.
+func searchScope(userID uint, req SearchRequest) string {
+	return strings.Join([]string{
+		strconv.FormatUint(uint64(userID), 10), strings.ToLower(req.Role), req.From, req.To,
+		req.Status, req.TaskStatus, req.Assignee, req.DueBefore, req.Location, req.Organizer, req.Tags,
+	}, "\x00")
+}
 
-	// Keyword search (title and description)
-	if req.Keyword != "" {
-		keyword := "%" + strings.ToLower(req.Keyword) + "%"
-		query = query.Where("LOWER(events.title) LIKE ? OR LOWER(events.description) LIKE ?", keyword, keyword)
+// searchQuery returns the query of the first page of a normalized search for
+// userID, in the order sortName.
+func searchQuery(userID uint, req SearchRequest, filters repository.SearchFilters, sortName string, limit int) repository.SearchQuery {
+	boolean := req.Mode == searchModeBoolean
+	return repository.SearchQuery{
+		UserID:  userID,
+		Filters: filters,
+		Keyword: req.Keyword,
+		Boolean: boolean,
+		Terms:   utils.SearchTerms(req.Keyword, boolean),
+		Sort:    sortName,
+		Limit:   limit,
 	}
+}
 
-	// Role filter
-	if req.Role != "" {
-		role := strings.ToLower(req.Role)
-		if role == "organizer" || role == "attendee" {
-			query = query.Where("event_attendees.role = ?", role)
-		}
+// eventSearchList searches the events of store the user is part of.
+func eventSearchList(store repository.Store) searchList {
+	return searchList{name: "events", find: store.Search().Events}
+}
+
+// taskSearchList searches the tasks of the events of store the user is part
+// of. Event filters apply to the events of the tasks.
+func taskSearchList(store repository.Store) searchList {
+	return searchList{name: "tasks", find: store.Search().Tasks}
+}
+
+// searchEvents returns a page of the events matching the search, and its
+// pagination with the counts of the tags of all the matching events.
+func (s *Service) searchEvents(userID uint, req SearchRequest, filters repository.SearchFilters, viewerLoc *time.Location) ([]gin.H, searchPage, error) {
+	q := searchQuery(userID, req, filters, req.Sort, req.Limit)
+	page, err := s.findSearchPage(eventSearchList(s.store), q, req.Cursor, searchScope(userID, req))
+	if err != nil {
+		return nil, page, err
+	}
+	tags, err := s.store.Search().EventTags(page.query, maxTagFacets)
+	if err != nil {
+		return nil, page, err
 	}
+	page.facets = gin.H{"tags": tags}
 
+	ids := make([]uint, len(page.Hits))
+	for i, hit := range page.Hits {
+		ids[i] = hit.ID
+	}
 	var events []models.Event
-	if err := query.
-		Preload("Organizer").
-		Preload("Attendees", func(db *gorm.DB) *gorm.DB {
-			return db.Preload("User")
-		}).
-		Select("events.*").
-		Group("events.event_id").
-		Order("events.created_at DESC").
-		Find(&events).Error; err != nil {
-		return nil, err
-	}
-
-	// Format response
-	result := make([]gin.H, len(events))
-	for i, event := range events {
-		result[i] = formatEventResponse(event)
+	if len(ids) > 0 {
+		if events, err = s.store.Events().ListWithDetails(ids); err != nil {
+			return nil, page, err
+		}
+	}
+	byID := make(map[uint]models.Event, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+
+	// Format response, in the order of the hits
+	pattern := utils.HighlightPattern(q.Terms)
+	result := make([]gin.H, 0, len(page.Hits))
+	for _, hit := range page.Hits {
+		event, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		formatted := formatEventResponse(event, viewerLoc)
 		// Find user's role and status from preloaded attendees
 		for _, att := range event.Attendees {
 			if att.UserID == userID {
-				result[i]["myRole"] = att.Role
-				result[i]["myStatus"] = att.Status
+				formatted["myRole"] = att.Role
+				formatted["myStatus"] = att.Status
 				break
 			}
 		}
-		if event.CreatedBy == userID && result[i]["myRole"] == nil {
-			result[i]["myRole"] = "organizer"
-			result[i]["myStatus"] = "going"
+		if event.CreatedBy == userID && formatted["myRole"] == nil {
+			formatted["myRole"] = "organizer"
+			formatted["myStatus"] = "going"
 		}
+		addHighlights(formatted, hit, pattern, map[string]string{"title": event.Title, "description": event.Description})
+		result = append(result, formatted)
 	}
 
-	return result, nil
+	return result, page, nil
 }
 
-// searchTasks searches tasks based on filters.
-func searchTasks(userID uint, req SearchRequest) ([]gin.H, error) {
-	// Start with tasks that belong to events the user is part of
-	query := config.DB.Model(&models.Task{}).
-		Joins("INNER JOIN events ON tasks.event_id = events.event_id").
-		Joins("INNER JOIN event_attendees ON events.event_id = event_attendees.event_id").
-		Where("event_attendees.user_id = ?", userID)
-
-	// Keyword search (task description)
-	if req.Keyword != "" {
-		keyword := "%" + strings.ToLower(req.Keyword) + "%"
-		query = query.Where("LOWER(tasks.description) LIKE ?", keyword)
+// searchTasks returns a page of the tasks matching the search, and its
+// pagination.
+func (s *Service) searchTasks(userID uint, req SearchRequest, filters repository.SearchFilters) ([]gin.H, searchPage, error) {
+	q := searchQuery(userID, req, filters, req.TaskSort, req.Limit)
+	page, err := s.findSearchPage(taskSearchList(s.store), q, req.TaskCursor, searchScope(userID, req))
+	if err != nil {
+		return nil, page, err
 	}
 
-	// Role filter (based on user's role in the event)
-	if req.Role != "" {
-		role := strings.ToLower(req.Role)
-		if role == "organizer" || role == "attendee" {
-			query = query.Where("event_attendees.role = ?", role)
-		}
+	ids := make([]uint, len(page.Hits))
+	for i, hit := range page.Hits {
+		ids[i] = hit.ID
 	}
-
 	var tasks []models.Task
-	if err := query.
-		Preload("Event", func(db *gorm.DB) *gorm.DB {
-			return db.Preload("Organizer")
-		}).
-		Preload("Assignee").
-		Preload("Creator").
-		Select("tasks.*").
-		Group("tasks.task_id").
-		Order("tasks.due_date ASC, tasks.created_at DESC").
-		Find(&tasks).Error; err != nil {
-		return nil, err
-	}
-
-	// Format response
-	result := make([]gin.H, len(tasks))
-	for i, task := range tasks {
-		dueDateStr := ""
-		if task.DueDate != nil {
-			dueDateStr = task.DueDate.Format("2006-01-02")
-		}
-
-		assigneeInfo := gin.H{}
-		if task.AssignedTo != nil && task.Assignee != nil {
-			assigneeInfo = gin.H{
-				"id":    task.Assignee.ID,
-				"name":  task.Assignee.Name,
-				"email": task.Assignee.Email,
-			}
+	if len(ids) > 0 {
+		if tasks, err = s.store.Tasks().ListByIDs(ids); err != nil {
+			return nil, page, err
 		}
+	}
+	byID := make(map[uint]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
 
-		result[i] = gin.H{
-			"id":          task.ID,
-			"eventId":     task.EventID,
-			"eventTitle":  task.Event.Title,
-			"description": task.Description,
-			"assignedTo":  task.AssignedTo,
-			"assignee":    assigneeInfo,
-			"status":      task.Status,
-			"dueDate":     dueDateStr,
-			"createdBy":   task.CreatedBy,
-			"creator": gin.H{
-				"id":    task.Creator.ID,
-				"name":  task.Creator.Name,
-				"email": task.Creator.Email,
-			},
-			"createdAt": task.CreatedAt,
-			"updatedAt": task.UpdatedAt,
+	// Format response, in the order of the hits
+	pattern := utils.HighlightPattern(q.Terms)
+	result := make([]gin.H, 0, len(page.Hits))
+	for _, hit := range page.Hits {
+		task, ok := byID[hit.ID]
+		if !ok {
+			continue
 		}
+		formatted := formatTaskResponse(task)
+		addHighlights(formatted, hit, pattern, map[string]string{"description": task.Description})
+		result = append(result, formatted)
 	}
 
-	return result, nil
+	return result, page, nil
 }
-
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"event_planner_backend/repository"
+)
+
+func TestFindSearchPageFallsBackToLike(t *testing.T) {
+	var fullText, like int
+	list := searchList{name: "events", find: func(q repository.SearchQuery) (repository.SearchPage, error) {
+		if q.Like {
+			like++
+			return repository.SearchPage{}, nil
+		}
+		fullText++
+		return repository.SearchPage{}, repository.ErrFullTextUnsupported
+	}}
+	svc := NewService(&memoryStore{})
+	q := repository.SearchQuery{Keyword: "launch", Terms: []string{"launch"}, Limit: 10}
+
+	for i := 0; i < 2; i++ {
+		page, err := svc.findSearchPage(list, q, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.mode != searchModeLike {
+			t.Errorf("search %d: mode = %q, want like", i, page.mode)
+		}
+	}
+	// The second search goes straight to the fallback
+	if fullText != 1 || like != 2 {
+		t.Errorf("%d full-text and %d LIKE queries, want 1 and 2", fullText, like)
+	}
+
+	// Other services still try the FULLTEXT indexes
+	if _, err := NewService(&memoryStore{}).findSearchPage(list, q, "", ""); err != nil || fullText != 2 {
+		t.Errorf("%d full-text queries, err %v; want another one", fullText, err)
+	}
+
+	// So does this one once fullTextRetry has passed
+	svc.fullText.until.Store(time.Now().Add(-time.Second).UnixNano())
+	if _, err := svc.findSearchPage(list, q, "", ""); err != nil || fullText != 3 {
+		t.Errorf("%d full-text queries, err %v; want a retry", fullText, err)
+	}
+}
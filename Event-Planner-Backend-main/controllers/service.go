@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// Service holds the store the handlers, its methods, read and write through.
+// It is built once at startup and its handlers registered by the router.
+type Service struct {
+	store    repository.Store
+	fullText fullTextFallback // Skips the FULLTEXT indexes after they were found missing
+}
+
+// NewService returns a Service using store, which is nil when no database is
+// connected.
+func NewService(store repository.Store) *Service {
+	return &Service{store: store}
+}
+
+// Store returns the store of the service, for the middleware of the router.
+func (s *Service) Store() repository.Store {
+	return s.store
+}
+
+// errResponded is returned from a transaction by handlers that already wrote
+// the response, so that it is rolled back without writing another one.
+var errResponded = errors.New("response already written")
+
+// available writes the error response and returns false when no database is
+// connected.
+func (s *Service) available(c *gin.Context) bool {
+	if s.store == nil {
+		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+		return false
+	}
+	return true
+}
+
+// findEvent loads the event of the request. It writes the error response and
+// returns ok=false when there is none.
+func (s *Service) findEvent(c *gin.Context) (models.Event, bool) {
+	eventID, ok := idParam(c, "id", "event")
+	if !ok || !s.available(c) {
+		return models.Event{}, false
+	}
+	event, err := s.store.Events().FindByID(eventID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "event not found")
+			return event, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return event, false
+	}
+	return event, true
+}
+
+// idParam parses a numeric route parameter. It writes the error response and
+// returns ok=false when the parameter is not a valid ID.
+func idParam(c *gin.Context, name, label string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		utils.JSONError(c, http.StatusBadRequest, "invalid "+label+" ID")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/config"
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// defaultAccessTokenTTL is the validity of access tokens when
+	// ACCESS_TOKEN_TTL_MINUTES is not set.
+	defaultAccessTokenTTL = 15 * time.Minute
+	// defaultRefreshTokenTTL is the validity of refresh tokens when
+	// REFRESH_TOKEN_TTL_HOURS is not set.
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// errRefreshTokenInvalid is returned for unknown, revoked or expired refresh
+// tokens.
+var errRefreshTokenInvalid = errors.New("refresh token is invalid or has expired")
+
+// RefreshTokenRequest defines the payload to renew an access token.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LogoutRequest defines the optional payload of a logout.
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"` // Default: the session of the access token
+	All          bool   `json:"all"`          // Log out of all sessions
+}
+
+// accessTokenTTL returns the validity of new access tokens.
+func accessTokenTTL() time.Duration {
+	if minutes, err := strconv.Atoi(config.GetEnv("ACCESS_TOKEN_TTL_MINUTES", "")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultAccessTokenTTL
+}
+
+// refreshTokenTTL returns the validity of new refresh tokens.
+func refreshTokenTTL() time.Duration {
+	if hours, err := strconv.Atoi(config.GetEnv("REFRESH_TOKEN_TTL_HOURS", "")); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultRefreshTokenTTL
+}
+
+// issueTokens creates an access token for user and, when store is not nil, a
+// refresh token of familyID, or of a new family when it is empty.
+func issueTokens(store repository.Store, user models.User, familyID string) (gin.H, error) {
+	jti, err := utils.GenerateToken(16)
+	if err != nil {
+		return nil, err
+	}
+	ttl := accessTokenTTL()
+	now := time.Now()
+	token, err := utils.GenerateJWT(jwtKeys(), user.ID, user.Email, jti, ttl)
+	if err != nil {
+		return nil, err
+	}
+	result := gin.H{
+		"token":     token,
+		"expiresIn": int(ttl.Seconds()),
+	}
+	if store == nil {
+		// Without a database, tokens cannot be renewed nor revoked
+		return result, nil
+	}
+
+	if familyID == "" {
+		if familyID, err = utils.GenerateToken(16); err != nil {
+			return nil, err
+		}
+	}
+	refresh, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	stored := models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		TokenHash:       utils.HashToken(refresh),
+		AccessJTI:       jti,
+		AccessExpiresAt: now.Add(ttl),
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(refreshTokenTTL()),
+	}
+	if err := store.Sessions().Create(&stored); err != nil {
+		return nil, err
+	}
+	result["refreshToken"] = refresh
+	result["refreshExpiresAt"] = stored.ExpiresAt
+	return result, nil
+}
+
+// loginUserPayload describes the logged in user in token responses.
+func loginUserPayload(user models.User) gin.H {
+	return gin.H{
+		"id":            user.ID,
+		"name":          user.Name,
+		"email":         user.Email,
+		"emailVerified": user.EmailVerifiedAt != nil,
+		"mfaEnabled":    user.TOTPEnabledAt != nil,
+	}
+}
+
+// logOutEverywhere ends every session of a user: their refresh tokens, the
+// access tokens issued with them, and their personal access tokens.
+func logOutEverywhere(store repository.Store, userID uint) error {
+	now := time.Now()
+	if err := store.Sessions().RevokeUser(userID, now); err != nil {
+		return err
+	}
+	return store.AccessTokens().RevokeAll(userID, now)
+}
+
+// GetJWKS publishes the public keys verifying the tokens of this server, so
+// that other services can check them. Clients may cache it for 5 minutes.
+func (s *Service) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwtKeys().JWKS())
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token is accepted once: presenting it again
+// revokes every token of its family, as one of the copies was stolen.
+func (s *Service) RefreshSession(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var tokens gin.H
+	var user models.User
+	reused := false
+	err := s.store.Transaction(func(store repository.Store) error {
+		stored, err := store.Sessions().FindByHashForUpdate(utils.HashToken(req.RefreshToken))
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return errRefreshTokenInvalid
+			}
+			return err
+		}
+		if stored.RevokedAt != nil {
+			return errRefreshTokenInvalid
+		}
+		if stored.UsedAt != nil {
+			// Committed, so that the family stays revoked
+			reused = true
+			return store.Sessions().RevokeFamilies([]string{stored.FamilyID}, time.Now())
+		}
+		if !time.Now().Before(stored.ExpiresAt) {
+			return errRefreshTokenInvalid
+		}
+
+		if err := store.Sessions().MarkUsed(stored.ID, time.Now()); err != nil {
+			return err
+		}
+		if user, err = store.Users().FindByID(stored.UserID); err != nil {
+			if err == repository.ErrNotFound {
+				return errRefreshTokenInvalid
+			}
+			return err
+		}
+		tokens, err = issueTokens(store, user, stored.FamilyID)
+		return err
+	})
+	if err != nil {
+		if err == errRefreshTokenInvalid {
+			utils.JSONError(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to refresh token: "+err.Error())
+		return
+	}
+	if reused {
+		utils.JSONError(c, http.StatusUnauthorized, "refresh token was already used; the session has been revoked, please log in again")
+		return
+	}
+
+	tokens["user"] = loginUserPayload(user)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the access token of the request and its refresh tokens, or
+// those of the given refresh token. With "all" it revokes every session of
+// the user, personal access tokens included.
+func (s *Service) Logout(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+	jti, expiresAt, _ := middleware.GetTokenID(c)
+
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+			return
+		}
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	err := s.store.Transaction(func(store repository.Store) error {
+		now := time.Now()
+		switch {
+		case req.All:
+			if err := logOutEverywhere(store, userID); err != nil {
+				return err
+			}
+		case req.RefreshToken != "":
+			stored, err := store.Sessions().FindByHash(utils.HashToken(req.RefreshToken), userID)
+			if err != nil && err != repository.ErrNotFound {
+				return err
+			}
+			if err == nil {
+				if err := store.Sessions().RevokeFamilies([]string{stored.FamilyID}, now); err != nil {
+					return err
+				}
+			}
+		case jti != "":
+			families, err := store.Sessions().FamiliesOfAccessToken(jti, userID)
+			if err != nil {
+				return err
+			}
+			if err := store.Sessions().RevokeFamilies(families, now); err != nil {
+				return err
+			}
+		}
+		if jti != "" {
+			if err := store.Sessions().RevokeAccessToken(jti, userID, expiresAt, now); err != nil {
+				return err
+			}
+		}
+		// Forget denylisted tokens that have expired anyway
+		return store.Sessions().DeleteExpiredRevoked(now)
+	})
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to log out: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// maxEventTags bounds the tags of an event.
+	maxEventTags = 20
+	// maxTagLength bounds the length of a tag, in characters.
+	maxTagLength = 50
+	// defaultTagSuggestions and maxTagSuggestions bound tag autocomplete.
+	defaultTagSuggestions = 10
+	maxTagSuggestions     = 50
+	// maxTagFacets bounds the tag counts returned with search results.
+	maxTagFacets = 20
+)
+
+var (
+	errInvalidTag  = errors.New("tags may only hold letters, digits, spaces, - and _, up to 50 characters")
+	errTooManyTags = errors.New("too many tags")
+)
+
+// AddEventTagsRequest defines the payload to tag an event.
+type AddEventTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// cleanTag lowercases a tag and collapses its spaces. A leading # is
+// dropped, so that "#Summer  Fest" and "summer fest" are the same tag.
+func cleanTag(value string) string {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "#")
+	return strings.Join(strings.Fields(strings.ToLower(value)), " ")
+}
+
+// normalizeTag cleans a tag and checks that it is valid.
+func normalizeTag(value string) (string, error) {
+	tag := cleanTag(value)
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return "", errInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' && r != '_' {
+			return "", errInvalidTag
+		}
+	}
+	return tag, nil
+}
+
+// normalizeTags normalizes tags and drops the duplicates.
+func normalizeTags(values []string) ([]string, error) {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, value := range values {
+		tag, err := normalizeTag(value)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// tagNames returns the names of preloaded tags.
+func tagNames(tags []models.EventTag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Tag
+	}
+	return names
+}
+
+// AddEventTags tags an event (organizers only). Tags the event already has
+// are kept once; an event has at most 20 tags.
+func (s *Service) AddEventTags(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req AddEventTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, "only organizers can tag events")
+		return
+	}
+
+	var result []string
+	err = s.store.Transaction(func(store repository.Store) error {
+		// Lock the event so that concurrent requests cannot exceed the limit
+		if _, err := store.Events().FindByIDForUpdate(event.ID); err != nil {
+			return err
+		}
+		existing, err := store.Events().Tags(event.ID)
+		if err != nil {
+			return err
+		}
+		have := map[string]bool{}
+		for _, tag := range existing {
+			have[tag] = true
+		}
+		var added []string
+		for _, tag := range tags {
+			if !have[tag] {
+				added = append(added, tag)
+			}
+		}
+		if len(existing)+len(added) > maxEventTags {
+			return errTooManyTags
+		}
+		if err := store.Events().AddTags(event.ID, added); err != nil {
+			return err
+		}
+		result, err = store.Events().Tags(event.ID)
+		return err
+	})
+	if errors.Is(err, errTooManyTags) {
+		utils.JSONError(c, http.StatusBadRequest, "an event can have at most "+strconv.Itoa(maxEventTags)+" tags")
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to tag event: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"eventId": event.ID,
+		"tags":    result,
+	})
+}
+
+// RemoveEventTag removes a tag from an event (organizers only).
+func (s *Service) RemoveEventTag(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	tag, err := normalizeTag(c.Param("tag"))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, "only organizers can untag events")
+		return
+	}
+
+	removed, err := s.store.Events().RemoveTag(event.ID, tag)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to remove tag: "+err.Error())
+		return
+	}
+	if !removed {
+		utils.JSONError(c, http.StatusNotFound, "tag not found on this event")
+		return
+	}
+
+	tags, err := s.store.Events().Tags(event.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch tags: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"eventId": event.ID,
+		"tags":    tags,
+	})
+}
+
+// GetTagSuggestions autocompletes tags from the events the user is part of:
+// the tags starting with the `prefix` query parameter, or all tags without
+// it, most used first.
+func (s *Service) GetTagSuggestions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	limit := defaultTagSuggestions
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxTagSuggestions {
+			utils.JSONError(c, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxTagSuggestions))
+			return
+		}
+		limit = n
+	}
+	prefix := cleanTag(c.Query("prefix"))
+
+	if !s.available(c) {
+		return
+	}
+
+	suggestions, err := s.store.Events().TagSuggestions(userID, prefix, limit)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch tags: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":  suggestions,
+		"count": len(suggestions),
+	})
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCleanTag(t *testing.T) {
+	tests := map[string]string{
+		"#Summer  Fest": "summer fest",
+		"  #MU ":        "mu",
+		"##hash":        "#hash",
+		"#":             "",
+		"":              "",
+	}
+	for value, want := range tests {
+		if tag := cleanTag(value); tag != want {
+			t.Errorf("cleanTag(%q) = %q, want %q", value, tag, want)
+		}
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		value, tag string
+		valid      bool
+	}{
+		{"#Summer  Fest", "summer fest", true},
+		{" outdoor_2024 ", "outdoor_2024", true},
+		{"café", "café", true},
+		{"", "", false},
+		{"#", "", false},
+		{"drinks!", "", false},
+		{strings.Repeat("a", maxTagLength+1), "", false},
+	}
+	for _, tt := range tests {
+		tag, err := normalizeTag(tt.value)
+		if tt.valid && (err != nil || tag != tt.tag) {
+			t.Errorf("normalizeTag(%q) = %q, %v, want %q", tt.value, tag, err, tt.tag)
+		}
+		if !tt.valid && err != errInvalidTag {
+			t.Errorf("normalizeTag(%q) = %q, %v, want errInvalidTag", tt.value, tag, err)
+		}
+	}
+}
+
+func TestEventTags(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	guest := store.addUser("Gus", "gus@example.com")
+	event := store.addEvent("Launch", organizer)
+	store.addAttendee(event, guest, "attendee")
+	svc := NewService(store)
+	path := fmt.Sprintf("/events/%d/tags", event.ID)
+
+	w := serve(t, svc.AddEventTags, http.MethodPost, "/events/:id/tags", path, organizer.ID,
+		map[string]interface{}{"tags": []string{"#Music", "outdoor", "music"}})
+	expectStatus(t, w, http.StatusOK)
+	if tags, _ := store.Events().Tags(event.ID); !reflect.DeepEqual(tags, []string{"music", "outdoor"}) {
+		t.Errorf("tags = %v", tags)
+	}
+
+	w = serve(t, svc.AddEventTags, http.MethodPost, "/events/:id/tags", path, guest.ID,
+		map[string]interface{}{"tags": []string{"food"}})
+	expectStatus(t, w, http.StatusForbidden)
+
+	many := make([]string, maxEventTags)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag %d", i)
+	}
+	w = serve(t, svc.AddEventTags, http.MethodPost, "/events/:id/tags", path, organizer.ID,
+		map[string]interface{}{"tags": many})
+	expectStatus(t, w, http.StatusBadRequest)
+	if tags, _ := store.Events().Tags(event.ID); len(tags) != 2 {
+		t.Errorf("%d tags after exceeding the limit, want 2", len(tags))
+	}
+
+	w = serve(t, svc.RemoveEventTag, http.MethodDelete, "/events/:id/tags/:tag", path+"/Music", organizer.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	w = serve(t, svc.RemoveEventTag, http.MethodDelete, "/events/:id/tags/:tag", path+"/music", organizer.ID, nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestGetTagSuggestions(t *testing.T) {
+	store := &memoryStore{}
+	user := store.addUser("Olivia", "olivia@example.com")
+	other := store.addUser("Gus", "gus@example.com")
+	first := store.addEvent("Launch", user)
+	second := store.addEvent("Party", user)
+	hidden := store.addEvent("Private", other)
+	store.Events().AddTags(first.ID, []string{"music", "outdoor"})
+	store.Events().AddTags(second.ID, []string{"music", "food"})
+	store.Events().AddTags(hidden.ID, []string{"museum"})
+	svc := NewService(store)
+
+	w := serve(t, svc.GetTagSuggestions, http.MethodGet, "/tags", "/tags?prefix=%23MU", user.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	body := decode(t, w)
+	tags := body["tags"].([]interface{})
+	if len(tags) != 1 {
+		t.Fatalf("tags = %v, want music only", tags)
+	}
+	if tag := tags[0].(map[string]interface{}); tag["tag"] != "music" || tag["count"] != 2.0 {
+		t.Errorf("tag = %v, want music used twice", tag)
+	}
+
+	w = serve(t, svc.GetTagSuggestions, http.MethodGet, "/tags", "/tags?limit=2", user.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := decode(t, w); body["count"] != 2.0 {
+		t.Errorf("count = %v, want 2", body["count"])
+	}
+
+	w = serve(t, svc.GetTagSuggestions, http.MethodGet, "/tags", fmt.Sprintf("/tags?limit=%d", maxTagSuggestions+1), user.ID, nil)
+	expectStatus(t, w, http.StatusBadRequest)
+}
@@ -0,0 +1,481 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/middleware"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// CreateTaskRequest represents the payload for creating a task on an event.
+type CreateTaskRequest struct {
+	Description string `json:"description" binding:"required,min=1"`
+	AssignedTo  *uint  `json:"assignedTo"`
+	Status      string `json:"status" binding:"omitempty,oneof=pending in_progress completed cancelled"` // Default: pending
+	DueDate     string `json:"dueDate"`                                                                  // Format: "2006-01-02"
+}
+
+// UpdateTaskRequest represents the payload for partially updating a task.
+// Omitted fields are left untouched; an empty dueDate clears the due date.
+type UpdateTaskRequest struct {
+	Description *string `json:"description" binding:"omitempty,min=1"`
+	Status      *string `json:"status" binding:"omitempty,oneof=pending in_progress completed cancelled"`
+	DueDate     *string `json:"dueDate"`
+}
+
+// AssignTaskRequest represents the payload for assigning a task.
+// Either userId or email must be provided.
+type AssignTaskRequest struct {
+	UserID uint   `json:"userId"`
+	Email  string `json:"email" binding:"omitempty,email"`
+}
+
+// loadEvent fetches the event from the :id param and the user's role on it.
+// It writes the error response itself and returns ok=false when the request
+// cannot continue (missing event or user not part of the event).
+func (s *Service) loadEvent(c *gin.Context, userID uint) (models.Event, string, bool) {
+	eventID, ok := idParam(c, "id", "event")
+	if !ok || !s.available(c) {
+		return models.Event{}, "", false
+	}
+
+	event, err := s.store.Events().FindByID(eventID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "event not found")
+			return event, "", false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch event: "+err.Error())
+		return event, "", false
+	}
+
+	role, err := s.store.Attendees().Role(event, userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+		return event, "", false
+	}
+	if role == "" {
+		utils.JSONError(c, http.StatusForbidden, "you are not authorized to view this event")
+		return event, "", false
+	}
+
+	return event, role, true
+}
+
+// findEventTask fetches the task from the :taskId param, scoped to the event.
+func (s *Service) findEventTask(c *gin.Context, event models.Event) (models.Task, bool) {
+	taskID, ok := idParam(c, "taskId", "task")
+	if !ok {
+		return models.Task{}, false
+	}
+	task, err := s.store.Tasks().FindInEvent(event.ID, taskID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "task not found")
+			return task, false
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch task: "+err.Error())
+		return task, false
+	}
+	task.Event = event
+	return task, true
+}
+
+// parseDueDate parses an optional YYYY-MM-DD due date; empty means no due date.
+func parseDueDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	dueDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &dueDate, nil
+}
+
+// GetEventTasks lists the tasks of an event (any event member).
+func (s *Service) GetEventTasks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, _, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.Tasks().ListByEvent(event.ID, c.Query("status"))
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch tasks: "+err.Error())
+		return
+	}
+
+	response := make([]gin.H, len(tasks))
+	for i, task := range tasks {
+		task.Event = event
+		response[i] = formatTaskResponse(task)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateTask creates a task on an event (organizer only).
+func (s *Service) CreateTask(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, "only organizers can create tasks")
+		return
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		utils.JSONError(c, http.StatusBadRequest, "description is required")
+		return
+	}
+
+	dueDate, err := parseDueDate(req.DueDate)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid dueDate format. Use YYYY-MM-DD: "+err.Error())
+		return
+	}
+
+	status := req.Status
+	if status == "" {
+		status = "pending"
+	}
+
+	if req.AssignedTo != nil {
+		assigneeRole, err := s.store.Attendees().Role(event, *req.AssignedTo)
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+			return
+		}
+		if assigneeRole == "" {
+			utils.JSONError(c, http.StatusBadRequest, "tasks can only be assigned to event members")
+			return
+		}
+	}
+
+	task := &models.Task{
+		EventID:     event.ID,
+		Description: description,
+		AssignedTo:  req.AssignedTo,
+		Status:      status,
+		DueDate:     dueDate,
+		CreatedBy:   userID,
+	}
+	if err := s.store.Tasks().Create(task); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create task: "+err.Error())
+		return
+	}
+
+	created, ok := s.findTaskByID(c, event, task.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, formatTaskResponse(created))
+}
+
+// GetTaskDetails returns a single task of an event (any event member).
+func (s *Service) GetTaskDetails(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, _, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+
+	task, ok := s.findEventTask(c, event)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTaskResponse(task))
+}
+
+// UpdateTask partially updates a task. Organizers can change every field;
+// the assignee can only change the status.
+func (s *Service) UpdateTask(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req UpdateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+
+	task, ok := s.findEventTask(c, event)
+	if !ok {
+		return
+	}
+
+	isAssignee := task.AssignedTo != nil && *task.AssignedTo == userID
+	if role != "organizer" {
+		if !isAssignee {
+			utils.JSONError(c, http.StatusForbidden, "only organizers or the assignee can update this task")
+			return
+		}
+		if req.Description != nil || req.DueDate != nil {
+			utils.JSONError(c, http.StatusForbidden, "assignees can only update the task status")
+			return
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if description == "" {
+			utils.JSONError(c, http.StatusBadRequest, "description cannot be empty")
+			return
+		}
+		updates["description"] = description
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if req.DueDate != nil {
+		dueDate, err := parseDueDate(*req.DueDate)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "invalid dueDate format. Use YYYY-MM-DD: "+err.Error())
+			return
+		}
+		updates["due_date"] = dueDate
+	}
+
+	if len(updates) == 0 {
+		utils.JSONError(c, http.StatusBadRequest, "no fields to update")
+		return
+	}
+
+	if err := s.store.Tasks().Update(task.ID, updates); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update task: "+err.Error())
+		return
+	}
+
+	updated, ok := s.findTaskByID(c, event, task.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatTaskResponse(updated))
+}
+
+// DeleteTask deletes a task (organizer only).
+func (s *Service) DeleteTask(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, "only organizers can delete tasks")
+		return
+	}
+
+	task, ok := s.findEventTask(c, event)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Tasks().Delete(task.ID); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to delete task: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "task deleted successfully",
+	})
+}
+
+// AssignTask assigns a task to an event member (organizer only).
+func (s *Service) AssignTask(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	var req AssignTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	if req.UserID == 0 && req.Email == "" {
+		utils.JSONError(c, http.StatusBadRequest, "userId or email is required")
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, "only organizers can assign tasks")
+		return
+	}
+
+	task, ok := s.findEventTask(c, event)
+	if !ok {
+		return
+	}
+
+	// Resolve the assignee
+	var assignee models.User
+	var err error
+	if req.UserID != 0 {
+		assignee, err = s.store.Users().FindByID(req.UserID)
+	} else {
+		assignee, err = s.store.Users().FindByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
+	}
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.JSONError(c, http.StatusNotFound, "user not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "failed to find user: "+err.Error())
+		return
+	}
+
+	assigneeRole, err := s.store.Attendees().Role(event, assignee.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch attendee record: "+err.Error())
+		return
+	}
+	if assigneeRole == "" {
+		utils.JSONError(c, http.StatusBadRequest, "tasks can only be assigned to event members")
+		return
+	}
+
+	if err := s.store.Tasks().Update(task.ID, map[string]interface{}{"assigned_to": assignee.ID}); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to assign task: "+err.Error())
+		return
+	}
+
+	updated, ok := s.findTaskByID(c, event, task.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatTaskResponse(updated))
+}
+
+// UnassignTask clears the assignee of a task (organizer only).
+func (s *Service) UnassignTask(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	event, role, ok := s.loadEvent(c, userID)
+	if !ok {
+		return
+	}
+	if role != "organizer" {
+		utils.JSONError(c, http.StatusForbidden, "only organizers can unassign tasks")
+		return
+	}
+
+	task, ok := s.findEventTask(c, event)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Tasks().Update(task.ID, map[string]interface{}{"assigned_to": nil}); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to unassign task: "+err.Error())
+		return
+	}
+
+	updated, ok := s.findTaskByID(c, event, task.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatTaskResponse(updated))
+}
+
+// findTaskByID reloads a task with its relations after a write.
+func (s *Service) findTaskByID(c *gin.Context, event models.Event, taskID uint) (models.Task, bool) {
+	task, err := s.store.Tasks().FindInEvent(event.ID, taskID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to fetch task: "+err.Error())
+		return task, false
+	}
+	task.Event = event
+	return task, true
+}
+
+// formatTaskResponse formats a task for JSON response.
+func formatTaskResponse(task models.Task) gin.H {
+	dueDateStr := ""
+	if task.DueDate != nil {
+		dueDateStr = task.DueDate.Format("2006-01-02")
+	}
+
+	assigneeInfo := gin.H{}
+	if task.AssignedTo != nil && task.Assignee != nil {
+		assigneeInfo = gin.H{
+			"id":    task.Assignee.ID,
+			"name":  task.Assignee.Name,
+			"email": task.Assignee.Email,
+		}
+	}
+
+	return gin.H{
+		"id":          task.ID,
+		"eventId":     task.EventID,
+		"eventTitle":  task.Event.Title,
+		"description": task.Description,
+		"assignedTo":  task.AssignedTo,
+		"assignee":    assigneeInfo,
+		"status":      task.Status,
+		"dueDate":     dueDateStr,
+		"createdBy":   task.CreatedBy,
+		"creator": gin.H{
+			"id":    task.Creator.ID,
+			"name":  task.Creator.Name,
+			"email": task.Creator.Email,
+		},
+		"createdAt": task.CreatedAt,
+		"updatedAt": task.UpdatedAt,
+	}
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateTask(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	guest := store.addUser("Gus", "gus@example.com")
+	event := store.addEvent("Launch", organizer)
+	store.addAttendee(event, guest, "attendee")
+	svc := NewService(store)
+	path := fmt.Sprintf("/events/%d/tasks", event.ID)
+
+	w := serve(t, svc.CreateTask, http.MethodPost, "/events/:id/tasks", path, organizer.ID,
+		map[string]interface{}{"description": "Book the room", "assignedTo": guest.ID, "dueDate": "2030-01-02"})
+	expectStatus(t, w, http.StatusCreated)
+	body := decode(t, w)
+	if body["description"] != "Book the room" || body["status"] != "pending" || body["dueDate"] != "2030-01-02" {
+		t.Errorf("task = %v", body)
+	}
+	if assignee := body["assignee"].(map[string]interface{}); assignee["email"] != guest.Email {
+		t.Errorf("assignee = %v", assignee)
+	}
+	if body["eventTitle"] != event.Title {
+		t.Errorf("eventTitle = %v", body["eventTitle"])
+	}
+	if len(store.tasks) != 1 {
+		t.Errorf("%d tasks stored, want 1", len(store.tasks))
+	}
+}
+
+func TestCreateTaskErrors(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	guest := store.addUser("Gus", "gus@example.com")
+	stranger := store.addUser("Sam", "sam@example.com")
+	event := store.addEvent("Launch", organizer)
+	store.addAttendee(event, guest, "attendee")
+	svc := NewService(store)
+	path := fmt.Sprintf("/events/%d/tasks", event.ID)
+	task := map[string]interface{}{"description": "Book the room"}
+
+	tests := []struct {
+		name    string
+		path    string
+		userID  uint
+		body    map[string]interface{}
+		status  int
+		message string
+	}{
+		{"unauthenticated", path, 0, task, http.StatusUnauthorized, "user not authenticated"},
+		{"invalid event ID", "/events/x/tasks", organizer.ID, task, http.StatusBadRequest, "invalid event ID"},
+		{"missing event", "/events/99/tasks", organizer.ID, task, http.StatusNotFound, "event not found"},
+		{"not a member", path, stranger.ID, task, http.StatusForbidden, "you are not authorized to view this event"},
+		{"attendee", path, guest.ID, task, http.StatusForbidden, "only organizers can create tasks"},
+		{"assignee not a member", path, organizer.ID,
+			map[string]interface{}{"description": "Book the room", "assignedTo": stranger.ID},
+			http.StatusBadRequest, "tasks can only be assigned to event members"},
+		{"invalid due date", path, organizer.ID,
+			map[string]interface{}{"description": "Book the room", "dueDate": "tomorrow"},
+			http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, svc.CreateTask, http.MethodPost, "/events/:id/tasks", tt.path, tt.userID, tt.body)
+			expectStatus(t, w, tt.status)
+			if msg := decode(t, w)["message"]; tt.message != "" && msg != tt.message {
+				t.Errorf("message = %v, want %q", msg, tt.message)
+			}
+		})
+	}
+	if len(store.tasks) != 0 {
+		t.Errorf("%d tasks stored, want 0", len(store.tasks))
+	}
+}
+
+func TestGetEventTasks(t *testing.T) {
+	store := &memoryStore{}
+	organizer := store.addUser("Olivia", "olivia@example.com")
+	guest := store.addUser("Gus", "gus@example.com")
+	event := store.addEvent("Launch", organizer)
+	store.addAttendee(event, guest, "attendee")
+	svc := NewService(store)
+	path := fmt.Sprintf("/events/%d/tasks", event.ID)
+
+	for _, description := range []string{"Book the room", "Order food"} {
+		w := serve(t, svc.CreateTask, http.MethodPost, "/events/:id/tasks", path, organizer.ID,
+			map[string]interface{}{"description": description})
+		expectStatus(t, w, http.StatusCreated)
+	}
+
+	w := serve(t, svc.GetEventTasks, http.MethodGet, "/events/:id/tasks", path, guest.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "Book the room") || !strings.Contains(body, "Order food") {
+		t.Errorf("tasks = %s", body)
+	}
+
+	w = serve(t, svc.GetEventTasks, http.MethodGet, "/events/:id/tasks", path+"?status=completed", guest.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); body != "[]" {
+		t.Errorf("completed tasks = %s, want []", body)
+	}
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"event_planner_backend/config"
+	"event_planner_backend/mailer"
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+const (
+	// defaultEmailVerificationTTL is how long verification links stay valid
+	// when EMAIL_VERIFICATION_TTL_HOURS is not set.
+	defaultEmailVerificationTTL = 48 * time.Hour
+	// defaultVerificationResendInterval is the minimum time between two
+	// verification emails when EMAIL_VERIFICATION_RESEND_SECONDS is not set.
+	defaultVerificationResendInterval = time.Minute
+)
+
+// errVerificationInvalid is returned for malformed, expired or outdated
+// verification tokens.
+var errVerificationInvalid = errors.New("verification link is invalid or has expired")
+
+// VerifyEmailRequest defines the payload to verify an email address.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// emailVerificationTTL returns the validity of new verification links.
+func emailVerificationTTL() time.Duration {
+	if hours, err := strconv.Atoi(config.GetEnv("EMAIL_VERIFICATION_TTL_HOURS", "")); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultEmailVerificationTTL
+}
+
+// verificationResendInterval returns the minimum time between two
+// verification emails to a user.
+func verificationResendInterval() time.Duration {
+	if seconds, err := strconv.Atoi(config.GetEnv("EMAIL_VERIFICATION_RESEND_SECONDS", "")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultVerificationResendInterval
+}
+
+// sendVerificationEmail mails user a link verifying their email. The token
+// names the email, so that it stops working if the email changes.
+func sendVerificationEmail(user models.User) error {
+	ttl := emailVerificationTTL()
+	token, err := utils.GenerateScopedJWT(jwtKeys(), utils.PurposeVerifyEmail, jwt.MapClaims{"uid": user.ID, "email": user.Email}, ttl)
+	if err != nil {
+		return err
+	}
+	link := linkWithToken(config.GetEnv("EMAIL_VERIFICATION_URL", "http://localhost:4200/verify-email"), token)
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Event Planner email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm that this email address belongs to you by opening this link within %d hours:\n\n%s\n\n"+
+			"If you did not create an Event Planner account, ignore this email.\n",
+			user.Name, int(ttl.Hours()), link),
+	})
+	return nil
+}
+
+// claimVerificationEmail records that a verification email is sent to an
+// unverified user now, unless one was sent less than the resend interval
+// ago. It returns the time to wait when throttled.
+func claimVerificationEmail(store repository.Store, userID uint) (bool, time.Duration, error) {
+	now := time.Now()
+	interval := verificationResendInterval()
+	claimed, err := store.Users().ClaimVerificationEmail(userID, now, now.Add(-interval))
+	if err != nil || claimed {
+		return claimed, 0, err
+	}
+
+	user, err := store.Users().FindByID(userID)
+	if err != nil {
+		return false, 0, err
+	}
+	wait := interval
+	if user.VerificationSentAt != nil {
+		wait = time.Until(user.VerificationSentAt.Add(interval))
+	}
+	return false, wait, nil
+}
+
+// VerifyEmail marks the email of a user as verified from the token of the
+// verification email. Invitations sent to that email are attached to the
+// account at this point. It is public: the signed token authenticates it.
+func (s *Service) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	claims, err := utils.ParseScopedJWT(jwtKeys(), utils.PurposeVerifyEmail, req.Token)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, errVerificationInvalid.Error())
+		return
+	}
+	userID, ok := utils.ClaimUint(claims, "uid")
+	email, _ := claims["email"].(string)
+	if !ok || email == "" {
+		utils.JSONError(c, http.StatusBadRequest, errVerificationInvalid.Error())
+		return
+	}
+
+	if !s.available(c) {
+		return
+	}
+
+	var user models.User
+	alreadyVerified := false
+	invitedEvents := []uint{}
+	err = s.store.Transaction(func(store repository.Store) error {
+		var err error
+		if user, err = store.Users().FindByID(userID); err != nil {
+			if err == repository.ErrNotFound {
+				return errVerificationInvalid
+			}
+			return err
+		}
+		if user.Email != email {
+			return errVerificationInvalid
+		}
+		if user.EmailVerifiedAt != nil {
+			alreadyVerified = true
+			return nil
+		}
+
+		now := time.Now()
+		if err := store.Users().Update(user.ID, map[string]interface{}{"email_verified_at": now}); err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		user.EmailVerifiedAt = &now
+		if invitedEvents, err = attachInvitations(store, user, ""); err != nil {
+			return fmt.Errorf("failed to attach invitations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errVerificationInvalid {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	message := "email verified successfully"
+	if alreadyVerified {
+		message = "email is already verified"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       message,
+		"email":         user.Email,
+		"invitedEvents": invitedEvents,
+	})
+}
+
+// ResendVerificationEmail sends a new verification email to the current user,
+// at most once per EMAIL_VERIFICATION_RESEND_SECONDS.
+func (s *Service) ResendVerificationEmail(c *gin.Context) {
+	user, ok := s.loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		utils.JSONError(c, http.StatusConflict, "email is already verified")
+		return
+	}
+
+	claimed, wait, err := claimVerificationEmail(s.store, user.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to update user: "+err.Error())
+		return
+	}
+	if !claimed {
+		seconds := retryAfterSeconds(wait)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		utils.JSONError(c, http.StatusTooManyRequests, fmt.Sprintf("a verification email was sent recently; try again in %d seconds", seconds))
+		return
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to create verification link")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "verification email sent to " + user.Email,
+	})
+}
@@ -13,6 +13,12 @@ CREATE TABLE IF NOT EXISTS `users` (
     `email` VARCHAR(255) NOT NULL,
     `password_hash` VARCHAR(255) NOT NULL,
     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `email_verified_at` DATETIME NULL, -- NULL until the email is verified
+    `verification_sent_at` DATETIME NULL, -- last verification email, for throttling
+    `is_admin` TINYINT(1) NOT NULL DEFAULT 0, -- set manually; grants the /api/admin routes
+    `totp_secret` VARCHAR(64) NULL, -- base32 TOTP secret, pending until totp_enabled_at is set
+    `totp_enabled_at` DATETIME NULL, -- two-factor login enabled
+    `totp_last_step` BIGINT NULL, -- last accepted TOTP time step, against replays
     PRIMARY KEY (`user_id`),
     UNIQUE KEY `ux_users_email` (`email`),
     KEY `ix_users_created_at` (`created_at`)
@@ -27,16 +33,23 @@ CREATE TABLE IF NOT EXISTS `events` (
     `title` VARCHAR(255) NOT NULL,
     `description` TEXT NULL,
     `location` VARCHAR(255) NOT NULL,
-    `event_date` DATE NOT NULL,
-    `event_time` TIME NOT NULL,
+    `starts_at` DATETIME NOT NULL, -- absolute instant
+    `ends_at` DATETIME NOT NULL,
+    `timezone` VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA zone the event is planned in
+    `rrule` VARCHAR(500) NOT NULL DEFAULT '', -- RFC 5545 recurrence rule, empty for one-off events
+    `exdates` TEXT NULL, -- excluded occurrences, comma separated UTC date-times
+    `rdates` TEXT NULL, -- extra occurrences, same format
+    `ical_uid` VARCHAR(255) NULL, -- iCalendar UID of imported events
+    `capacity` INT UNSIGNED NULL, -- maximum attendees going, NULL for no limit
     `created_by` INT UNSIGNED NOT NULL, -- the main organizer
     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 
     PRIMARY KEY (`event_id`),
 
     KEY `ix_events_title` (`title`),
-    KEY `ix_events_date` (`event_date`),
+    KEY `ix_events_starts_at` (`starts_at`),
     KEY `ix_events_creator` (`created_by`),
+    KEY `ix_events_ical_uid` (`ical_uid`),
 
     CONSTRAINT `fk_events_creator`
         FOREIGN KEY (`created_by`) REFERENCES `users` (`user_id`)
@@ -55,11 +68,12 @@ CREATE TABLE IF NOT EXISTS `event_attendees` (
     -- organizer, attendee
     `role` ENUM('organizer','attendee') NOT NULL DEFAULT 'attendee',
 
-    -- attendance status
-    `status` ENUM('going','maybe','not_going','pending') 
+    -- attendance status; waitlisted when the event was full
+    `status` ENUM('going','maybe','not_going','pending','waitlisted') 
         NOT NULL DEFAULT 'pending',
 
     `invited_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `waitlisted_at` DATETIME(6) NULL, -- orders the waitlist
 
     PRIMARY KEY (`event_id`, `user_id`),
 
@@ -117,8 +131,386 @@ CREATE TABLE IF NOT EXISTS `tasks` (
 
 
 -------------------------------------------------------
--- SEARCH SUPPORT: Optional Keywords Table (Optional)
--- Helps with advanced filtering & indexing for search
+-- EVENT CHANGES TABLE
+-- One row per field modified by an event update
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `event_changes` (
+    `change_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `event_id` INT UNSIGNED NOT NULL,
+    `changed_by` INT UNSIGNED NOT NULL,
+    `field` VARCHAR(50) NOT NULL,
+    `old_value` TEXT NULL,
+    `new_value` TEXT NULL,
+    `occurrence_start` DATETIME NULL, -- set when a single occurrence changed
+    `changed_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`change_id`),
+
+    KEY `ix_event_changes_event` (`event_id`),
+
+    CONSTRAINT `fk_event_changes_event`
+        FOREIGN KEY (`event_id`) REFERENCES `events` (`event_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+
+    CONSTRAINT `fk_event_changes_user`
+        FOREIGN KEY (`changed_by`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-------------------------------------------------------
+-- EVENT OCCURRENCES TABLE
+-- Overrides for single occurrences of recurring events,
+-- keyed by the start given by the recurrence rule
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `event_occurrences` (
+    `event_id` INT UNSIGNED NOT NULL,
+    `occurrence_start` DATETIME NOT NULL,
+    `title` VARCHAR(255) NULL, -- NULL keeps the series value
+    `description` TEXT NULL,
+    `location` VARCHAR(255) NULL,
+    `starts_at` DATETIME NULL,
+    `ends_at` DATETIME NULL,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`event_id`, `occurrence_start`),
+
+    CONSTRAINT `fk_event_occurrences_event`
+        FOREIGN KEY (`event_id`) REFERENCES `events` (`event_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-------------------------------------------------------
+-- EVENT ATTENDEE OCCURRENCES TABLE
+-- Responses to single occurrences of recurring events;
+-- event_attendees.status applies to all other occurrences
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `event_attendee_occurrences` (
+    `event_id` INT UNSIGNED NOT NULL,
+    `user_id` INT UNSIGNED NOT NULL,
+    `occurrence_start` DATETIME NOT NULL,
+    `status` ENUM('going','maybe','not_going') NOT NULL,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`event_id`, `user_id`, `occurrence_start`),
+    KEY `ix_event_attendee_occurrences_user` (`user_id`),
+
+    CONSTRAINT `fk_event_attendee_occurrences_attendee`
+        FOREIGN KEY (`event_id`, `user_id`) REFERENCES `event_attendees` (`event_id`, `user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-------------------------------------------------------
+-- CALENDAR FEEDS TABLE
+-- Secret iCalendar subscription feed per user (token hash only)
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `calendar_feeds` (
+    `user_id` INT UNSIGNED NOT NULL,
+    `token_hash` CHAR(64) NOT NULL, -- SHA-256 of the feed token
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_used_at` TIMESTAMP NULL,
+
+    PRIMARY KEY (`user_id`),
+    UNIQUE KEY `ux_calendar_feeds_token` (`token_hash`),
+
+    CONSTRAINT `fk_calendar_feeds_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-------------------------------------------------------
+-- EVENT INVITATIONS TABLE
+-- Invitations of emails without an account, attached on signup
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `event_invitations` (
+    `invitation_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `event_id` INT UNSIGNED NOT NULL,
+    `email` VARCHAR(255) NOT NULL,
+    `name` VARCHAR(100) NOT NULL DEFAULT '', -- name given by the inviter
+    `role` ENUM('organizer','attendee') NOT NULL DEFAULT 'attendee',
+    `invited_by` INT UNSIGNED NOT NULL,
+    `invited_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` DATETIME NOT NULL,
+    `accepted_by` INT UNSIGNED NULL, -- user the invitation was attached to
+    `accepted_at` DATETIME NULL,
+
+    PRIMARY KEY (`invitation_id`),
+    UNIQUE KEY `ux_event_invitations_event_email` (`event_id`, `email`),
+    KEY `ix_event_invitations_email` (`email`),
+
+    CONSTRAINT `fk_event_invitations_event`
+        FOREIGN KEY (`event_id`) REFERENCES `events` (`event_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_event_invitations_inviter`
+        FOREIGN KEY (`invited_by`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_event_invitations_accepted_by`
+        FOREIGN KEY (`accepted_by`) REFERENCES `users` (`user_id`)
+        ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- NOTIFICATIONS TABLE
+-- In-app messages, e.g. promotions from a waitlist
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `notifications` (
+    `notification_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `event_id` INT UNSIGNED NULL,
+    `type` VARCHAR(50) NOT NULL, -- e.g. waitlist_promoted
+    `message` VARCHAR(500) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `read_at` DATETIME NULL,
+
+    PRIMARY KEY (`notification_id`),
+    KEY `ix_notifications_user` (`user_id`),
+
+    CONSTRAINT `fk_notifications_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_notifications_event`
+        FOREIGN KEY (`event_id`) REFERENCES `events` (`event_id`)
+        ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- EVENT OWNERSHIP TRANSFERS TABLE
+-- Nominations of a new creator, kept as an audit trail
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `event_ownership_transfers` (
+    `transfer_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `event_id` INT UNSIGNED NOT NULL,
+    `from_user_id` INT UNSIGNED NOT NULL,
+    `to_user_id` INT UNSIGNED NOT NULL,
+    `status` ENUM('pending','accepted','declined','cancelled') NOT NULL DEFAULT 'pending',
+    `requested_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `responded_at` DATETIME NULL,
+
+    PRIMARY KEY (`transfer_id`),
+    KEY `ix_event_ownership_transfers_event` (`event_id`),
+    KEY `ix_event_ownership_transfers_to_user` (`to_user_id`),
+
+    CONSTRAINT `fk_event_ownership_transfers_event`
+        FOREIGN KEY (`event_id`) REFERENCES `events` (`event_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_event_ownership_transfers_from_user`
+        FOREIGN KEY (`from_user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_event_ownership_transfers_to_user`
+        FOREIGN KEY (`to_user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- REFRESH TOKENS TABLE
+-- Rotating refresh tokens (hash only); a login starts a family
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+    `token_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `family_id` VARCHAR(64) NOT NULL,
+    `token_hash` CHAR(64) NOT NULL, -- SHA-256 of the refresh token
+    `access_jti` VARCHAR(64) NOT NULL, -- access token issued with it
+    `access_expires_at` DATETIME NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` DATETIME NOT NULL,
+    `used_at` DATETIME NULL, -- exchanged for a new token
+    `revoked_at` DATETIME NULL, -- logout, or reuse of a used token
+
+    PRIMARY KEY (`token_id`),
+    UNIQUE KEY `ux_refresh_tokens_hash` (`token_hash`),
+    KEY `ix_refresh_tokens_user` (`user_id`),
+    KEY `ix_refresh_tokens_family` (`family_id`),
+    KEY `ix_refresh_tokens_access_jti` (`access_jti`),
+
+    CONSTRAINT `fk_refresh_tokens_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- REVOKED TOKENS TABLE
+-- Denylist of access tokens (by jti) revoked before they expire
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `revoked_tokens` (
+    `jti` VARCHAR(64) NOT NULL,
+    `user_id` INT UNSIGNED NOT NULL,
+    `expires_at` DATETIME NOT NULL,
+    `revoked_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`jti`),
+    KEY `ix_revoked_tokens_expires` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- PASSWORD RESET TOKENS TABLE
+-- Single-use, expiring reset tokens mailed to users (hash only)
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `password_reset_tokens` (
+    `reset_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `token_hash` CHAR(64) NOT NULL, -- SHA-256 of the reset token
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` DATETIME NOT NULL,
+    `used_at` DATETIME NULL,
+
+    PRIMARY KEY (`reset_id`),
+    UNIQUE KEY `ux_password_reset_tokens_hash` (`token_hash`),
+    KEY `ix_password_reset_tokens_user` (`user_id`),
+
+    CONSTRAINT `fk_password_reset_tokens_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- RECOVERY CODES TABLE
+-- Single-use codes replacing TOTP codes (hash only)
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `recovery_codes` (
+    `code_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `code_hash` CHAR(64) NOT NULL, -- SHA-256 of the code
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `used_at` DATETIME NULL,
+
+    PRIMARY KEY (`code_id`),
+    KEY `ix_recovery_codes_user` (`user_id`),
+
+    CONSTRAINT `fk_recovery_codes_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- LOGIN THROTTLES TABLE
+-- Recent failed logins per email and per client IP, with backoff and lockout
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `login_throttles` (
+    `throttle_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `kind` ENUM('account','ip') NOT NULL,
+    `subject` VARCHAR(255) NOT NULL, -- email (whether an account uses it or not) or IP
+    `failures` INT UNSIGNED NOT NULL DEFAULT 0, -- since the last success or lockout
+    `last_failure_at` DATETIME NULL,
+    `blocked_until` DATETIME NULL, -- exponential backoff after a failure
+    `locked_until` DATETIME NULL,
+    `lockouts` INT UNSIGNED NOT NULL DEFAULT 0, -- each lockout doubles the next one
+
+    PRIMARY KEY (`throttle_id`),
+    UNIQUE KEY `ux_login_throttles_subject` (`kind`, `subject`),
+    KEY `ix_login_throttles_locked` (`locked_until`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- USER IDENTITIES TABLE
+-- Accounts of OpenID Connect providers linked to users
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `user_identities` (
+    `identity_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `provider` VARCHAR(50) NOT NULL, -- name in OIDC_PROVIDERS
+    `subject` VARCHAR(255) NOT NULL, -- `sub` claim of the ID token
+    `email` VARCHAR(255) NOT NULL, -- as last reported by the provider
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_login_at` DATETIME NULL,
+
+    PRIMARY KEY (`identity_id`),
+    UNIQUE KEY `ux_user_identities_subject` (`provider`, `subject`),
+    KEY `ix_user_identities_user` (`user_id`),
+
+    CONSTRAINT `fk_user_identities_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- OIDC LOGIN STATES TABLE
+-- Nonce and PKCE verifier of logins in progress at a provider
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `oidc_login_states` (
+    `state_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `state_hash` CHAR(64) NOT NULL, -- SHA-256 of the state
+    `provider` VARCHAR(50) NOT NULL,
+    `nonce` VARCHAR(64) NOT NULL,
+    `code_verifier` VARCHAR(128) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` DATETIME NOT NULL,
+
+    PRIMARY KEY (`state_id`),
+    UNIQUE KEY `ux_oidc_login_states_hash` (`state_hash`),
+    KEY `ix_oidc_login_states_expires` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- PERSONAL ACCESS TOKENS TABLE
+-- Long-lived, scoped API tokens users create for scripts
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `personal_access_tokens` (
+    `token_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `token_hash` CHAR(64) NOT NULL, -- SHA-256 of the token
+    `prefix` VARCHAR(16) NOT NULL, -- first characters of the token, to recognize it
+    `scopes` VARCHAR(255) NOT NULL, -- comma separated: events:read, events:write, tasks:manage
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` DATETIME NULL, -- NULL: never expires
+    `last_used_at` DATETIME NULL,
+    `revoked_at` DATETIME NULL,
+
+    PRIMARY KEY (`token_id`),
+    UNIQUE KEY `ux_personal_access_tokens_hash` (`token_hash`),
+    KEY `ix_personal_access_tokens_user` (`user_id`),
+
+    CONSTRAINT `fk_personal_access_tokens_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- SAVED SEARCHES
+-- Named searches of users (JSON of the query parameters). Subscribed ones
+-- are checked periodically; saved_search_matches holds the matches of the
+-- last check, so that only new matches are notified
+-------------------------------------------------------
+CREATE TABLE IF NOT EXISTS `saved_searches` (
+    `saved_search_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `query` TEXT NOT NULL,
+    `timezone` VARCHAR(64) NOT NULL DEFAULT '',
+    `notify` TINYINT(1) NOT NULL DEFAULT 0,
+    `checked_at` DATETIME NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`saved_search_id`),
+    UNIQUE KEY `ux_saved_searches_user_name` (`user_id`, `name`),
+    KEY `ix_saved_searches_notify` (`notify`),
+
+    CONSTRAINT `fk_saved_searches_user`
+        FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+        ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `saved_search_matches` (
+    `saved_search_id` INT UNSIGNED NOT NULL,
+    `kind` ENUM('event','task') NOT NULL,
+    `item_id` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`saved_search_id`, `kind`, `item_id`),
+
+    CONSTRAINT `fk_saved_search_matches_search`
+        FOREIGN KEY (`saved_search_id`) REFERENCES `saved_searches` (`saved_search_id`)
+        ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-------------------------------------------------------
+-- EVENT TAGS
+-- Normalized tags of events (lowercase, single spaces), used to filter
+-- searches and count matches per tag
 -------------------------------------------------------
 CREATE TABLE IF NOT EXISTS `event_keywords` (
     `keyword_id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
@@ -126,7 +518,8 @@ CREATE TABLE IF NOT EXISTS `event_keywords` (
     `keyword` VARCHAR(100) NOT NULL,
 
     PRIMARY KEY (`keyword_id`),
-    KEY `ix_keyword_event` (`event_id`),
+    UNIQUE KEY `ux_event_keywords_event_keyword` (`event_id`, `keyword`),
+    KEY `ix_event_keywords_keyword` (`keyword`),
     FULLTEXT KEY `ft_keyword_text` (`keyword`),
 
     CONSTRAINT `fk_event_keywords_event`
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer is meant for local development: it appends emails to the file
+// at Path, or writes them to the application log when Path is empty.
+type FileMailer struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Send implements Mailer.
+func (m *FileMailer) Send(msg Message) error {
+	text := fmt.Sprintf("To: %s\nSubject: %s\nDate: %s\n\n%s\n", msg.To, msg.Subject, time.Now().Format(time.RFC1123Z), msg.Body)
+	if m.Path == "" {
+		log.Printf("email:\n%s", text)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text + "----\n"); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
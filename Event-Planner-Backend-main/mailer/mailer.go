@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"event_planner_backend/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the application, set by Init. It logs the
+// messages until Init is called.
+var Default Mailer = &FileMailer{}
+
+// Init sets Default from the MAIL_DRIVER environment variable: "smtp" sends
+// through SMTP_HOST, "file" appends to MAIL_FILE, and "log" (the default)
+// writes messages to the application log.
+func Init() {
+	switch driver := strings.ToLower(config.GetEnv("MAIL_DRIVER", "log")); driver {
+	case "smtp":
+		port, err := strconv.Atoi(config.GetEnv("SMTP_PORT", "587"))
+		if err != nil {
+			log.Printf("invalid SMTP_PORT, using 587")
+			port = 587
+		}
+		Default = &SMTPMailer{
+			Host:     config.GetEnv("SMTP_HOST", "localhost"),
+			Port:     port,
+			Username: config.GetEnv("SMTP_USERNAME", ""),
+			Password: config.GetEnv("SMTP_PASSWORD", ""),
+			From:     config.GetEnv("MAIL_FROM", "Event Planner <no-reply@localhost>"),
+		}
+	case "file":
+		Default = &FileMailer{Path: config.GetEnv("MAIL_FILE", "mail.log")}
+	case "log":
+		Default = &FileMailer{}
+	default:
+		log.Printf("unknown MAIL_DRIVER %q, writing emails to the log", driver)
+		Default = &FileMailer{}
+	}
+}
+
+// Send delivers msg with the Default mailer.
+func Send(msg Message) error {
+	return Default.Send(msg)
+}
@@ -2,30 +2,74 @@ package main
 
 import (
 	"log"
+	_ "time/tzdata" // Embed the zoneinfo database; the runtime image has none
 
 	"event_planner_backend/config"
+	"event_planner_backend/controllers"
+	"event_planner_backend/mailer"
 	"event_planner_backend/models"
+	"event_planner_backend/oidc"
+	"event_planner_backend/repository"
 	"event_planner_backend/routes"
 )
 
 // main starts the HTTP server.
 func main() {
 	config.LoadEnv()
+	config.InitJWTKeys()
 	config.InitDB()
+	mailer.Init()
+	oidc.Init()
+
+	var store repository.Store
+	if config.DB != nil {
+		store = repository.NewGormStore(config.DB)
+	}
+	svc := controllers.NewService(store)
 
 	// Auto-migrate if DB is connected; safe no-op otherwise
 	if config.DB != nil {
+		if err := config.MigrateLegacyEventSchedule(config.DB); err != nil {
+			log.Printf("legacy event migration failed: %v", err)
+		}
+		if err := config.MigrateEmailVerification(config.DB); err != nil {
+			log.Printf("email verification migration failed: %v", err)
+		}
 		if err := config.DB.AutoMigrate(
 			&models.User{},
 			&models.Event{},
 			&models.EventAttendee{},
+			&models.EventTag{},
 			&models.Task{},
+			&models.EventChange{},
+			&models.EventOccurrence{},
+			&models.EventAttendeeOccurrence{},
+			&models.CalendarFeed{},
+			&models.EventInvitation{},
+			&models.Notification{},
+			&models.EventOwnershipTransfer{},
+			&models.RefreshToken{},
+			&models.RevokedToken{},
+			&models.PasswordResetToken{},
+			&models.LoginThrottle{},
+			&models.RecoveryCode{},
+			&models.UserIdentity{},
+			&models.OIDCLoginState{},
+			&models.PersonalAccessToken{},
+			&models.SavedSearch{},
+			&models.SavedSearchMatch{},
 		); err != nil {
 			log.Printf("auto-migrate failed: %v", err)
 		}
+		if err := config.EnsureFullTextIndexes(config.DB); err != nil {
+			log.Printf("full-text index migration failed: %v", err)
+		}
+
+		// Notify users of new matches of their saved searches
+		go svc.WatchSavedSearches()
 	}
 
-	r := routes.SetupRouter()
+	r := routes.SetupRouter(svc)
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("server failed: %v", err)
 	}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/models"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// AccessTokenPrefix starts every personal access token, which tells them
+// apart from JWTs in the Authorization header.
+const AccessTokenPrefix = "ept_"
+
+// accessTokenUseInterval limits how often the last use of a token is saved.
+const accessTokenUseInterval = time.Minute
+
+// accessTokenScopeRule grants the routes under a path prefix to a scope.
+type accessTokenScopeRule struct {
+	prefix    string
+	readScope string // For GET requests
+	scope     string // For other methods
+}
+
+// accessTokenScopeRules are checked in order; routes matching none are
+// refused to personal access tokens, so that these cannot manage sessions,
+// two-factor login or tokens. The RSVP links and invitation links of an event
+// let anyone answer or join it, and ownership transfers hand it over: tokens
+// may not read the former nor answer the latter.
+var accessTokenScopeRules = []accessTokenScopeRule{
+	{"/api/events/:id/rsvp-links", "", ""},
+	{"/api/events/:id/invitations", "", models.ScopeEventsWrite},
+	{"/api/events/:id/transfer/accept", "", ""},
+	{"/api/events/:id/transfer/decline", "", ""},
+	{"/api/events/:id/tasks", models.ScopeTasksManage, models.ScopeTasksManage},
+	{"/api/events", models.ScopeEventsRead, models.ScopeEventsWrite},
+	{"/api/invitations/accept", "", models.ScopeEventsWrite},
+	{"/api/search", models.ScopeEventsRead, ""},
+	{"/api/tags", models.ScopeEventsRead, ""},
+	{"/api/saved-searches", models.ScopeEventsRead, models.ScopeEventsWrite},
+}
+
+// requiredScope returns the scope a personal access token needs for a route,
+// or "" when tokens may not use it.
+func requiredScope(method, path string) string {
+	for _, rule := range accessTokenScopeRules {
+		if path != rule.prefix && !strings.HasPrefix(path, rule.prefix+"/") {
+			continue
+		}
+		if method == http.MethodGet || method == http.MethodHead {
+			return rule.readScope
+		}
+		return rule.scope
+	}
+	return ""
+}
+
+// HasScope reports whether a comma separated list of scopes holds scope.
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Split(scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateAccessToken sets the user of a personal access token in the
+// context, or responds with an error.
+func authenticateAccessToken(c *gin.Context, store repository.Store, token string) bool {
+	if store == nil {
+		utils.JSONError(c, http.StatusInternalServerError, "database not available")
+		return false
+	}
+
+	now := time.Now()
+	stored, err := store.AccessTokens().FindActiveByHash(utils.HashToken(token), now)
+	if err == repository.ErrNotFound {
+		utils.JSONError(c, http.StatusUnauthorized, "invalid, expired or revoked token")
+		return false
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to check token")
+		return false
+	}
+
+	scope := requiredScope(c.Request.Method, c.FullPath())
+	if scope == "" {
+		utils.JSONError(c, http.StatusForbidden, "this endpoint cannot be used with a personal access token")
+		return false
+	}
+	if !HasScope(stored.Scopes, scope) {
+		utils.JSONError(c, http.StatusForbidden, "token lacks the "+scope+" scope")
+		return false
+	}
+
+	user, err := store.Users().FindByID(stored.UserID)
+	if err != nil {
+		utils.JSONError(c, http.StatusUnauthorized, "user not found")
+		return false
+	}
+
+	// Saved at most once per interval, so scripts do not cause a write per call
+	store.AccessTokens().MarkUsed(stored.ID, now, now.Add(-accessTokenUseInterval))
+
+	c.Set("userID", user.ID)
+	c.Set("email", user.Email)
+	return true
+}
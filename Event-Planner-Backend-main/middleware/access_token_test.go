@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"event_planner_backend/models"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{http.MethodGet, "/api/events", models.ScopeEventsRead},
+		{http.MethodGet, "/api/events/:id", models.ScopeEventsRead},
+		{http.MethodPatch, "/api/events/:id", models.ScopeEventsWrite},
+		{http.MethodGet, "/api/events/:id/tasks/:taskId", models.ScopeTasksManage},
+		{http.MethodPost, "/api/invitations/accept", models.ScopeEventsWrite},
+		{http.MethodGet, "/api/search", models.ScopeEventsRead},
+		{http.MethodPost, "/api/saved-searches", models.ScopeEventsWrite},
+
+		// Links granting access to the event, and ownership changes
+		{http.MethodGet, "/api/events/:id/rsvp-links", ""},
+		{http.MethodGet, "/api/events/:id/invitations", ""},
+		{http.MethodDelete, "/api/events/:id/invitations/:invitationId", models.ScopeEventsWrite},
+		{http.MethodGet, "/api/events/:id/transfer", models.ScopeEventsRead},
+		{http.MethodPost, "/api/events/:id/transfer", models.ScopeEventsWrite},
+		{http.MethodPost, "/api/events/:id/transfer/accept", ""},
+		{http.MethodPost, "/api/events/:id/transfer/decline", ""},
+
+		// Account routes
+		{http.MethodPost, "/api/logout", ""},
+		{http.MethodGet, "/api/tokens", ""},
+		{http.MethodGet, "/api/notifications", ""},
+	}
+	for _, tt := range tests {
+		if got := requiredScope(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s: scope %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
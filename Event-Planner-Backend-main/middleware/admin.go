@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// RequireAdmin refuses the request unless the user is an administrator in
+// store. It must run after AuthMiddleware.
+func RequireAdmin(store repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+			return
+		}
+		if store == nil {
+			utils.JSONError(c, http.StatusInternalServerError, "database not available")
+			return
+		}
+		user, err := store.Users().FindByID(userID)
+		if err != nil {
+			utils.JSONError(c, http.StatusUnauthorized, "user not found")
+			return
+		}
+		if !user.IsAdmin {
+			utils.JSONError(c, http.StatusForbidden, "admin access required")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -4,15 +4,19 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"event_planner_backend/config"
+	"event_planner_backend/repository"
 	"event_planner_backend/utils"
 )
 
-// AuthMiddleware validates JWT tokens and sets user context.
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens, or personal access tokens within their
+// scopes, and sets user context. Revoked tokens and personal access tokens
+// are looked up in store, which is nil when no database is connected.
+func AuthMiddleware(store repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -28,13 +32,25 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
-		secret := config.MustGetEnv("JWT_SECRET", "dev_secret_change_me")
-		claims, err := utils.ParseAndValidateJWT(secret, tokenString)
+		if strings.HasPrefix(tokenString, AccessTokenPrefix) {
+			if authenticateAccessToken(c, store, tokenString) {
+				c.Next()
+			}
+			return
+		}
+
+		claims, err := utils.ParseAndValidateJWT(config.JWTKeys, tokenString)
 		if err != nil {
 			utils.JSONError(c, http.StatusUnauthorized, "invalid or expired token")
 			return
 		}
 
+		// Scoped tokens (invitations, ...) cannot be used to log in
+		if _, scoped := claims["purpose"]; scoped {
+			utils.JSONError(c, http.StatusUnauthorized, "invalid or expired token")
+			return
+		}
+
 		// Extract user ID from claims
 		sub, ok := claims["sub"]
 		if !ok {
@@ -62,9 +78,31 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Revoked tokens (logout, stolen refresh tokens) are denied until they expire
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			utils.JSONError(c, http.StatusUnauthorized, "invalid token claims")
+			return
+		}
+		if store != nil {
+			revoked, err := store.Sessions().AccessTokenRevoked(jti)
+			if err != nil {
+				utils.JSONError(c, http.StatusInternalServerError, "failed to check token")
+				return
+			}
+			if revoked {
+				utils.JSONError(c, http.StatusUnauthorized, "token has been revoked")
+				return
+			}
+		}
+
 		// Set user ID in context
 		c.Set("userID", userID)
 		c.Set("email", claims["email"])
+		c.Set("jti", jti)
+		if exp, ok := claims["exp"].(float64); ok {
+			c.Set("tokenExpiresAt", time.Unix(int64(exp), 0))
+		}
 
 		c.Next()
 	}
@@ -80,3 +118,10 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	return id, ok
 }
 
+// GetTokenID returns the jti and expiry of the access token of the request
+// (set by AuthMiddleware).
+func GetTokenID(c *gin.Context) (string, time.Time, bool) {
+	jti := c.GetString("jti")
+	expiresAt := c.GetTime("tokenExpiresAt")
+	return jti, expiresAt, jti != ""
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"event_planner_backend/config"
+	"event_planner_backend/repository"
+	"event_planner_backend/utils"
+)
+
+// Actions that UNVERIFIED_USER_RESTRICTIONS can forbid to users whose email
+// is not verified yet.
+const (
+	ActionLogin        = "login"
+	ActionCreateEvents = "create_events"
+	ActionInvite       = "invite"
+)
+
+// defaultUnverifiedRestrictions applies when UNVERIFIED_USER_RESTRICTIONS is
+// not set.
+const defaultUnverifiedRestrictions = ActionCreateEvents + "," + ActionInvite
+
+// actionDescriptions completes the error message of restricted actions.
+var actionDescriptions = map[string]string{
+	ActionLogin:        "log in",
+	ActionCreateEvents: "create events",
+	ActionInvite:       "invite people",
+}
+
+// RestrictedForUnverified reports whether users with an unverified email may
+// not perform action, according to UNVERIFIED_USER_RESTRICTIONS, a comma
+// separated list of actions ("none" allows everything).
+func RestrictedForUnverified(action string) bool {
+	for _, a := range strings.Split(config.GetEnv("UNVERIFIED_USER_RESTRICTIONS", defaultUnverifiedRestrictions), ",") {
+		if strings.TrimSpace(strings.ToLower(a)) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// UnverifiedMessage is the error returned when an unverified user attempts a
+// restricted action.
+func UnverifiedMessage(action string) string {
+	return "verify your email address to " + actionDescriptions[action]
+}
+
+// RequireVerifiedEmail refuses action to users whose email is not verified in
+// store, when the action is restricted. It must run after AuthMiddleware.
+func RequireVerifiedEmail(store repository.Store, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !RestrictedForUnverified(action) || store == nil {
+			c.Next()
+			return
+		}
+
+		userID, ok := GetUserID(c)
+		if !ok {
+			utils.JSONError(c, http.StatusUnauthorized, "user not authenticated")
+			return
+		}
+		user, err := store.Users().FindByID(userID)
+		if err != nil {
+			utils.JSONError(c, http.StatusUnauthorized, "user not found")
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			utils.JSONError(c, http.StatusForbidden, UnverifiedMessage(action))
+			return
+		}
+
+		c.Next()
+	}
+}
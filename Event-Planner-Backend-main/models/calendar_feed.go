@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// CalendarFeed maps to the `calendar_feeds` table.
+// It holds the secret of a user's iCalendar subscription feed; only the hash
+// of the token in the feed URL is stored.
+type CalendarFeed struct {
+	UserID     uint       `gorm:"column:user_id;type:int unsigned;primaryKey" json:"userId"`
+	TokenHash  string     `gorm:"column:token_hash;type:char(64);not null;uniqueIndex:ux_calendar_feeds_token" json:"-"`
+	CreatedAt  time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at;type:timestamp NULL" json:"lastUsedAt,omitempty"`
+}
+
+// TableName forces the GORM table name to `calendar_feeds`.
+func (CalendarFeed) TableName() string { return "calendar_feeds" }
@@ -8,15 +8,25 @@ type Event struct {
 	Title       string    `gorm:"column:title;type:varchar(255);not null" json:"title"`
 	Description string    `gorm:"column:description;type:text" json:"description"`
 	Location    string    `gorm:"column:location;type:varchar(255);not null" json:"location"`
-	EventDate   time.Time `gorm:"column:event_date;type:date;not null" json:"eventDate"`
-	EventTime   string    `gorm:"column:event_time;type:varchar(8);not null" json:"eventTime"`
+	StartsAt    time.Time `gorm:"column:starts_at;type:datetime;not null;index:ix_events_starts_at" json:"startsAt"` // Stored as an absolute instant
+	EndsAt      time.Time `gorm:"column:ends_at;type:datetime;not null" json:"endsAt"`
+	Timezone    string    `gorm:"column:timezone;type:varchar(64);not null;default:'UTC'" json:"timezone"`         // IANA name, e.g. "Europe/Berlin"
+	RRule       string    `gorm:"column:rrule;type:varchar(500);not null;default:''" json:"rrule,omitempty"`       // RFC 5545 RRULE, empty for one-off events
+	ExDates     string    `gorm:"column:exdates;type:text" json:"exdates,omitempty"`                               // Comma separated UTC date-times (20060102T150405Z)
+	RDates      string    `gorm:"column:rdates;type:text" json:"rdates,omitempty"`                                 // Same format as exdates
+	UID         *string   `gorm:"column:ical_uid;type:varchar(255);index:ix_events_ical_uid" json:"uid,omitempty"` // iCalendar UID of imported events
+	Capacity    *uint     `gorm:"column:capacity;type:int unsigned" json:"capacity,omitempty"`                     // Maximum attendees going, nil for no limit
 	CreatedBy   uint      `gorm:"column:created_by;type:int unsigned;not null" json:"createdBy"`
 	CreatedAt   time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
 
 	// Relations
 	Organizer User            `gorm:"foreignKey:CreatedBy" json:"organizer,omitempty"`
 	Attendees []EventAttendee `gorm:"foreignKey:EventID" json:"attendees,omitempty"`
+	Tags      []EventTag      `gorm:"foreignKey:EventID" json:"tags,omitempty"`
 }
 
+// IsRecurring reports whether the event has more than one occurrence.
+func (e Event) IsRecurring() bool { return e.RRule != "" || e.RDates != "" }
+
 // TableName forces the GORM table name to `events`.
 func (Event) TableName() string { return "events" }
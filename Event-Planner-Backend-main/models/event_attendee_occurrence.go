@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// EventAttendeeOccurrence maps to the `event_attendee_occurrences` table.
+// It stores an attendee's response to a single occurrence of a recurring
+// event; occurrences without a row use the status from `event_attendees`.
+type EventAttendeeOccurrence struct {
+	EventID         uint      `gorm:"column:event_id;type:int unsigned;primaryKey" json:"eventId"`
+	UserID          uint      `gorm:"column:user_id;type:int unsigned;primaryKey" json:"userId"`
+	OccurrenceStart time.Time `gorm:"column:occurrence_start;type:datetime;primaryKey" json:"occurrenceStart"`
+	Status          string    `gorm:"column:status;type:enum('going','maybe','not_going');not null" json:"status"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+// TableName forces the GORM table name to `event_attendee_occurrences`.
+func (EventAttendeeOccurrence) TableName() string { return "event_attendee_occurrences" }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// EventChange maps to the `event_changes` table.
+// Each row records a single field modified by an event update, so attendees
+// can see what changed and when.
+type EventChange struct {
+	ID         uint       `gorm:"column:change_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	EventID    uint       `gorm:"column:event_id;type:int unsigned;not null;index:ix_event_changes_event" json:"eventId"`
+	ChangedBy  uint       `gorm:"column:changed_by;type:int unsigned;not null" json:"changedBy"`
+	Field      string     `gorm:"column:field;type:varchar(50);not null" json:"field"`
+	OldValue   string     `gorm:"column:old_value;type:text" json:"oldValue"`
+	NewValue   string     `gorm:"column:new_value;type:text" json:"newValue"`
+	Occurrence *time.Time `gorm:"column:occurrence_start;type:datetime" json:"occurrence,omitempty"` // Set when a single occurrence of a recurring event changed
+	ChangedAt  time.Time  `gorm:"column:changed_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"changedAt"`
+
+	// Relations
+	User User `gorm:"foreignKey:ChangedBy" json:"user,omitempty"`
+}
+
+// TableName forces the GORM table name to `event_changes`.
+func (EventChange) TableName() string { return "event_changes" }
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// EventInvitation maps to the `event_invitations` table.
+// It holds an invitation sent to an email address that has no account yet.
+// The invitation becomes an EventAttendee row when that person signs up, or
+// when a user follows the invite link.
+type EventInvitation struct {
+	ID         uint       `gorm:"column:invitation_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	EventID    uint       `gorm:"column:event_id;type:int unsigned;not null;uniqueIndex:ux_event_invitations_event_email,priority:1" json:"eventId"`
+	Email      string     `gorm:"column:email;type:varchar(255);not null;uniqueIndex:ux_event_invitations_event_email,priority:2;index:ix_event_invitations_email" json:"email"`
+	Name       string     `gorm:"column:name;type:varchar(100);not null;default:''" json:"name,omitempty"` // Name given by the inviter, if any
+	Role       string     `gorm:"column:role;type:enum('organizer','attendee');not null;default:'attendee'" json:"role"`
+	InvitedBy  uint       `gorm:"column:invited_by;type:int unsigned;not null" json:"invitedBy"`
+	InvitedAt  time.Time  `gorm:"column:invited_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"invitedAt"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at;type:datetime;not null" json:"expiresAt"`
+	AcceptedBy *uint      `gorm:"column:accepted_by;type:int unsigned" json:"acceptedBy,omitempty"` // User the invitation was attached to
+	AcceptedAt *time.Time `gorm:"column:accepted_at;type:datetime" json:"acceptedAt,omitempty"`
+
+	// Relations
+	Event   Event `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	Inviter User  `gorm:"foreignKey:InvitedBy" json:"inviter,omitempty"`
+}
+
+// TableName forces the GORM table name to `event_invitations`.
+func (EventInvitation) TableName() string { return "event_invitations" }
+
+// Pending reports whether the invitation can still be accepted.
+func (i EventInvitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// EventOccurrence maps to the `event_occurrences` table.
+// Each row overrides a single occurrence of a recurring event, identified by
+// the start it would have had according to the recurrence rule. Nil fields
+// keep the value of the series.
+type EventOccurrence struct {
+	EventID         uint       `gorm:"column:event_id;type:int unsigned;primaryKey" json:"eventId"`
+	OccurrenceStart time.Time  `gorm:"column:occurrence_start;type:datetime;primaryKey" json:"occurrenceStart"`
+	Title           *string    `gorm:"column:title;type:varchar(255)" json:"title,omitempty"`
+	Description     *string    `gorm:"column:description;type:text" json:"description,omitempty"`
+	Location        *string    `gorm:"column:location;type:varchar(255)" json:"location,omitempty"`
+	StartsAt        *time.Time `gorm:"column:starts_at;type:datetime" json:"startsAt,omitempty"`
+	EndsAt          *time.Time `gorm:"column:ends_at;type:datetime" json:"endsAt,omitempty"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+// TableName forces the GORM table name to `event_occurrences`.
+func (EventOccurrence) TableName() string { return "event_occurrences" }
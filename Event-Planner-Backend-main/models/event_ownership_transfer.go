@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// EventOwnershipTransfer maps to the `event_ownership_transfers` table.
+// The creator of an event nominates another organizer, who becomes the
+// creator on accepting. Rows are kept once answered, as an audit trail of
+// the owners of the event.
+type EventOwnershipTransfer struct {
+	ID          uint       `gorm:"column:transfer_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	EventID     uint       `gorm:"column:event_id;type:int unsigned;not null;index:ix_event_ownership_transfers_event" json:"eventId"`
+	FromUserID  uint       `gorm:"column:from_user_id;type:int unsigned;not null" json:"fromUserId"`
+	ToUserID    uint       `gorm:"column:to_user_id;type:int unsigned;not null;index:ix_event_ownership_transfers_to_user" json:"toUserId"`
+	Status      string     `gorm:"column:status;type:enum('pending','accepted','declined','cancelled');not null;default:'pending'" json:"status"`
+	RequestedAt time.Time  `gorm:"column:requested_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"requestedAt"`
+	RespondedAt *time.Time `gorm:"column:responded_at;type:datetime" json:"respondedAt,omitempty"` // When accepted, declined or cancelled
+
+	// Relations
+	Event    Event `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	FromUser User  `gorm:"foreignKey:FromUserID" json:"fromUser,omitempty"`
+	ToUser   User  `gorm:"foreignKey:ToUserID" json:"toUser,omitempty"`
+}
+
+// TableName forces the GORM table name to `event_ownership_transfers`.
+func (EventOwnershipTransfer) TableName() string { return "event_ownership_transfers" }
@@ -0,0 +1,13 @@
+package models
+
+// EventTag maps to the `event_keywords` table.
+// Each row tags an event with a normalized keyword (lowercase, single spaces),
+// used to filter and facet searches.
+type EventTag struct {
+	ID      uint   `gorm:"column:keyword_id;type:int unsigned;primaryKey;autoIncrement" json:"-"`
+	EventID uint   `gorm:"column:event_id;type:int unsigned;not null;uniqueIndex:ux_event_keywords_event_keyword,priority:1" json:"eventId"`
+	Tag     string `gorm:"column:keyword;type:varchar(100);not null;uniqueIndex:ux_event_keywords_event_keyword,priority:2;index:ix_event_keywords_keyword" json:"tag"`
+}
+
+// TableName forces the GORM table name to `event_keywords`.
+func (EventTag) TableName() string { return "event_keywords" }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Kinds of LoginThrottle subjects.
+const (
+	LoginThrottleAccount = "account" // Subject is a normalized email
+	LoginThrottleIP      = "ip"      // Subject is a client IP address
+)
+
+// LoginThrottle maps to the `login_throttles` table.
+// It counts the recent failed logins of an email or of a client IP. Each
+// failure delays the next attempt exponentially, and too many lock the
+// subject out for a while. Emails are tracked whether an account uses them
+// or not, so that throttling does not reveal which ones exist.
+type LoginThrottle struct {
+	ID            uint       `gorm:"column:throttle_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	Kind          string     `gorm:"column:kind;type:enum('account','ip');not null;uniqueIndex:ux_login_throttles_subject,priority:1" json:"kind"`
+	Subject       string     `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:ux_login_throttles_subject,priority:2" json:"subject"`
+	Failures      int        `gorm:"column:failures;type:int unsigned;not null;default:0" json:"failures"` // Since the last success or lockout
+	LastFailureAt *time.Time `gorm:"column:last_failure_at;type:datetime" json:"lastFailureAt,omitempty"`
+	BlockedUntil  *time.Time `gorm:"column:blocked_until;type:datetime" json:"blockedUntil,omitempty"` // Backoff after the last failure
+	LockedUntil   *time.Time `gorm:"column:locked_until;type:datetime;index:ix_login_throttles_locked" json:"lockedUntil,omitempty"`
+	Lockouts      int        `gorm:"column:lockouts;type:int unsigned;not null;default:0" json:"lockouts"`
+}
+
+// TableName forces the GORM table name to `login_throttles`.
+func (LoginThrottle) TableName() string { return "login_throttles" }
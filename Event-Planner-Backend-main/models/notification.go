@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Notification maps to the `notifications` table.
+// It is a message shown to a user in the app, such as a waitlist promotion.
+type Notification struct {
+	ID        uint       `gorm:"column:notification_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"column:user_id;type:int unsigned;not null;index:ix_notifications_user" json:"userId"`
+	EventID   *uint      `gorm:"column:event_id;type:int unsigned" json:"eventId,omitempty"`
+	Type      string     `gorm:"column:type;type:varchar(50);not null" json:"type"` // e.g. "waitlist_promoted"
+	Message   string     `gorm:"column:message;type:varchar(500);not null" json:"message"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	ReadAt    *time.Time `gorm:"column:read_at;type:datetime" json:"readAt,omitempty"`
+}
+
+// TableName forces the GORM table name to `notifications`.
+func (Notification) TableName() string { return "notifications" }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OIDCLoginState maps to the `oidc_login_states` table.
+// It keeps the nonce and PKCE verifier of an OpenID Connect login between
+// the redirect to the provider and the callback. Each state is used once.
+type OIDCLoginState struct {
+	ID           uint      `gorm:"column:state_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	StateHash    string    `gorm:"column:state_hash;type:char(64);not null;uniqueIndex:ux_oidc_login_states_hash" json:"-"` // SHA-256 of the state
+	Provider     string    `gorm:"column:provider;type:varchar(50);not null" json:"provider"`
+	Nonce        string    `gorm:"column:nonce;type:varchar(64);not null" json:"-"`
+	CodeVerifier string    `gorm:"column:code_verifier;type:varchar(128);not null" json:"-"`
+	CreatedAt    time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;type:datetime;not null;index:ix_oidc_login_states_expires" json:"expiresAt"`
+}
+
+// TableName forces the GORM table name to `oidc_login_states`.
+func (OIDCLoginState) TableName() string { return "oidc_login_states" }
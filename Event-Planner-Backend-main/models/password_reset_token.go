@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PasswordResetToken maps to the `password_reset_tokens` table.
+// Reset tokens are mailed to the user and stored hashed; each can be used
+// once, before ExpiresAt.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"column:reset_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"column:user_id;type:int unsigned;not null;index:ix_password_reset_tokens_user" json:"userId"`
+	TokenHash string     `gorm:"column:token_hash;type:char(64);not null;uniqueIndex:ux_password_reset_tokens_hash" json:"-"` // SHA-256 of the token
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:datetime;not null" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:datetime" json:"usedAt,omitempty"`
+}
+
+// TableName forces the GORM table name to `password_reset_tokens`.
+func (PasswordResetToken) TableName() string { return "password_reset_tokens" }
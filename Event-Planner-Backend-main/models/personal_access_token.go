@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Scopes of personal access tokens.
+const (
+	ScopeEventsRead  = "events:read"  // List, view, export and search events
+	ScopeEventsWrite = "events:write" // Create, edit and delete events, invite and answer
+	ScopeTasksManage = "tasks:manage" // List, create, edit, assign and delete tasks
+)
+
+// PersonalAccessTokenScopes lists the valid scopes, in display order.
+var PersonalAccessTokenScopes = []string{ScopeEventsRead, ScopeEventsWrite, ScopeTasksManage}
+
+// PersonalAccessToken maps to the `personal_access_tokens` table.
+// It is a long-lived token a user creates for scripts, limited to scopes.
+// Only the hash of the token is stored; the prefix identifies it in lists.
+type PersonalAccessToken struct {
+	ID         uint       `gorm:"column:token_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID     uint       `gorm:"column:user_id;type:int unsigned;not null;index:ix_personal_access_tokens_user" json:"-"`
+	Name       string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	TokenHash  string     `gorm:"column:token_hash;type:char(64);not null;uniqueIndex:ux_personal_access_tokens_hash" json:"-"` // SHA-256 of the token
+	Prefix     string     `gorm:"column:prefix;type:varchar(16);not null" json:"prefix"`                                        // First characters of the token
+	Scopes     string     `gorm:"column:scopes;type:varchar(255);not null" json:"-"`                                            // Comma separated
+	CreatedAt  time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at;type:datetime" json:"expiresAt,omitempty"` // NULL for tokens that never expire
+	LastUsedAt *time.Time `gorm:"column:last_used_at;type:datetime" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at;type:datetime" json:"revokedAt,omitempty"`
+}
+
+// TableName forces the GORM table name to `personal_access_tokens`.
+func (PersonalAccessToken) TableName() string { return "personal_access_tokens" }
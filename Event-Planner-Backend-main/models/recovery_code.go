@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RecoveryCode maps to the `recovery_codes` table.
+// Single-use codes replacing a TOTP code when the authenticator is lost.
+// Only the SHA-256 of each code is stored; they are shown once, when
+// generated.
+type RecoveryCode struct {
+	ID        uint       `gorm:"column:code_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"column:user_id;type:int unsigned;not null;index:ix_recovery_codes_user" json:"userId"`
+	CodeHash  string     `gorm:"column:code_hash;type:char(64);not null" json:"-"` // SHA-256 of the code
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:datetime" json:"usedAt,omitempty"`
+}
+
+// TableName forces the GORM table name to `recovery_codes`.
+func (RecoveryCode) TableName() string { return "recovery_codes" }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RefreshToken maps to the `refresh_tokens` table.
+// Refresh tokens are stored hashed and used once: each refresh replaces the
+// token by a new one of the same family. Presenting a used token again means
+// it was stolen, and revokes the whole family.
+type RefreshToken struct {
+	ID              uint       `gorm:"column:token_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID          uint       `gorm:"column:user_id;type:int unsigned;not null;index:ix_refresh_tokens_user" json:"userId"`
+	FamilyID        string     `gorm:"column:family_id;type:varchar(64);not null;index:ix_refresh_tokens_family" json:"familyId"` // Shared by the tokens of one login
+	TokenHash       string     `gorm:"column:token_hash;type:char(64);not null;uniqueIndex:ux_refresh_tokens_hash" json:"-"`      // SHA-256 of the token
+	AccessJTI       string     `gorm:"column:access_jti;type:varchar(64);not null;index:ix_refresh_tokens_access_jti" json:"-"`   // Access token issued with it
+	AccessExpiresAt time.Time  `gorm:"column:access_expires_at;type:datetime;not null" json:"-"`
+	CreatedAt       time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	ExpiresAt       time.Time  `gorm:"column:expires_at;type:datetime;not null" json:"expiresAt"`
+	UsedAt          *time.Time `gorm:"column:used_at;type:datetime" json:"usedAt,omitempty"`       // Set when exchanged for a new token
+	RevokedAt       *time.Time `gorm:"column:revoked_at;type:datetime" json:"revokedAt,omitempty"` // Set on logout or reuse
+}
+
+// TableName forces the GORM table name to `refresh_tokens`.
+func (RefreshToken) TableName() string { return "refresh_tokens" }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RevokedToken maps to the `revoked_tokens` table.
+// It is the denylist of access tokens revoked before they expire, by their
+// `jti` claim. Rows are useless once ExpiresAt has passed.
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;type:varchar(64);primaryKey" json:"jti"`
+	UserID    uint      `gorm:"column:user_id;type:int unsigned;not null" json:"userId"`
+	ExpiresAt time.Time `gorm:"column:expires_at;type:datetime;not null;index:ix_revoked_tokens_expires" json:"expiresAt"`
+	RevokedAt time.Time `gorm:"column:revoked_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"revokedAt"`
+}
+
+// TableName forces the GORM table name to `revoked_tokens`.
+func (RevokedToken) TableName() string { return "revoked_tokens" }
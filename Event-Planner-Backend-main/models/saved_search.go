@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// SavedSearch maps to the `saved_searches` table.
+// It is a named search of a user, stored as the JSON of its query
+// parameters. With Notify, the user is notified of new matches.
+type SavedSearch struct {
+	ID        uint       `gorm:"column:saved_search_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"column:user_id;type:int unsigned;not null;uniqueIndex:ux_saved_searches_user_name,priority:1" json:"userId"`
+	Name      string     `gorm:"column:name;type:varchar(100);not null;uniqueIndex:ux_saved_searches_user_name,priority:2" json:"name"`
+	Query     string     `gorm:"column:query;type:text;not null" json:"-"`                                       // JSON of the search parameters
+	Timezone  string     `gorm:"column:timezone;type:varchar(64);not null;default:''" json:"timezone,omitempty"` // Reads dates of the filters; empty for UTC
+	Notify    bool       `gorm:"column:notify;not null;default:false;index:ix_saved_searches_notify" json:"notify"`
+	CheckedAt *time.Time `gorm:"column:checked_at;type:datetime" json:"checkedAt,omitempty"` // Last check for new matches
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+// TableName forces the GORM table name to `saved_searches`.
+func (SavedSearch) TableName() string { return "saved_searches" }
+
+// SavedSearchMatch maps to the `saved_search_matches` table.
+// It records an event or task that matched a subscribed saved search at a
+// check, so that each is notified once.
+type SavedSearchMatch struct {
+	SavedSearchID uint   `gorm:"column:saved_search_id;type:int unsigned;primaryKey" json:"savedSearchId"`
+	Kind          string `gorm:"column:kind;type:enum('event','task');primaryKey" json:"kind"`
+	ItemID        uint   `gorm:"column:item_id;type:int unsigned;primaryKey" json:"itemId"`
+}
+
+// TableName forces the GORM table name to `saved_search_matches`.
+func (SavedSearchMatch) TableName() string { return "saved_search_matches" }
@@ -9,15 +9,33 @@ import "time"
 // - user_id (INT UNSIGNED, PK, auto-increment)
 // - name (VARCHAR(100), NOT NULL)
 // - email (VARCHAR(255), NOT NULL, UNIQUE)
-// - password_hash (VARCHAR(255), NOT NULL)
+// - password_hash (VARCHAR(255), NOT NULL; empty for accounts created by OIDC login)
 // - role (ENUM('organizer','attendee') NOT NULL DEFAULT 'attendee')
 // - created_at (TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, indexed)
+// - email_verified_at (DATETIME NULL; NULL until the email is verified)
+// - verification_sent_at (DATETIME NULL; throttles verification emails)
+// - is_admin (TINYINT(1) NOT NULL DEFAULT 0)
+// - totp_secret (VARCHAR(64) NULL; base32 TOTP secret, pending until confirmed)
+// - totp_enabled_at (DATETIME NULL; set once two-factor login is confirmed)
+// - totp_last_step (BIGINT NULL; last accepted TOTP step, against replays)
 type User struct {
 	ID           uint      `gorm:"column:user_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
 	Name         string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
 	Email        string    `gorm:"column:email;type:varchar(255);not null;uniqueIndex:ux_users_email" json:"email"`
 	PasswordHash string    `gorm:"column:password_hash;type:varchar(255);not null" json:"-"`
 	CreatedAt    time.Time `gorm:"column:created_at;index:ix_users_created_at" json:"createdAt"`
+
+	EmailVerifiedAt    *time.Time `gorm:"column:email_verified_at;type:datetime" json:"emailVerifiedAt,omitempty"`
+	VerificationSentAt *time.Time `gorm:"column:verification_sent_at;type:datetime" json:"-"`
+
+	// IsAdmin grants access to the /api/admin routes. It is only set in the
+	// database, never through the API.
+	IsAdmin bool `gorm:"column:is_admin;not null;default:false" json:"-"`
+
+	// Two-factor authentication with TOTP
+	TOTPSecret    *string    `gorm:"column:totp_secret;type:varchar(64)" json:"-"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at;type:datetime" json:"-"`
+	TOTPLastStep  *int64     `gorm:"column:totp_last_step;type:bigint" json:"-"`
 }
 
 // TableName forces the GORM table name to `users`.
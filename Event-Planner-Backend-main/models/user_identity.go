@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UserIdentity maps to the `user_identities` table.
+// It links an account of an OpenID Connect provider, identified by the
+// provider name and the `sub` claim, to a user. A user may have several.
+type UserIdentity struct {
+	ID          uint       `gorm:"column:identity_id;type:int unsigned;primaryKey;autoIncrement" json:"id"`
+	UserID      uint       `gorm:"column:user_id;type:int unsigned;not null;index:ix_user_identities_user" json:"userId"`
+	Provider    string     `gorm:"column:provider;type:varchar(50);not null;uniqueIndex:ux_user_identities_subject,priority:1" json:"provider"`
+	Subject     string     `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:ux_user_identities_subject,priority:2" json:"subject"`
+	Email       string     `gorm:"column:email;type:varchar(255);not null" json:"email"` // As last reported by the provider
+	CreatedAt   time.Time  `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at;type:datetime" json:"lastLoginAt,omitempty"`
+}
+
+// TableName forces the GORM table name to `user_identities`.
+func (UserIdentity) TableName() string { return "user_identities" }
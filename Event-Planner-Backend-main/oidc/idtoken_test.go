@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		change func(jwt.MapClaims)
+		key    *rsa.PrivateKey
+	}{
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, nil},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "client-2" }, nil},
+		{"issued to another client", func(c jwt.MapClaims) { c["aud"] = []string{"client-1", "client-2"}; c["azp"] = "client-2" }, nil},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }, nil},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, nil},
+		{"other nonce", func(c jwt.MapClaims) { c["nonce"] = "nonce-2" }, nil},
+		{"no nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, nil},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, nil},
+		{"bad signature", func(jwt.MapClaims) {}, otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := newTestProvider(t)
+			claims := tp.claims("nonce-1")
+			tt.change(claims)
+			key := tp.key
+			if tt.key != nil {
+				key = tt.key
+			}
+			if _, err := tp.provider().verifyIDToken(context.Background(), sign(t, key, claims), "nonce-1"); err == nil {
+				t.Error("invalid ID token accepted")
+			}
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		tp := newTestProvider(t)
+		claims := tp.claims("nonce-1")
+		claims["email_verified"] = "false"
+		got, err := tp.provider().verifyIDToken(context.Background(), sign(t, tp.key, claims), "nonce-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Subject != "subject-1" || got.EmailVerified {
+			t.Errorf("claims = %+v", *got)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		tp := newTestProvider(t)
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, tp.claims("nonce-1"))
+		raw, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tp.provider().verifyIDToken(context.Background(), raw, "nonce-1"); err == nil {
+			t.Error("unsigned ID token accepted")
+		}
+	})
+}
+
+func TestSigningKeysCached(t *testing.T) {
+	tp := newTestProvider(t)
+	p := tp.provider()
+	for i := 0; i < 2; i++ {
+		if _, err := p.verifyIDToken(context.Background(), sign(t, tp.key, tp.claims("n")), "n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An unknown key ID does not fetch the keys again right away
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, tp.claims("n"))
+	token.Header["kid"] = "key-2"
+	raw, err := token.SignedString(tp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verifyIDToken(context.Background(), raw, "n"); err == nil {
+		t.Error("ID token with an unknown key accepted")
+	}
+	if tp.keyHits != 1 {
+		t.Errorf("keys fetched %d times, want 1", tp.keyHits)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// The example of RFC 7636, appendix B
+	if got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %s", got)
+	}
+}
@@ -0,0 +1,225 @@
+// Package oidc logs users in with OpenID Connect providers, using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"event_planner_backend/config"
+)
+
+// httpClient calls the providers.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Provider is an OpenID Connect identity provider, configured from the
+// OIDC_<NAME>_* environment variables.
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Empty for public clients, which rely on PKCE only
+	RedirectURL  string
+	Scopes       []string
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// metadata is the part of the discovery document used here.
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Claims are the identity claims of a verified ID token.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// providers holds the configured providers by name, set by Init.
+var providers = map[string]*Provider{}
+
+// Init reads the providers listed in OIDC_PROVIDERS, a comma separated list
+// of names. Each needs OIDC_<NAME>_ISSUER and OIDC_<NAME>_CLIENT_ID, and may
+// set OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL (default:
+// OIDC_REDIRECT_URL) and OIDC_<NAME>_SCOPES. The providers are contacted
+// on first use only.
+func Init() {
+	providers = map[string]*Provider{}
+	for _, name := range strings.Split(config.GetEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := &Provider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(config.GetEnv(prefix+"ISSUER", ""), "/"),
+			ClientID:     config.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: config.GetEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  config.GetEnv(prefix+"REDIRECT_URL", config.GetEnv("OIDC_REDIRECT_URL", "http://localhost:4200/auth/callback")),
+			Scopes:       strings.Fields(config.GetEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("OIDC provider %q needs %sISSUER and %sCLIENT_ID, skipping it", name, prefix, prefix)
+			continue
+		}
+		providers[name] = p
+	}
+}
+
+// Get returns the provider configured under name.
+func Get(name string) (*Provider, bool) {
+	p, ok := providers[strings.ToLower(name)]
+	return p, ok
+}
+
+// Names returns the names of the configured providers, sorted.
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getJSON decodes the JSON document at u into v.
+func getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// discover fetches the discovery document of the provider once.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	var m metadata
+	if err := getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(m.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("discovery returned issuer %q, expected %q", m.Issuer, p.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("discovery document lacks endpoints")
+	}
+	p.metadata = &m
+	return p.metadata, nil
+}
+
+// AuthCodeURL returns the URL of the provider where the user logs in. The
+// provider redirects to RedirectURL with a code and state.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades an authorization code for the claims of the ID token the
+// provider issues with it, after verifying the token and its nonce.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.ClientID)
+	basicAuth := p.ClientSecret != "" && (len(m.TokenAuthMethods) == 0 || contains(m.TokenAuthMethods, "client_secret_basic"))
+	if p.ClientSecret != "" && !basicAuth {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("token request refused: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, errors.New("token response lacks an id_token")
+	}
+	return p.verifyIDToken(ctx, body.IDToken, nonce)
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
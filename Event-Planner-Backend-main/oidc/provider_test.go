@@ -0,0 +1,207 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testProvider is a fake OpenID Connect provider. Its token endpoint issues
+// the ID token returned by idToken and records the form it received.
+type testProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	discovery map[string]interface{}
+	idToken   func() string
+	form      url.Values
+	user      string
+	password  string
+	keyHits   int
+}
+
+// newTestProvider starts a fake provider, stopped at the end of the test.
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(tp.discovery)
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		tp.keyHits++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		tp.form = r.PostForm
+		tp.user, tp.password, _ = r.BasicAuth()
+		if tp.form.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": tp.idToken()})
+	})
+	tp.Server = httptest.NewServer(mux)
+	t.Cleanup(tp.Close)
+	tp.discovery = map[string]interface{}{
+		"issuer":                 tp.URL,
+		"authorization_endpoint": tp.URL + "/authorize",
+		"token_endpoint":         tp.URL + "/token",
+		"jwks_uri":               tp.URL + "/keys",
+	}
+	return tp
+}
+
+// provider returns a Provider configured for the fake provider.
+func (tp *testProvider) provider() *Provider {
+	return &Provider{
+		Name:         "test",
+		Issuer:       tp.URL,
+		ClientID:     "client-1",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:4200/auth/callback",
+		Scopes:       []string{"openid", "email"},
+	}
+}
+
+// claims returns the claims of a valid ID token for nonce.
+func (tp *testProvider) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            tp.URL,
+		"aud":            "client-1",
+		"sub":            "subject-1",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "olivia@example.com",
+		"email_verified": true,
+		"name":           "Olivia",
+	}
+}
+
+// sign signs claims with key under the key ID of the fake provider.
+func sign(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	tp := newTestProvider(t)
+	u, err := tp.provider().AuthCodeURL(context.Background(), "state-1", "nonce-1", CodeChallenge("verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, tp.URL+"/authorize?") {
+		t.Errorf("URL = %s, want the authorization endpoint", u)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client-1",
+		"redirect_uri":          "http://localhost:4200/auth/callback",
+		"scope":                 "openid email",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := parsed.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestDiscoveryErrors(t *testing.T) {
+	tests := map[string]func(map[string]interface{}){
+		"other issuer":      func(d map[string]interface{}) { d["issuer"] = "https://evil.example.com" },
+		"missing endpoints": func(d map[string]interface{}) { delete(d, "jwks_uri") },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			tp := newTestProvider(t)
+			change(tp.discovery)
+			if _, err := tp.provider().AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+				t.Error("discovery succeeded")
+			}
+		})
+	}
+
+	tp := newTestProvider(t)
+	p := tp.provider()
+	p.Issuer += "/missing"
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Error("discovery of an unknown issuer succeeded")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tp := newTestProvider(t)
+	tp.idToken = func() string { return sign(t, tp.key, tp.claims("nonce-1")) }
+	p := tp.provider()
+
+	claims, err := p.Exchange(context.Background(), "good-code", "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *claims != (Claims{Subject: "subject-1", Email: "olivia@example.com", EmailVerified: true, Name: "Olivia"}) {
+		t.Errorf("claims = %+v", *claims)
+	}
+	if got := tp.form.Get("code_verifier"); got != "verifier-1" {
+		t.Errorf("code_verifier = %q, want verifier-1", got)
+	}
+	if tp.form.Get("grant_type") != "authorization_code" || tp.form.Get("redirect_uri") != p.RedirectURL {
+		t.Errorf("form = %v", tp.form)
+	}
+	if tp.user != "client-1" || tp.password != "secret" || tp.form.Get("client_secret") != "" {
+		t.Errorf("client authenticated as %q:%q, form secret %q; want basic auth", tp.user, tp.password, tp.form.Get("client_secret"))
+	}
+
+	if _, err := p.Exchange(context.Background(), "bad-code", "verifier-1", "nonce-1"); err == nil {
+		t.Error("refused code exchanged")
+	}
+}
+
+func TestExchangeClientSecretPost(t *testing.T) {
+	tp := newTestProvider(t)
+	tp.discovery["token_endpoint_auth_methods_supported"] = []string{"client_secret_post"}
+	tp.idToken = func() string { return sign(t, tp.key, tp.claims("nonce-1")) }
+
+	if _, err := tp.provider().Exchange(context.Background(), "good-code", "verifier-1", "nonce-1"); err != nil {
+		t.Fatal(err)
+	}
+	if tp.user != "" || tp.form.Get("client_secret") != "secret" {
+		t.Errorf("client authenticated as %q, form secret %q; want the form", tp.user, tp.form.Get("client_secret"))
+	}
+}
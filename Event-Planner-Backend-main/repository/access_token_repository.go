@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// AccessTokenRepository reads and writes the personal access tokens of
+// users.
+type AccessTokenRepository interface {
+	// List returns the tokens of a user, newest first.
+	List(userID uint) ([]models.PersonalAccessToken, error)
+	// CountActive counts the tokens of a user neither revoked nor expired at
+	// now.
+	CountActive(userID uint, now time.Time) (int64, error)
+	// Create inserts a token and sets its ID.
+	Create(token *models.PersonalAccessToken) error
+	// Find returns a token of a user, or ErrNotFound.
+	Find(tokenID, userID uint) (models.PersonalAccessToken, error)
+	// FindActiveByHash returns the token with the given hash, neither revoked
+	// nor expired at now, or ErrNotFound.
+	FindActiveByHash(tokenHash string, now time.Time) (models.PersonalAccessToken, error)
+	// MarkUsed records at as the last use of a token, unless it was already
+	// used since since.
+	MarkUsed(tokenID uint, at, since time.Time) error
+	// Revoke records when a token was revoked.
+	Revoke(tokenID uint, at time.Time) error
+	// RevokeAll revokes the tokens of a user not revoked yet.
+	RevokeAll(userID uint, at time.Time) error
+}
+
+// gormAccessTokenRepository implements AccessTokenRepository with GORM.
+type gormAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAccessTokenRepository returns an AccessTokenRepository backed by db.
+func NewAccessTokenRepository(db *gorm.DB) AccessTokenRepository {
+	return gormAccessTokenRepository{db: db}
+}
+
+func (r gormAccessTokenRepository) List(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, token_id DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r gormAccessTokenRepository) CountActive(userID uint, now time.Time) (int64, error) {
+	var active int64
+	err := r.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&active).Error
+	return active, err
+}
+
+func (r gormAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r gormAccessTokenRepository) Find(tokenID, userID uint) (models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := r.db.Where("token_id = ? AND user_id = ?", tokenID, userID).First(&token).Error
+	return token, notFound(err)
+}
+
+func (r gormAccessTokenRepository) FindActiveByHash(tokenHash string, now time.Time) (models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := r.db.Where("token_hash = ? AND revoked_at IS NULL", tokenHash).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		First(&token).Error
+	return token, notFound(err)
+}
+
+func (r gormAccessTokenRepository) MarkUsed(tokenID uint, at, since time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).
+		Where("token_id = ? AND (last_used_at IS NULL OR last_used_at < ?)", tokenID, since).
+		Update("last_used_at", at).Error
+}
+
+func (r gormAccessTokenRepository) Revoke(tokenID uint, at time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).Where("token_id = ?", tokenID).Update("revoked_at", at).Error
+}
+
+func (r gormAccessTokenRepository) RevokeAll(userID uint, at time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// AttendeeRepository reads and writes the members of events, their roles and
+// their attendance.
+type AttendeeRepository interface {
+	// Role returns the role of a user on an event: "organizer" for its
+	// creator, the role of their attendee record otherwise, or "" when they
+	// are not part of the event.
+	Role(event models.Event, userID uint) (string, error)
+	// Find returns the attendee record of a user on an event, or ErrNotFound.
+	Find(eventID, userID uint) (models.EventAttendee, error)
+	// FindWithUser is Find with the user of the record.
+	FindWithUser(eventID, userID uint) (models.EventAttendee, error)
+	// HasOtherOrganizer reports whether an event has an organizer other than
+	// userID: its creator, or an attendee with the organizer role.
+	HasOtherOrganizer(event models.Event, userID uint) (bool, error)
+	// ListByEvent returns the attendees of an event with their users,
+	// organizers first.
+	ListByEvent(eventID uint) ([]models.EventAttendee, error)
+	// ListByUser returns the attendee records of a user, most recently
+	// invited first, with their event, its organizer, attendees and tags.
+	ListByUser(userID uint) ([]models.EventAttendee, error)
+	// Create inserts an attendee record.
+	Create(attendee *models.EventAttendee) error
+	// Save updates an attendee record.
+	Save(attendee *models.EventAttendee) error
+	// CopyToEvent gives another event the attendees of an event, with the
+	// same roles and statuses.
+	CopyToEvent(fromEventID, toEventID uint) error
+	// Delete removes a user from an event.
+	Delete(eventID, userID uint) error
+	// UpdateRole sets the role of an attendee.
+	UpdateRole(eventID, userID uint, role string) error
+	// MakeOrganizer gives a user the organizer role on an event, creating
+	// their attendee record if they have none.
+	MakeOrganizer(eventID, userID uint, at time.Time) error
+	// CountGoing counts the attendees going to an event.
+	CountGoing(eventID uint) (int64, error)
+	// ListWaitlisted returns up to limit attendees of the waitlist of an
+	// event, earliest first; a negative limit returns all of them.
+	ListWaitlisted(eventID uint, limit int) ([]models.EventAttendee, error)
+	// Promote moves an attendee from the waitlist to going.
+	Promote(eventID, userID uint) error
+	// CountWaitlistedBefore counts the attendees waitlisted before the given
+	// time.
+	CountWaitlistedBefore(eventID uint, at time.Time) (int64, error)
+	// GoingToOccurrence returns the users going to the occurrence of an event
+	// at start: their response to it, or their series status when they have
+	// none.
+	GoingToOccurrence(eventID uint, start time.Time) ([]uint, error)
+}
+
+// gormAttendeeRepository implements AttendeeRepository with GORM.
+type gormAttendeeRepository struct {
+	db *gorm.DB
+}
+
+// NewAttendeeRepository returns an AttendeeRepository backed by db.
+func NewAttendeeRepository(db *gorm.DB) AttendeeRepository {
+	return gormAttendeeRepository{db: db}
+}
+
+func (r gormAttendeeRepository) Role(event models.Event, userID uint) (string, error) {
+	if event.CreatedBy == userID {
+		return "organizer", nil
+	}
+	attendee, err := r.Find(event.ID, userID)
+	if err == ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return attendee.Role, nil
+}
+
+func (r gormAttendeeRepository) Find(eventID, userID uint) (models.EventAttendee, error) {
+	var attendee models.EventAttendee
+	err := r.db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&attendee).Error
+	return attendee, notFound(err)
+}
+
+func (r gormAttendeeRepository) FindWithUser(eventID, userID uint) (models.EventAttendee, error) {
+	var attendee models.EventAttendee
+	err := r.db.Preload("User").Where("event_id = ? AND user_id = ?", eventID, userID).First(&attendee).Error
+	return attendee, notFound(err)
+}
+
+func (r gormAttendeeRepository) HasOtherOrganizer(event models.Event, userID uint) (bool, error) {
+	if event.CreatedBy != userID {
+		// The creator is an organizer even without an attendee record
+		return true, nil
+	}
+	var others int64
+	err := r.db.Model(&models.EventAttendee{}).
+		Where("event_id = ? AND role = ? AND user_id NOT IN ?", event.ID, "organizer", []uint{userID, event.CreatedBy}).
+		Count(&others).Error
+	return others > 0, err
+}
+
+func (r gormAttendeeRepository) ListByEvent(eventID uint) ([]models.EventAttendee, error) {
+	var attendees []models.EventAttendee
+	err := r.db.Where("event_id = ?", eventID).
+		Preload("User").
+		Order("role DESC, invited_at ASC"). // Organizers first
+		Find(&attendees).Error
+	return attendees, err
+}
+
+func (r gormAttendeeRepository) ListByUser(userID uint) ([]models.EventAttendee, error) {
+	var attendees []models.EventAttendee
+	err := r.db.Where("user_id = ?", userID).
+		Preload("Event", func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Organizer").Preload("Attendees", withUser).Preload("Tags", orderTags)
+		}).
+		Preload("User").
+		Order("invited_at DESC").
+		Find(&attendees).Error
+	return attendees, err
+}
+
+func (r gormAttendeeRepository) Create(attendee *models.EventAttendee) error {
+	return r.db.Create(attendee).Error
+}
+
+func (r gormAttendeeRepository) Save(attendee *models.EventAttendee) error {
+	return r.db.Save(attendee).Error
+}
+
+func (r gormAttendeeRepository) CopyToEvent(fromEventID, toEventID uint) error {
+	var attendees []models.EventAttendee
+	if err := r.db.Where("event_id = ?", fromEventID).Find(&attendees).Error; err != nil {
+		return err
+	}
+	if len(attendees) == 0 {
+		return nil
+	}
+	for i := range attendees {
+		attendees[i].EventID = toEventID
+	}
+	return r.db.Create(&attendees).Error
+}
+
+func (r gormAttendeeRepository) Delete(eventID, userID uint) error {
+	return r.db.Where("event_id = ? AND user_id = ?", eventID, userID).Delete(&models.EventAttendee{}).Error
+}
+
+func (r gormAttendeeRepository) UpdateRole(eventID, userID uint, role string) error {
+	return r.db.Model(&models.EventAttendee{}).
+		Where("event_id = ? AND user_id = ?", eventID, userID).
+		Update("role", role).Error
+}
+
+func (r gormAttendeeRepository) MakeOrganizer(eventID, userID uint, at time.Time) error {
+	organizer := models.EventAttendee{EventID: eventID, UserID: userID, Role: "organizer", Status: "pending", InvitedAt: at}
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"role": "organizer"}),
+	}).Create(&organizer).Error
+}
+
+func (r gormAttendeeRepository) CountGoing(eventID uint) (int64, error) {
+	var going int64
+	err := r.db.Model(&models.EventAttendee{}).
+		Where("event_id = ? AND status = ?", eventID, "going").
+		Count(&going).Error
+	return going, err
+}
+
+func (r gormAttendeeRepository) ListWaitlisted(eventID uint, limit int) ([]models.EventAttendee, error) {
+	query := r.db.Where("event_id = ? AND status = ?", eventID, "waitlisted").
+		Order("waitlisted_at ASC, invited_at ASC")
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+	var attendees []models.EventAttendee
+	err := query.Find(&attendees).Error
+	return attendees, err
+}
+
+func (r gormAttendeeRepository) Promote(eventID, userID uint) error {
+	return r.db.Model(&models.EventAttendee{}).
+		Where("event_id = ? AND user_id = ?", eventID, userID).
+		Updates(map[string]interface{}{"status": "going", "waitlisted_at": nil}).Error
+}
+
+func (r gormAttendeeRepository) CountWaitlistedBefore(eventID uint, at time.Time) (int64, error) {
+	var ahead int64
+	err := r.db.Model(&models.EventAttendee{}).
+		Where("event_id = ? AND status = ? AND waitlisted_at < ?", eventID, "waitlisted", at).
+		Count(&ahead).Error
+	return ahead, err
+}
+
+func (r gormAttendeeRepository) GoingToOccurrence(eventID uint, start time.Time) ([]uint, error) {
+	var going []uint
+	err := r.db.Model(&models.EventAttendee{}).
+		Joins("LEFT JOIN event_attendee_occurrences ON event_attendee_occurrences.event_id = event_attendees.event_id"+
+			" AND event_attendee_occurrences.user_id = event_attendees.user_id"+
+			" AND event_attendee_occurrences.occurrence_start = ?", start).
+		Where("event_attendees.event_id = ? AND COALESCE(event_attendee_occurrences.status, event_attendees.status) = ?", eventID, "going").
+		Pluck("event_attendees.user_id", &going).Error
+	return going, err
+}
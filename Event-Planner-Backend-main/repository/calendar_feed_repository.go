@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// CalendarFeedRepository reads and writes the secret calendar subscription
+// feeds of users, one per user.
+type CalendarFeedRepository interface {
+	// FindByUser returns the feed of a user, or ErrNotFound.
+	FindByUser(userID uint) (models.CalendarFeed, error)
+	// FindByHash returns the feed whose token hashes to tokenHash, or
+	// ErrNotFound.
+	FindByHash(tokenHash string) (models.CalendarFeed, error)
+	// Upsert creates the feed of a user or replaces its token, resetting
+	// when it was last used.
+	Upsert(feed *models.CalendarFeed) error
+	// Delete deletes the feed of a user, and reports whether there was one.
+	Delete(userID uint) (bool, error)
+	// Touch records when the feed of a user was last fetched.
+	Touch(userID uint, at time.Time) error
+}
+
+// gormCalendarFeedRepository implements CalendarFeedRepository with GORM.
+type gormCalendarFeedRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarFeedRepository returns a CalendarFeedRepository backed by db.
+func NewCalendarFeedRepository(db *gorm.DB) CalendarFeedRepository {
+	return gormCalendarFeedRepository{db: db}
+}
+
+func (r gormCalendarFeedRepository) FindByUser(userID uint) (models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.db.Where("user_id = ?", userID).First(&feed).Error
+	return feed, notFound(err)
+}
+
+func (r gormCalendarFeedRepository) FindByHash(tokenHash string) (models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.db.Where("token_hash = ?", tokenHash).First(&feed).Error
+	return feed, notFound(err)
+}
+
+func (r gormCalendarFeedRepository) Upsert(feed *models.CalendarFeed) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"token_hash": feed.TokenHash, "created_at": feed.CreatedAt, "last_used_at": nil}),
+	}).Create(feed).Error
+}
+
+func (r gormCalendarFeedRepository) Delete(userID uint) (bool, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r gormCalendarFeedRepository) Touch(userID uint, at time.Time) error {
+	return r.db.Model(&models.CalendarFeed{}).Where("user_id = ?", userID).Update("last_used_at", at).Error
+}
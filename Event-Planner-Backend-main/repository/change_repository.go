@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// ChangeRepository reads and writes the change history of events.
+type ChangeRepository interface {
+	// Create records changes and sets their IDs.
+	Create(changes []models.EventChange) error
+	// ListByEvent returns the changes of an event with the user who made
+	// them, newest first.
+	ListByEvent(eventID uint) ([]models.EventChange, error)
+}
+
+// gormChangeRepository implements ChangeRepository with GORM.
+type gormChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewChangeRepository returns a ChangeRepository backed by db.
+func NewChangeRepository(db *gorm.DB) ChangeRepository {
+	return gormChangeRepository{db: db}
+}
+
+func (r gormChangeRepository) Create(changes []models.EventChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return r.db.Create(&changes).Error
+}
+
+func (r gormChangeRepository) ListByEvent(eventID uint) ([]models.EventChange, error) {
+	var changes []models.EventChange
+	err := r.db.Where("event_id = ?", eventID).
+		Preload("User").
+		Order("changed_at DESC, change_id DESC").
+		Find(&changes).Error
+	return changes, err
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// EventRepository reads and writes events and manages their tags.
+type EventRepository interface {
+	// FindByID returns the event with the given ID, or ErrNotFound.
+	FindByID(id uint) (models.Event, error)
+	// FindByIDForUpdate is FindByID locking the event row until the end of
+	// the transaction.
+	FindByIDForUpdate(id uint) (models.Event, error)
+	// FindWithDetails returns an event with its organizer, its attendees and
+	// their users, and its tags, or ErrNotFound.
+	FindWithDetails(id uint) (models.Event, error)
+	// ListWithDetails returns the events with the given IDs that exist, with
+	// the details of FindWithDetails.
+	ListWithDetails(ids []uint) ([]models.Event, error)
+	// FindForCalendar returns an event with its organizer and its attendees
+	// and their users, or ErrNotFound.
+	FindForCalendar(id uint) (models.Event, error)
+	// ListVisibleTo returns the events a user created or is invited to, by
+	// start, with their organizer and their attendees and users.
+	ListVisibleTo(userID uint) ([]models.Event, error)
+	// FindByICalUID returns the ID of the event of a user imported with the
+	// given iCalendar UID, or 0 when there is none.
+	FindByICalUID(userID uint, uid string) (uint, error)
+	// FindVisible returns eventID when the user created the event or is
+	// invited to it, and 0 otherwise.
+	FindVisible(eventID, userID uint) (uint, error)
+	// ListOrganizedBy returns the events created by a user, newest first,
+	// with their attendees and tags.
+	ListOrganizedBy(userID uint) ([]models.Event, error)
+	// Create inserts an event and sets its ID.
+	Create(event *models.Event) error
+	// Update sets the given columns of an event.
+	Update(eventID uint, updates map[string]interface{}) error
+	// DeleteCascade deletes an event together with its attendees, invitations,
+	// tasks, tags, change history, ownership transfers and occurrence records.
+	DeleteCascade(event models.Event) error
+	// Tags returns the tags of an event, alphabetically.
+	Tags(eventID uint) ([]string, error)
+	// AddTags adds normalized tags the event does not have yet.
+	AddTags(eventID uint, tags []string) error
+	// RemoveTag removes a tag from an event, and reports whether it had it.
+	RemoveTag(eventID uint, tag string) (bool, error)
+	// TagSuggestions counts the tags starting with prefix, or all tags
+	// without it, of the events a user is part of, most used first, up to
+	// limit.
+	TagSuggestions(userID uint, prefix string, limit int) ([]TagCount, error)
+}
+
+// gormEventRepository implements EventRepository with GORM.
+type gormEventRepository struct {
+	db *gorm.DB
+}
+
+// NewEventRepository returns an EventRepository backed by db.
+func NewEventRepository(db *gorm.DB) EventRepository {
+	return gormEventRepository{db: db}
+}
+
+// orderTags preloads tags alphabetically.
+func orderTags(db *gorm.DB) *gorm.DB {
+	return db.Order("keyword ASC")
+}
+
+// withUser preloads the user of attendee records.
+func withUser(db *gorm.DB) *gorm.DB {
+	return db.Preload("User")
+}
+
+func (r gormEventRepository) FindByID(id uint) (models.Event, error) {
+	var event models.Event
+	err := r.db.Where("event_id = ?", id).First(&event).Error
+	return event, notFound(err)
+}
+
+func (r gormEventRepository) FindByIDForUpdate(id uint) (models.Event, error) {
+	var event models.Event
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("event_id = ?", id).First(&event).Error
+	return event, notFound(err)
+}
+
+func (r gormEventRepository) FindWithDetails(id uint) (models.Event, error) {
+	var event models.Event
+	err := r.db.Preload("Organizer").
+		Preload("Attendees", withUser).
+		Preload("Tags", orderTags).
+		Where("event_id = ?", id).
+		First(&event).Error
+	return event, notFound(err)
+}
+
+func (r gormEventRepository) ListWithDetails(ids []uint) ([]models.Event, error) {
+	var events []models.Event
+	err := r.db.Preload("Organizer").
+		Preload("Attendees", withUser).
+		Preload("Tags", orderTags).
+		Where("event_id IN ?", ids).
+		Find(&events).Error
+	return events, err
+}
+
+func (r gormEventRepository) FindForCalendar(id uint) (models.Event, error) {
+	var event models.Event
+	err := r.db.Preload("Organizer").
+		Preload("Attendees", withUser).
+		Where("event_id = ?", id).
+		First(&event).Error
+	return event, notFound(err)
+}
+
+func (r gormEventRepository) ListVisibleTo(userID uint) ([]models.Event, error) {
+	var events []models.Event
+	err := r.db.Preload("Organizer").
+		Preload("Attendees", withUser).
+		Where("created_by = ? OR event_id IN (?)", userID,
+			r.db.Model(&models.EventAttendee{}).Select("event_id").Where("user_id = ?", userID)).
+		Order("starts_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+func (r gormEventRepository) FindByICalUID(userID uint, uid string) (uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Event{}).
+		Where("created_by = ? AND ical_uid = ?", userID, uid).
+		Limit(1).Pluck("event_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (r gormEventRepository) FindVisible(eventID, userID uint) (uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Event{}).
+		Where("event_id = ? AND (created_by = ? OR event_id IN (?))", eventID, userID,
+			r.db.Model(&models.EventAttendee{}).Select("event_id").Where("user_id = ?", userID)).
+		Limit(1).Pluck("event_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (r gormEventRepository) ListOrganizedBy(userID uint) ([]models.Event, error) {
+	var events []models.Event
+	err := r.db.Where("created_by = ?", userID).
+		Preload("Attendees", withUser).
+		Preload("Tags", orderTags).
+		Order("created_at DESC").
+		Find(&events).Error
+	return events, err
+}
+
+func (r gormEventRepository) Create(event *models.Event) error {
+	return r.db.Create(event).Error
+}
+
+func (r gormEventRepository) Update(eventID uint, updates map[string]interface{}) error {
+	return r.db.Model(&models.Event{}).Where("event_id = ?", eventID).Updates(updates).Error
+}
+
+func (r gormEventRepository) DeleteCascade(event models.Event) error {
+	related := []struct {
+		model interface{}
+		name  string
+	}{
+		{&models.EventAttendeeOccurrence{}, "occurrence responses"},
+		{&models.EventOccurrence{}, "occurrence overrides"},
+		{&models.EventAttendee{}, "attendees"},
+		{&models.EventInvitation{}, "invitations"},
+		{&models.Task{}, "tasks"},
+		{&models.EventTag{}, "tags"},
+		{&models.EventChange{}, "event changes"},
+		{&models.EventOwnershipTransfer{}, "ownership transfers"},
+	}
+	for _, rel := range related {
+		if err := r.db.Where("event_id = ?", event.ID).Delete(rel.model).Error; err != nil {
+			return fmt.Errorf("failed to delete %s: %w", rel.name, err)
+		}
+	}
+	if err := r.db.Where("event_id = ?", event.ID).Delete(&models.Event{}).Error; err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+func (r gormEventRepository) Tags(eventID uint) ([]string, error) {
+	var rows []models.EventTag
+	if err := r.db.Where("event_id = ?", eventID).Order("keyword ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(rows))
+	for i, row := range rows {
+		tags[i] = row.Tag
+	}
+	return tags, nil
+}
+
+func (r gormEventRepository) AddTags(eventID uint, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]models.EventTag, len(tags))
+	for i, tag := range tags {
+		rows[i] = models.EventTag{EventID: eventID, Tag: tag}
+	}
+	return r.db.Create(&rows).Error
+}
+
+func (r gormEventRepository) RemoveTag(eventID uint, tag string) (bool, error) {
+	deleted := r.db.Where("event_id = ? AND keyword = ?", eventID, tag).Delete(&models.EventTag{})
+	return deleted.RowsAffected > 0, deleted.Error
+}
+
+func (r gormEventRepository) TagSuggestions(userID uint, prefix string, limit int) ([]TagCount, error) {
+	query := r.db.Model(&models.EventTag{}).
+		Select("event_keywords.keyword AS tag, COUNT(*) AS count").
+		Joins("INNER JOIN event_attendees ON event_keywords.event_id = event_attendees.event_id").
+		Where("event_attendees.user_id = ?", userID)
+	if prefix != "" {
+		query = query.Where("event_keywords.keyword LIKE ?", likeEscaper.Replace(prefix)+"%")
+	}
+	suggestions := []TagCount{}
+	err := query.Group("event_keywords.keyword").
+		Order("count DESC, tag ASC").
+		Limit(limit).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// IdentityRepository reads and writes the OpenID Connect identities linked
+// to users and the logins waiting for the callback of their provider.
+type IdentityRepository interface {
+	// Find returns the identity of a provider subject, or ErrNotFound.
+	Find(provider, subject string) (models.UserIdentity, error)
+	// Create inserts an identity.
+	Create(identity *models.UserIdentity) error
+	// Touch records a login with an identity and the email the provider
+	// reported, unless it is empty.
+	Touch(identityID uint, email string, at time.Time) error
+	// CreateLoginState inserts a login state, forgetting those expired at
+	// its creation.
+	CreateLoginState(state *models.OIDCLoginState) error
+	// ClaimLoginState deletes and returns the login state of a provider with
+	// the given hash, or ErrNotFound. Expired states are returned too.
+	ClaimLoginState(stateHash, provider string) (models.OIDCLoginState, error)
+}
+
+// gormIdentityRepository implements IdentityRepository with GORM.
+type gormIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository returns an IdentityRepository backed by db.
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return gormIdentityRepository{db: db}
+}
+
+func (r gormIdentityRepository) Find(provider, subject string) (models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return identity, notFound(err)
+}
+
+func (r gormIdentityRepository) Create(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r gormIdentityRepository) Touch(identityID uint, email string, at time.Time) error {
+	updates := map[string]interface{}{"last_login_at": at}
+	if email != "" {
+		updates["email"] = email
+	}
+	return r.db.Model(&models.UserIdentity{}).Where("identity_id = ?", identityID).Updates(updates).Error
+}
+
+func (r gormIdentityRepository) CreateLoginState(state *models.OIDCLoginState) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Forget the logins abandoned at the provider
+		if err := tx.Where("expires_at < ?", state.CreatedAt).Delete(&models.OIDCLoginState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+}
+
+func (r gormIdentityRepository) ClaimLoginState(stateHash, provider string) (models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND provider = ?", stateHash, provider).
+			First(&state).Error; err != nil {
+			return err
+		}
+		return tx.Where("state_id = ?", state.ID).Delete(&models.OIDCLoginState{}).Error
+	})
+	return state, notFound(err)
+}
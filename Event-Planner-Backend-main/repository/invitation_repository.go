@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// InvitationRepository reads and writes the invitations of emails without a
+// verified account.
+type InvitationRepository interface {
+	// FindForUpdate returns and locks the invitation of an email to an
+	// event, or ErrNotFound.
+	FindForUpdate(eventID uint, email string) (models.EventInvitation, error)
+	// FindByIDForUpdate returns and locks the invitation with the given ID,
+	// or ErrNotFound.
+	FindByIDForUpdate(id uint) (models.EventInvitation, error)
+	// PendingForEmailForUpdate returns and locks the invitations of an email
+	// that are neither accepted nor expired at now.
+	PendingForEmailForUpdate(email string, now time.Time) ([]models.EventInvitation, error)
+	// ListPending returns the invitations of an event not accepted yet,
+	// oldest first.
+	ListPending(eventID uint) ([]models.EventInvitation, error)
+	// Save creates or updates an invitation.
+	Save(invitation *models.EventInvitation) error
+	// MarkAccepted records that a user accepted an invitation.
+	MarkAccepted(id, userID uint, at time.Time) error
+	// Revoke deletes an invitation of an event not accepted yet, and reports
+	// whether there was one.
+	Revoke(id, eventID uint) (bool, error)
+}
+
+// gormInvitationRepository implements InvitationRepository with GORM.
+type gormInvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository returns an InvitationRepository backed by db.
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return gormInvitationRepository{db: db}
+}
+
+func (r gormInvitationRepository) FindForUpdate(eventID uint, email string) (models.EventInvitation, error) {
+	var invitation models.EventInvitation
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("event_id = ? AND email = ?", eventID, email).First(&invitation).Error
+	return invitation, notFound(err)
+}
+
+func (r gormInvitationRepository) FindByIDForUpdate(id uint) (models.EventInvitation, error) {
+	var invitation models.EventInvitation
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("invitation_id = ?", id).First(&invitation).Error
+	return invitation, notFound(err)
+}
+
+func (r gormInvitationRepository) PendingForEmailForUpdate(email string, now time.Time) ([]models.EventInvitation, error) {
+	var invitations []models.EventInvitation
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("email = ? AND accepted_at IS NULL AND expires_at > ?", email, now).
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r gormInvitationRepository) ListPending(eventID uint) ([]models.EventInvitation, error) {
+	var invitations []models.EventInvitation
+	err := r.db.Where("event_id = ? AND accepted_at IS NULL", eventID).
+		Order("invited_at ASC").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r gormInvitationRepository) Save(invitation *models.EventInvitation) error {
+	return r.db.Save(invitation).Error
+}
+
+func (r gormInvitationRepository) MarkAccepted(id, userID uint, at time.Time) error {
+	return r.db.Model(&models.EventInvitation{}).Where("invitation_id = ?", id).
+		Updates(map[string]interface{}{"accepted_by": userID, "accepted_at": at}).Error
+}
+
+func (r gormInvitationRepository) Revoke(id, eventID uint) (bool, error) {
+	result := r.db.Where("invitation_id = ? AND event_id = ? AND accepted_at IS NULL", id, eventID).
+		Delete(&models.EventInvitation{})
+	return result.RowsAffected > 0, result.Error
+}
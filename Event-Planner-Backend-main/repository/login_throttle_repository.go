@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// LoginThrottleRepository reads and writes the failed login counters of
+// emails and client IPs.
+type LoginThrottleRepository interface {
+	// Acquire returns and locks the throttle of a subject, creating it when
+	// there is none.
+	Acquire(kind, subject string) (models.LoginThrottle, error)
+	// FindByID returns the throttle with the given ID, or ErrNotFound.
+	FindByID(id uint) (models.LoginThrottle, error)
+	// FindByIDForUpdate is FindByID locking the throttle until the end of
+	// the transaction.
+	FindByIDForUpdate(id uint) (models.LoginThrottle, error)
+	// List returns the throttles locked out at now or, unless lockedOnly,
+	// with a failure since the given time, optionally of one kind only,
+	// locked out ones first.
+	List(lockedOnly bool, kind string, now, since time.Time) ([]models.LoginThrottle, error)
+	// Save updates a throttle.
+	Save(throttle *models.LoginThrottle) error
+	// Delete deletes the throttle with the given ID.
+	Delete(id uint) error
+	// DeleteSubject deletes the throttle of a subject.
+	DeleteSubject(kind, subject string) error
+	// DeleteIdle deletes the throttles without a failure since the given
+	// time that are not locked out at now.
+	DeleteIdle(since, now time.Time) error
+}
+
+// gormLoginThrottleRepository implements LoginThrottleRepository with GORM.
+type gormLoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginThrottleRepository returns a LoginThrottleRepository backed by db.
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return gormLoginThrottleRepository{db: db}
+}
+
+func (r gormLoginThrottleRepository) Acquire(kind, subject string) (models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Kind: kind, Subject: subject}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&throttle).Error; err != nil {
+		return throttle, err
+	}
+	throttle = models.LoginThrottle{}
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("kind = ? AND subject = ?", kind, subject).
+		First(&throttle).Error
+	return throttle, err
+}
+
+func (r gormLoginThrottleRepository) FindByID(id uint) (models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := r.db.Where("throttle_id = ?", id).First(&throttle).Error
+	return throttle, notFound(err)
+}
+
+func (r gormLoginThrottleRepository) FindByIDForUpdate(id uint) (models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("throttle_id = ?", id).First(&throttle).Error
+	return throttle, notFound(err)
+}
+
+func (r gormLoginThrottleRepository) List(lockedOnly bool, kind string, now, since time.Time) ([]models.LoginThrottle, error) {
+	query := r.db.Model(&models.LoginThrottle{})
+	if lockedOnly {
+		query = query.Where("locked_until > ?", now)
+	} else {
+		query = query.Where("locked_until > ? OR last_failure_at > ?", now, since)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var throttles []models.LoginThrottle
+	err := query.Order("locked_until DESC").Order("last_failure_at DESC").Find(&throttles).Error
+	return throttles, err
+}
+
+func (r gormLoginThrottleRepository) Save(throttle *models.LoginThrottle) error {
+	return r.db.Save(throttle).Error
+}
+
+func (r gormLoginThrottleRepository) Delete(id uint) error {
+	return r.db.Where("throttle_id = ?", id).Delete(&models.LoginThrottle{}).Error
+}
+
+func (r gormLoginThrottleRepository) DeleteSubject(kind, subject string) error {
+	return r.db.Where("kind = ? AND subject = ?", kind, subject).Delete(&models.LoginThrottle{}).Error
+}
+
+func (r gormLoginThrottleRepository) DeleteIdle(since, now time.Time) error {
+	return r.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", since, now).
+		Delete(&models.LoginThrottle{}).Error
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// NotificationRepository reads and writes the in-app notifications of users.
+type NotificationRepository interface {
+	// Create inserts a notification and sets its ID.
+	Create(notification *models.Notification) error
+	// List returns up to limit notifications of a user, newest first,
+	// optionally only the unread ones.
+	List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error)
+	// CountUnread counts the unread notifications of a user.
+	CountUnread(userID uint) (int64, error)
+	// Find returns a notification of a user, or ErrNotFound.
+	Find(id, userID uint) (models.Notification, error)
+	// MarkRead sets the read time of a notification.
+	MarkRead(id uint, at time.Time) error
+	// MarkAllRead marks the unread notifications of a user as read, and
+	// returns how many there were.
+	MarkAllRead(userID uint, at time.Time) (int64, error)
+}
+
+// gormNotificationRepository implements NotificationRepository with GORM.
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository returns a NotificationRepository backed by db.
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return gormNotificationRepository{db: db}
+}
+
+func (r gormNotificationRepository) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r gormNotificationRepository) List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.Notification
+	err := query.Order("created_at DESC, notification_id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (r gormNotificationRepository) CountUnread(userID uint) (int64, error) {
+	var unread int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error
+	return unread, err
+}
+
+func (r gormNotificationRepository) Find(id, userID uint) (models.Notification, error) {
+	var notification models.Notification
+	err := r.db.Where("notification_id = ? AND user_id = ?", id, userID).First(&notification).Error
+	return notification, notFound(err)
+}
+
+func (r gormNotificationRepository) MarkRead(id uint, at time.Time) error {
+	return r.db.Model(&models.Notification{}).Where("notification_id = ?", id).Update("read_at", at).Error
+}
+
+func (r gormNotificationRepository) MarkAllRead(userID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// OccurrenceRepository reads and writes the overrides of single occurrences
+// of recurring events and the responses of attendees to them. Occurrences
+// are identified by their event and the start the recurrence gives them.
+type OccurrenceRepository interface {
+	// Overrides returns the overrides of the given events.
+	Overrides(eventIDs []uint) ([]models.EventOccurrence, error)
+	// FindOverride returns the override of an occurrence, or ErrNotFound.
+	FindOverride(eventID uint, start time.Time) (models.EventOccurrence, error)
+	// SaveOverride creates or replaces the override of an occurrence.
+	SaveOverride(override *models.EventOccurrence) error
+	// ReplaceOverrides replaces all the overrides of an event.
+	ReplaceOverrides(eventID uint, overrides []models.EventOccurrence) error
+	// Responses returns the responses to occurrences of the given events,
+	// only those of userID unless it is 0.
+	Responses(eventIDs []uint, userID uint) ([]models.EventAttendeeOccurrence, error)
+	// ResponsesAt returns the responses to the occurrence of an event at
+	// start.
+	ResponsesAt(eventID uint, start time.Time) ([]models.EventAttendeeOccurrence, error)
+	// SaveResponse creates or replaces the response of a user to an
+	// occurrence.
+	SaveResponse(response *models.EventAttendeeOccurrence) error
+	// ReplaceResponses replaces all the responses to occurrences of an
+	// event.
+	ReplaceResponses(eventID uint, responses []models.EventAttendeeOccurrence) error
+	// DeleteUserResponses deletes the responses of a user to the occurrences
+	// of an event.
+	DeleteUserResponses(eventID, userID uint) error
+	// Delete deletes the overrides and responses of the occurrence of an
+	// event at start, or of all its occurrences from start on when
+	// following is set.
+	Delete(eventID uint, start time.Time, following bool) error
+	// MoveFollowing moves the overrides and responses of the occurrences of
+	// an event from start on to another event.
+	MoveFollowing(fromEventID, toEventID uint, start time.Time) error
+}
+
+// gormOccurrenceRepository implements OccurrenceRepository with GORM.
+type gormOccurrenceRepository struct {
+	db *gorm.DB
+}
+
+// NewOccurrenceRepository returns an OccurrenceRepository backed by db.
+func NewOccurrenceRepository(db *gorm.DB) OccurrenceRepository {
+	return gormOccurrenceRepository{db: db}
+}
+
+func (r gormOccurrenceRepository) Overrides(eventIDs []uint) ([]models.EventOccurrence, error) {
+	var overrides []models.EventOccurrence
+	err := r.db.Where("event_id IN ?", eventIDs).Find(&overrides).Error
+	return overrides, err
+}
+
+func (r gormOccurrenceRepository) FindOverride(eventID uint, start time.Time) (models.EventOccurrence, error) {
+	var override models.EventOccurrence
+	err := r.db.Where("event_id = ? AND occurrence_start = ?", eventID, start).First(&override).Error
+	return override, notFound(err)
+}
+
+func (r gormOccurrenceRepository) SaveOverride(override *models.EventOccurrence) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(override).Error
+}
+
+func (r gormOccurrenceRepository) ReplaceOverrides(eventID uint, overrides []models.EventOccurrence) error {
+	if err := r.db.Where("event_id = ?", eventID).Delete(&models.EventOccurrence{}).Error; err != nil {
+		return err
+	}
+	if len(overrides) == 0 {
+		return nil
+	}
+	return r.db.Create(&overrides).Error
+}
+
+func (r gormOccurrenceRepository) Responses(eventIDs []uint, userID uint) ([]models.EventAttendeeOccurrence, error) {
+	query := r.db.Where("event_id IN ?", eventIDs)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var responses []models.EventAttendeeOccurrence
+	err := query.Find(&responses).Error
+	return responses, err
+}
+
+func (r gormOccurrenceRepository) ResponsesAt(eventID uint, start time.Time) ([]models.EventAttendeeOccurrence, error) {
+	var responses []models.EventAttendeeOccurrence
+	err := r.db.Where("event_id = ? AND occurrence_start = ?", eventID, start).Find(&responses).Error
+	return responses, err
+}
+
+func (r gormOccurrenceRepository) SaveResponse(response *models.EventAttendeeOccurrence) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(response).Error
+}
+
+func (r gormOccurrenceRepository) ReplaceResponses(eventID uint, responses []models.EventAttendeeOccurrence) error {
+	if err := r.db.Where("event_id = ?", eventID).Delete(&models.EventAttendeeOccurrence{}).Error; err != nil {
+		return err
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return r.db.Create(&responses).Error
+}
+
+func (r gormOccurrenceRepository) DeleteUserResponses(eventID, userID uint) error {
+	return r.db.Where("event_id = ? AND user_id = ?", eventID, userID).Delete(&models.EventAttendeeOccurrence{}).Error
+}
+
+func (r gormOccurrenceRepository) Delete(eventID uint, start time.Time, following bool) error {
+	condition := "event_id = ? AND occurrence_start = ?"
+	if following {
+		condition = "event_id = ? AND occurrence_start >= ?"
+	}
+	if err := r.db.Where(condition, eventID, start).Delete(&models.EventAttendeeOccurrence{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where(condition, eventID, start).Delete(&models.EventOccurrence{}).Error
+}
+
+func (r gormOccurrenceRepository) MoveFollowing(fromEventID, toEventID uint, start time.Time) error {
+	for _, model := range []interface{}{&models.EventOccurrence{}, &models.EventAttendeeOccurrence{}} {
+		if err := r.db.Model(model).Where("event_id = ? AND occurrence_start >= ?", fromEventID, start).
+			Update("event_id", toEventID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
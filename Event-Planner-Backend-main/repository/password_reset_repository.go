@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// PasswordResetRepository reads and writes password reset tokens.
+type PasswordResetRepository interface {
+	// Create inserts a reset token.
+	Create(reset *models.PasswordResetToken) error
+	// FindByHashForUpdate returns and locks the reset token with the given
+	// hash, or ErrNotFound.
+	FindByHashForUpdate(tokenHash string) (models.PasswordResetToken, error)
+	// UseAll uses up the reset tokens of a user not used yet.
+	UseAll(userID uint, at time.Time) error
+}
+
+// gormPasswordResetRepository implements PasswordResetRepository with GORM.
+type gormPasswordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository returns a PasswordResetRepository backed by db.
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return gormPasswordResetRepository{db: db}
+}
+
+func (r gormPasswordResetRepository) Create(reset *models.PasswordResetToken) error {
+	return r.db.Create(reset).Error
+}
+
+func (r gormPasswordResetRepository) FindByHashForUpdate(tokenHash string) (models.PasswordResetToken, error) {
+	var reset models.PasswordResetToken
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).First(&reset).Error
+	return reset, notFound(err)
+}
+
+func (r gormPasswordResetRepository) UseAll(userID uint, at time.Time) error {
+	return r.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// RecoveryCodeRepository reads and writes the two-factor recovery codes of
+// users.
+type RecoveryCodeRepository interface {
+	// Replace replaces the codes of a user with the given ones.
+	Replace(userID uint, codes []models.RecoveryCode) error
+	// DeleteAll deletes the codes of a user.
+	DeleteAll(userID uint) error
+	// Use uses up the unused code of a user with the given hash, and reports
+	// whether there was one.
+	Use(userID uint, codeHash string, at time.Time) (bool, error)
+	// CountUnused counts the codes of a user not used yet.
+	CountUnused(userID uint) (int64, error)
+}
+
+// gormRecoveryCodeRepository implements RecoveryCodeRepository with GORM.
+type gormRecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository returns a RecoveryCodeRepository backed by db.
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return gormRecoveryCodeRepository{db: db}
+}
+
+func (r gormRecoveryCodeRepository) Replace(userID uint, codes []models.RecoveryCode) error {
+	if err := r.DeleteAll(userID); err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return r.db.Create(&codes).Error
+}
+
+func (r gormRecoveryCodeRepository) DeleteAll(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+func (r gormRecoveryCodeRepository) Use(userID uint, codeHash string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r gormRecoveryCodeRepository) CountUnused(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// SavedSearchRepository reads and writes the saved searches of users and the
+// matches recorded for their notifications.
+type SavedSearchRepository interface {
+	// Find returns a saved search of a user, or ErrNotFound.
+	Find(id, userID uint) (models.SavedSearch, error)
+	// FindByID returns the saved search with the given ID, or ErrNotFound.
+	FindByID(id uint) (models.SavedSearch, error)
+	// FindByIDForUpdate is FindByID locking the saved search row until the
+	// end of the transaction.
+	FindByIDForUpdate(id uint) (models.SavedSearch, error)
+	// List returns the saved searches of a user by name.
+	List(userID uint) ([]models.SavedSearch, error)
+	// Count counts the saved searches of a user.
+	Count(userID uint) (int64, error)
+	// NameTaken reports whether a user has a saved search named name, other
+	// than exceptID.
+	NameTaken(userID uint, name string, exceptID uint) (bool, error)
+	// Create inserts a saved search and sets its ID.
+	Create(search *models.SavedSearch) error
+	// Update sets the given columns of a saved search.
+	Update(id uint, updates map[string]interface{}) error
+	// Delete deletes a saved search and its matches.
+	Delete(id uint) error
+	// Matches returns the matches recorded for a saved search: the events and
+	// tasks that matched it at any check since it last changed.
+	Matches(id uint) ([]models.SavedSearchMatch, error)
+	// ClearMatches deletes the matches recorded for a saved search.
+	ClearMatches(id uint) error
+	// AddMatches records matches.
+	AddMatches(matches []models.SavedSearchMatch) error
+	// ListDue returns up to limit saved searches with notifications that were
+	// not checked since since, least recently checked first.
+	ListDue(since time.Time, limit int) ([]models.SavedSearch, error)
+	// ClaimCheck sets the check time of a saved search to at, if it was still
+	// checkedAt, and reports whether it did.
+	ClaimCheck(id uint, checkedAt *time.Time, at time.Time) (bool, error)
+	// ReleaseCheck sets the check time of a saved search back to checkedAt,
+	// if it is still the time at of a failed claim.
+	ReleaseCheck(id uint, at time.Time, checkedAt *time.Time) error
+	// SetCheckedAt sets the check time of a saved search.
+	SetCheckedAt(id uint, at time.Time) error
+}
+
+// gormSavedSearchRepository implements SavedSearchRepository with GORM.
+type gormSavedSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchRepository returns a SavedSearchRepository backed by db.
+func NewSavedSearchRepository(db *gorm.DB) SavedSearchRepository {
+	return gormSavedSearchRepository{db: db}
+}
+
+func (r gormSavedSearchRepository) Find(id, userID uint) (models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.db.Where("saved_search_id = ? AND user_id = ?", id, userID).First(&search).Error
+	return search, notFound(err)
+}
+
+func (r gormSavedSearchRepository) FindByID(id uint) (models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.db.Where("saved_search_id = ?", id).First(&search).Error
+	return search, notFound(err)
+}
+
+func (r gormSavedSearchRepository) FindByIDForUpdate(id uint) (models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("saved_search_id = ?", id).First(&search).Error
+	return search, notFound(err)
+}
+
+func (r gormSavedSearchRepository) List(userID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&searches).Error
+	return searches, err
+}
+
+func (r gormSavedSearchRepository) Count(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.SavedSearch{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r gormSavedSearchRepository) NameTaken(userID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SavedSearch{}).
+		Where("user_id = ? AND name = ? AND saved_search_id <> ?", userID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r gormSavedSearchRepository) Create(search *models.SavedSearch) error {
+	return r.db.Create(search).Error
+}
+
+func (r gormSavedSearchRepository) Update(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&models.SavedSearch{}).Where("saved_search_id = ?", id).Updates(updates).Error
+}
+
+func (r gormSavedSearchRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("saved_search_id = ?", id).Delete(&models.SavedSearchMatch{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("saved_search_id = ?", id).Delete(&models.SavedSearch{}).Error
+	})
+}
+
+func (r gormSavedSearchRepository) Matches(id uint) ([]models.SavedSearchMatch, error) {
+	var matches []models.SavedSearchMatch
+	err := r.db.Where("saved_search_id = ?", id).Find(&matches).Error
+	return matches, err
+}
+
+func (r gormSavedSearchRepository) ClearMatches(id uint) error {
+	return r.db.Where("saved_search_id = ?", id).Delete(&models.SavedSearchMatch{}).Error
+}
+
+func (r gormSavedSearchRepository) AddMatches(matches []models.SavedSearchMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(&matches, 500).Error
+}
+
+func (r gormSavedSearchRepository) ListDue(since time.Time, limit int) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.db.Where("notify = ? AND (checked_at IS NULL OR checked_at <= ?)", true, since).
+		Order("checked_at ASC").
+		Limit(limit).
+		Find(&searches).Error
+	return searches, err
+}
+
+func (r gormSavedSearchRepository) ClaimCheck(id uint, checkedAt *time.Time, at time.Time) (bool, error) {
+	result := r.db.Model(&models.SavedSearch{}).
+		Where("saved_search_id = ? AND checked_at <=> ?", id, checkedAt).
+		UpdateColumn("checked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r gormSavedSearchRepository) ReleaseCheck(id uint, at time.Time, checkedAt *time.Time) error {
+	return r.db.Model(&models.SavedSearch{}).
+		Where("saved_search_id = ? AND checked_at = ?", id, at).
+		UpdateColumn("checked_at", checkedAt).Error
+}
+
+func (r gormSavedSearchRepository) SetCheckedAt(id uint, at time.Time) error {
+	return r.db.Model(&models.SavedSearch{}).Where("saved_search_id = ?", id).
+		UpdateColumn("checked_at", at).Error
+}
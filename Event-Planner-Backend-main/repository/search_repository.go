@@ -0,0 +1,516 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// MySQL errors meaning that full-text search is not available on a table.
+const (
+	mysqlErrFullTextIndexMissing = 1191 // ER_FT_MATCHING_KEY_NOT_FOUND
+	mysqlErrFullTextUnsupported  = 1214 // ER_TABLE_CANT_HANDLE_FT
+)
+
+// Kinds of the values of sort keys, which pages return as text.
+const (
+	sortKindTime   = "time"
+	sortKindFloat  = "float"
+	sortKindInt    = "int"
+	sortKindString = "string"
+)
+
+// relevanceDigits is the number of decimals full-text relevance is rounded
+// to, to rank and page results.
+const relevanceDigits = 6
+
+// Default orders of events and tasks. SortRelevance ranks results by
+// full-text relevance; it is the default with a keyword, and falls back to
+// the default order without one.
+const (
+	DefaultEventSort = "-createdAt"
+	DefaultTaskSort  = "dueDate"
+	SortRelevance    = "relevance"
+)
+
+var (
+	// ErrFullTextUnsupported is returned by searches matching a keyword with
+	// the FULLTEXT indexes when the database has none; they can be run again
+	// with LIKE.
+	ErrFullTextUnsupported = errors.New("full-text search is not available")
+	// ErrInvalidCursor is returned when the values a page starts after do
+	// not fit its order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchFilters restrict a search. Event filters apply to the events of
+// tasks too; events must have all the tags.
+type SearchFilters struct {
+	Role           string
+	From, To       *time.Time
+	Statuses       []string
+	TaskStatuses   []string
+	AssigneeID     *uint
+	Unassigned     bool
+	DueBefore      string // YYYY-MM-DD
+	Location       string
+	OrganizerID    *uint
+	OrganizerEmail string
+	Tags           []string
+}
+
+// SearchQuery selects a page of the events, or of the tasks of the events,
+// a user is part of.
+type SearchQuery struct {
+	UserID  uint
+	Filters SearchFilters
+	// Keyword is matched with the FULLTEXT indexes, using the boolean syntax
+	// with Boolean. With Like, any of Terms is matched with LIKE instead.
+	Keyword string
+	Boolean bool
+	Like    bool
+	Terms   []string
+	Sort    string   // An order of EventSorts or TaskSorts, or SortRelevance
+	After   []string // Sort key values of the last result of the previous page
+	Limit   int
+}
+
+// SearchHit is a match of a search, with its relevance when ranked by a
+// full-text index.
+type SearchHit struct {
+	ID        uint
+	Relevance *float64
+}
+
+// SearchPage is a page of search results.
+type SearchPage struct {
+	Hits  []SearchHit
+	Total int64
+	Sort  string   // The order used
+	Next  []string // Sort key values of the last hit, when more follow
+}
+
+// TagCount is a tag and the number of events carrying it.
+type TagCount struct {
+	Tag   string `gorm:"column:tag" json:"tag"`
+	Count int64  `gorm:"column:count" json:"count"`
+}
+
+// SearchRepository finds the events and tasks matching a search.
+type SearchRepository interface {
+	// Events returns a page of the events matching q.
+	Events(q SearchQuery) (SearchPage, error)
+	// Tasks returns a page of the tasks matching q.
+	Tasks(q SearchQuery) (SearchPage, error)
+	// EventTags counts the tags of all the events matching q, most used
+	// first, up to limit.
+	EventTags(q SearchQuery, limit int) ([]TagCount, error)
+}
+
+// sortKey is one column of a search order.
+type sortKey struct {
+	expr string
+	args []interface{}
+	kind string
+	desc bool
+}
+
+// eventSorts are the orders of event results. The event ID breaks ties.
+var eventSorts = map[string][]sortKey{
+	"startsAt":   {{expr: "events.starts_at", kind: sortKindTime}},
+	"-startsAt":  {{expr: "events.starts_at", kind: sortKindTime, desc: true}},
+	"createdAt":  {{expr: "events.created_at", kind: sortKindTime}},
+	"-createdAt": {{expr: "events.created_at", kind: sortKindTime, desc: true}},
+	"title":      {{expr: "events.title", kind: sortKindString}},
+	"-title":     {{expr: "events.title", kind: sortKindString, desc: true}},
+}
+
+// taskSorts are the orders of task results; tasks without due date come last.
+// The task ID breaks ties.
+var taskSorts = map[string][]sortKey{
+	"dueDate": {
+		{expr: "COALESCE(tasks.due_date, CAST('9999-12-31' AS DATE))", kind: sortKindTime},
+		{expr: "tasks.created_at", kind: sortKindTime, desc: true},
+	},
+	"-dueDate": {
+		{expr: "COALESCE(tasks.due_date, CAST('1000-01-01' AS DATE))", kind: sortKindTime, desc: true},
+		{expr: "tasks.created_at", kind: sortKindTime, desc: true},
+	},
+	"createdAt":  {{expr: "tasks.created_at", kind: sortKindTime}},
+	"-createdAt": {{expr: "tasks.created_at", kind: sortKindTime, desc: true}},
+}
+
+// EventSorts returns the names of the orders of events, alphabetically.
+func EventSorts() []string { return sortNames(eventSorts) }
+
+// TaskSorts returns the names of the orders of tasks, alphabetically.
+func TaskSorts() []string { return sortNames(taskSorts) }
+
+// sortNames returns the names of sorts, alphabetically.
+func sortNames(sorts map[string][]sortKey) []string {
+	names := make([]string, 0, len(sorts))
+	for name := range sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// searchList describes one kind of search results.
+type searchList struct {
+	base        func() *gorm.DB // Filtered rows visible to the user
+	idColumn    string
+	columns     []string // Columns matched by keywords, covered by a FULLTEXT index
+	sorts       map[string][]sortKey
+	defaultSort string
+}
+
+// gormSearchRepository implements SearchRepository with GORM.
+type gormSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository returns a SearchRepository backed by db.
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return gormSearchRepository{db: db}
+}
+
+// eventList returns the events the user of q is part of, filtered.
+func (r gormSearchRepository) eventList(q SearchQuery) searchList {
+	return searchList{
+		base: func() *gorm.DB {
+			query := r.db.Model(&models.Event{}).
+				Joins("INNER JOIN event_attendees ON events.event_id = event_attendees.event_id").
+				Where("event_attendees.user_id = ?", q.UserID)
+			return applyEventFilters(query, q.Filters)
+		},
+		idColumn:    "events.event_id",
+		columns:     []string{"events.title", "events.description"},
+		sorts:       eventSorts,
+		defaultSort: DefaultEventSort,
+	}
+}
+
+// taskList returns the tasks of the events the user of q is part of,
+// filtered.
+func (r gormSearchRepository) taskList(q SearchQuery) searchList {
+	return searchList{
+		base: func() *gorm.DB {
+			query := r.db.Model(&models.Task{}).
+				Joins("INNER JOIN events ON tasks.event_id = events.event_id").
+				Joins("INNER JOIN event_attendees ON events.event_id = event_attendees.event_id").
+				Where("event_attendees.user_id = ?", q.UserID)
+			return applyTaskFilters(applyEventFilters(query, q.Filters), q.Filters)
+		},
+		idColumn:    "tasks.task_id",
+		columns:     []string{"tasks.description"},
+		sorts:       taskSorts,
+		defaultSort: DefaultTaskSort,
+	}
+}
+
+func (r gormSearchRepository) Events(q SearchQuery) (SearchPage, error) {
+	return findSearchPage(r.eventList(q), q)
+}
+
+func (r gormSearchRepository) Tasks(q SearchQuery) (SearchPage, error) {
+	return findSearchPage(r.taskList(q), q)
+}
+
+func (r gormSearchRepository) EventTags(q SearchQuery, limit int) ([]TagCount, error) {
+	list := r.eventList(q)
+	match, matchArgs, _ := list.match(q)
+	matches := list.base()
+	if match != "" {
+		matches = matches.Where(match, matchArgs...)
+	}
+	facets := []TagCount{}
+	err := r.db.Model(&models.EventTag{}).
+		Select("keyword AS tag, COUNT(*) AS count").
+		Where("event_id IN (?)", matches.Select(list.idColumn)).
+		Group("keyword").
+		Order("count DESC, tag ASC").
+		Limit(limit).
+		Scan(&facets).Error
+	return facets, fullText(err)
+}
+
+// fullText wraps the errors meaning that the table has no usable FULLTEXT
+// index in ErrFullTextUnsupported.
+func fullText(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) &&
+		(mysqlErr.Number == mysqlErrFullTextIndexMissing || mysqlErr.Number == mysqlErrFullTextUnsupported) {
+		return fmt.Errorf("%w: %v", ErrFullTextUnsupported, err)
+	}
+	return err
+}
+
+// applyEventFilters restricts query, which joins events and the
+// event_attendees row of the user, to the events matching the event filters.
+// Recurring events are kept when their series starts before To.
+func applyEventFilters(query *gorm.DB, f SearchFilters) *gorm.DB {
+	if f.Role != "" {
+		query = query.Where("event_attendees.role = ?", f.Role)
+	}
+	if len(f.Statuses) > 0 {
+		query = query.Where("event_attendees.status IN ?", f.Statuses)
+	}
+	if f.From != nil {
+		query = query.Where("(events.ends_at > ? OR events.rrule <> '' OR COALESCE(events.rdates, '') <> '')", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("events.starts_at < ?", *f.To)
+	}
+	if f.Location != "" {
+		query = query.Where("LOWER(events.location) LIKE ?", "%"+likeEscaper.Replace(strings.ToLower(f.Location))+"%")
+	}
+	if f.OrganizerID != nil {
+		query = query.Where("events.created_by = ?", *f.OrganizerID)
+	}
+	if f.OrganizerEmail != "" {
+		query = query.Where("events.created_by IN (SELECT user_id FROM users WHERE LOWER(email) = ?)", f.OrganizerEmail)
+	}
+	if len(f.Tags) > 0 {
+		query = query.Where("events.event_id IN (SELECT event_id FROM event_keywords WHERE keyword IN ? GROUP BY event_id HAVING COUNT(*) = ?)",
+			f.Tags, len(f.Tags))
+	}
+	return query
+}
+
+// applyTaskFilters restricts query, which joins tasks, to the tasks matching
+// the task filters.
+func applyTaskFilters(query *gorm.DB, f SearchFilters) *gorm.DB {
+	if len(f.TaskStatuses) > 0 {
+		query = query.Where("tasks.status IN ?", f.TaskStatuses)
+	}
+	if f.AssigneeID != nil {
+		query = query.Where("tasks.assigned_to = ?", *f.AssigneeID)
+	}
+	if f.Unassigned {
+		query = query.Where("tasks.assigned_to IS NULL")
+	}
+	if f.DueBefore != "" {
+		query = query.Where("tasks.due_date < ?", f.DueBefore)
+	}
+	return query
+}
+
+// newHolder returns a pointer to scan a value of the key into.
+func (k sortKey) newHolder() interface{} {
+	switch k.kind {
+	case sortKindTime:
+		return new(time.Time)
+	case sortKindFloat:
+		return new(float64)
+	case sortKindInt:
+		return new(int64)
+	}
+	return new(string)
+}
+
+// formatSortValue returns the text of a scanned sort key value.
+func formatSortValue(holder interface{}) string {
+	switch v := holder.(type) {
+	case *time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *float64:
+		return strconv.FormatFloat(*v, 'g', -1, 64)
+	case *int64:
+		return strconv.FormatInt(*v, 10)
+	case *string:
+		return *v
+	}
+	return ""
+}
+
+// parseValue parses a value of the key formatted by formatSortValue.
+func (k sortKey) parseValue(value string) (interface{}, error) {
+	switch k.kind {
+	case sortKindTime:
+		return time.Parse(time.RFC3339Nano, value)
+	case sortKindFloat:
+		return strconv.ParseFloat(value, 64)
+	case sortKindInt:
+		return strconv.ParseInt(value, 10, 64)
+	}
+	return value, nil
+}
+
+// keysetCondition returns the condition selecting the rows ordered after the
+// row whose sort key values are values.
+func keysetCondition(keys []sortKey, values []string) (string, []interface{}, error) {
+	parsed := make([]interface{}, len(keys))
+	for i, key := range keys {
+		v, err := key.parseValue(values[i])
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		parsed[i] = v
+	}
+
+	var alternatives []string
+	var args []interface{}
+	for i, key := range keys {
+		var conditions []string
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, keys[j].expr+" = ?")
+			args = append(append(args, keys[j].args...), parsed[j])
+		}
+		op := " > ?"
+		if key.desc {
+			op = " < ?"
+		}
+		conditions = append(conditions, key.expr+op)
+		args = append(append(args, key.args...), parsed[i])
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// match returns the condition matching the keyword of q, and the relevance
+// key when it is ranked by a FULLTEXT index.
+func (list searchList) match(q SearchQuery) (string, []interface{}, *sortKey) {
+	switch {
+	case q.Keyword == "":
+		return "", nil, nil
+	case q.Like:
+		var conditions []string
+		var args []interface{}
+		for _, term := range q.Terms {
+			pattern := "%" + likeEscaper.Replace(strings.ToLower(strings.TrimRight(term, "*"))) + "%"
+			for _, column := range list.columns {
+				conditions = append(conditions, "LOWER("+column+") LIKE ?")
+				args = append(args, pattern)
+			}
+		}
+		if len(conditions) == 0 {
+			conditions = []string{"FALSE"}
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+	}
+	against := "IN NATURAL LANGUAGE MODE"
+	if q.Boolean {
+		against = "IN BOOLEAN MODE"
+	}
+	match := "MATCH(" + strings.Join(list.columns, ", ") + ") AGAINST (? " + against + ")"
+	args := []interface{}{q.Keyword}
+	// Rounded, so that the score in a cursor compares equal to the one of the
+	// row it was read from, and ties are paged by the next keys
+	relevance := "ROUND(" + match + ", " + strconv.Itoa(relevanceDigits) + ")"
+	return match, args, &sortKey{expr: relevance, args: args, kind: sortKindFloat, desc: true}
+}
+
+// findSearchPage returns the page of the results of list selected by q.
+func findSearchPage(list searchList, q SearchQuery) (SearchPage, error) {
+	var page SearchPage
+	match, matchArgs, relevance := list.match(q)
+
+	// Resolve the order; the ID always comes last so that it is total
+	sortName := q.Sort
+	if sortName == "" || (sortName == SortRelevance && relevance == nil) {
+		sortName = list.defaultSort
+		if relevance != nil {
+			sortName = SortRelevance
+		}
+	}
+	var keys []sortKey
+	if sortName == SortRelevance {
+		keys = append(keys, *relevance)
+		keys = append(keys, list.sorts[list.defaultSort]...)
+	} else {
+		keys = append(keys, list.sorts[sortName]...)
+	}
+	if len(keys) == 0 {
+		return page, fmt.Errorf("unknown sort %q", sortName)
+	}
+	keys = append(keys, sortKey{expr: list.idColumn, kind: sortKindInt, desc: keys[len(keys)-1].desc})
+	page.Sort = sortName
+
+	filtered := func() *gorm.DB {
+		query := list.base()
+		if match != "" {
+			query = query.Where(match, matchArgs...)
+		}
+		return query
+	}
+	if err := filtered().Select("COUNT(DISTINCT " + list.idColumn + ")").Scan(&page.Total).Error; err != nil {
+		return page, fullText(err)
+	}
+
+	query := filtered()
+	if q.After != nil {
+		if len(q.After) != len(keys) {
+			return page, ErrInvalidCursor
+		}
+		condition, args, err := keysetCondition(keys, q.After)
+		if err != nil {
+			return page, err
+		}
+		query = query.Where(condition, args...)
+	}
+
+	columns := []string{list.idColumn + " AS id", "NULL AS relevance"}
+	var args []interface{}
+	if relevance != nil {
+		columns[1] = relevance.expr + " AS relevance"
+		args = append(args, relevance.args...)
+	}
+	orders := make([]string, len(keys))
+	for i, key := range keys {
+		alias := "k" + strconv.Itoa(i)
+		columns = append(columns, key.expr+" AS "+alias)
+		args = append(args, key.args...)
+		orders[i] = alias + " ASC"
+		if key.desc {
+			orders[i] = alias + " DESC"
+		}
+	}
+	rows, err := query.Select(strings.Join(columns, ", "), args...).
+		Group(list.idColumn).
+		Order(strings.Join(orders, ", ")).
+		Limit(q.Limit + 1).
+		Rows()
+	if err != nil {
+		return page, fullText(err)
+	}
+	defer rows.Close()
+
+	var last []string
+	for rows.Next() {
+		var hit SearchHit
+		var score sql.NullFloat64
+		holders := make([]interface{}, len(keys))
+		for i, key := range keys {
+			holders[i] = key.newHolder()
+		}
+		if err := rows.Scan(append([]interface{}{&hit.ID, &score}, holders...)...); err != nil {
+			return page, err
+		}
+		if len(page.Hits) == q.Limit {
+			page.Next = last
+			break
+		}
+		if score.Valid {
+			hit.Relevance = &score.Float64
+		}
+		page.Hits = append(page.Hits, hit)
+		last = make([]string, len(holders))
+		for i, holder := range holders {
+			last[i] = formatSortValue(holder)
+		}
+	}
+	return page, rows.Err()
+}
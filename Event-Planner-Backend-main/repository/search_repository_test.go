@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRelevanceKeysetTiedScores(t *testing.T) {
+	list := searchList{idColumn: "events.event_id", columns: []string{"events.title", "events.description"}}
+	match, _, relevance := list.match(SearchQuery{Keyword: "launch", Terms: []string{"launch"}})
+	if relevance == nil {
+		t.Fatal("no relevance key")
+	}
+	rounded := "ROUND(" + match + ", 6)"
+	if relevance.expr != rounded {
+		t.Fatalf("relevance = %s, want %s", relevance.expr, rounded)
+	}
+	keys := []sortKey{*relevance, {expr: list.idColumn, kind: sortKindInt, desc: true}}
+
+	// Two rows tie on the rounded score; the page after the first selects
+	// the second through the ID
+	score := 0.453218
+	cursor := []string{formatSortValue(&score), "7"}
+	condition, args, err := keysetCondition(keys, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "((" + rounded + " < ?) OR (" + rounded + " = ? AND events.event_id < ?))"
+	if condition != want {
+		t.Errorf("condition = %s, want %s", condition, want)
+	}
+	if len(args) != 5 || args[1] != score || args[3] != score || args[4] != int64(7) {
+		t.Errorf("args = %v, want the score of the cursor in both comparisons", args)
+	}
+	if strings.Count(condition, "?") != len(args) {
+		t.Errorf("%d placeholders for %d args", strings.Count(condition, "?"), len(args))
+	}
+}
+
+func TestKeysetConditionInvalidCursor(t *testing.T) {
+	keys := []sortKey{{expr: "ROUND(score, 6)", kind: sortKindFloat, desc: true}, {expr: "id", kind: sortKindInt}}
+	for _, values := range [][]string{{"high", "1"}, {"0.5", "one"}} {
+		if _, _, err := keysetCondition(keys, values); err != ErrInvalidCursor {
+			t.Errorf("values %q: err = %v, want ErrInvalidCursor", values, err)
+		}
+	}
+}
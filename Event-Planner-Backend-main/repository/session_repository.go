@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"event_planner_backend/models"
+)
+
+// SessionRepository reads and writes the refresh tokens of logins and the
+// denylist of revoked access tokens.
+type SessionRepository interface {
+	// Create inserts a refresh token.
+	Create(token *models.RefreshToken) error
+	// FindByHashForUpdate returns and locks the refresh token with the given
+	// hash, or ErrNotFound.
+	FindByHashForUpdate(tokenHash string) (models.RefreshToken, error)
+	// FindByHash returns the refresh token of a user with the given hash, or
+	// ErrNotFound.
+	FindByHash(tokenHash string, userID uint) (models.RefreshToken, error)
+	// MarkUsed records when a refresh token was exchanged for a new one.
+	MarkUsed(tokenID uint, at time.Time) error
+	// FamiliesOfAccessToken returns the families of the refresh tokens of a
+	// user issued with the given access token.
+	FamiliesOfAccessToken(jti string, userID uint) ([]string, error)
+	// RevokeUser revokes the refresh tokens of a user and denylists the
+	// unexpired access tokens issued with them.
+	RevokeUser(userID uint, at time.Time) error
+	// RevokeFamilies is RevokeUser for the refresh tokens of the given
+	// families.
+	RevokeFamilies(familyIDs []string, at time.Time) error
+	// RevokeAccessToken denylists an access token until it expires.
+	RevokeAccessToken(jti string, userID uint, expiresAt, at time.Time) error
+	// AccessTokenRevoked reports whether an access token is denylisted.
+	AccessTokenRevoked(jti string) (bool, error)
+	// DeleteExpiredRevoked forgets the denylisted access tokens expired at
+	// now.
+	DeleteExpiredRevoked(now time.Time) error
+}
+
+// gormSessionRepository implements SessionRepository with GORM.
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository returns a SessionRepository backed by db.
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return gormSessionRepository{db: db}
+}
+
+func (r gormSessionRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r gormSessionRepository) FindByHashForUpdate(tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).First(&token).Error
+	return token, notFound(err)
+}
+
+func (r gormSessionRepository) FindByHash(tokenHash string, userID uint) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ? AND user_id = ?", tokenHash, userID).First(&token).Error
+	return token, notFound(err)
+}
+
+func (r gormSessionRepository) MarkUsed(tokenID uint, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).Where("token_id = ?", tokenID).Update("used_at", at).Error
+}
+
+func (r gormSessionRepository) FamiliesOfAccessToken(jti string, userID uint) ([]string, error) {
+	var families []string
+	err := r.db.Model(&models.RefreshToken{}).Where("access_jti = ? AND user_id = ?", jti, userID).
+		Pluck("family_id", &families).Error
+	return families, err
+}
+
+func (r gormSessionRepository) RevokeUser(userID uint, at time.Time) error {
+	return r.revoke(at, "user_id = ?", userID)
+}
+
+func (r gormSessionRepository) RevokeFamilies(familyIDs []string, at time.Time) error {
+	if len(familyIDs) == 0 {
+		return nil
+	}
+	return r.revoke(at, "family_id IN ?", familyIDs)
+}
+
+// revoke revokes the refresh tokens matching the condition and denylists the
+// unexpired access tokens issued with them.
+func (r gormSessionRepository) revoke(at time.Time, query string, args ...interface{}) error {
+	var tokens []models.RefreshToken
+	if err := r.db.Where(query, args...).Where("access_expires_at > ?", at).
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := r.RevokeAccessToken(t.AccessJTI, t.UserID, t.AccessExpiresAt, at); err != nil {
+			return err
+		}
+	}
+	return r.db.Model(&models.RefreshToken{}).Where(query, args...).Where("revoked_at IS NULL").
+		Update("revoked_at", at).Error
+}
+
+func (r gormSessionRepository) RevokeAccessToken(jti string, userID uint, expiresAt, at time.Time) error {
+	revoked := models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt, RevokedAt: at}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+}
+
+func (r gormSessionRepository) AccessTokenRevoked(jti string) (bool, error) {
+	var revoked int64
+	err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&revoked).Error
+	return revoked > 0, err
+}
+
+func (r gormSessionRepository) DeleteExpiredRevoked(now time.Time) error {
+	return r.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,82 @@
+// Package repository holds the data access of the handlers behind
+// interfaces, so that they do not depend on the global database handle and
+// can be given other implementations.
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// Store gives access to the repositories.
+type Store interface {
+	Users() UserRepository
+	Events() EventRepository
+	Attendees() AttendeeRepository
+	Tasks() TaskRepository
+	Changes() ChangeRepository
+	Occurrences() OccurrenceRepository
+	Invitations() InvitationRepository
+	Transfers() TransferRepository
+	Notifications() NotificationRepository
+	CalendarFeeds() CalendarFeedRepository
+	Sessions() SessionRepository
+	AccessTokens() AccessTokenRepository
+	PasswordResets() PasswordResetRepository
+	RecoveryCodes() RecoveryCodeRepository
+	LoginThrottles() LoginThrottleRepository
+	Identities() IdentityRepository
+	SavedSearches() SavedSearchRepository
+	Search() SearchRepository
+
+	// Transaction runs fn with repositories bound to a single transaction,
+	// committed when fn returns nil and rolled back otherwise.
+	Transaction(fn func(Store) error) error
+}
+
+// gormStore implements Store with GORM.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns a Store backed by db.
+func NewGormStore(db *gorm.DB) Store {
+	return gormStore{db: db}
+}
+
+func (s gormStore) Users() UserRepository                   { return NewUserRepository(s.db) }
+func (s gormStore) Events() EventRepository                 { return NewEventRepository(s.db) }
+func (s gormStore) Attendees() AttendeeRepository           { return NewAttendeeRepository(s.db) }
+func (s gormStore) Tasks() TaskRepository                   { return NewTaskRepository(s.db) }
+func (s gormStore) Changes() ChangeRepository               { return NewChangeRepository(s.db) }
+func (s gormStore) Occurrences() OccurrenceRepository       { return NewOccurrenceRepository(s.db) }
+func (s gormStore) Invitations() InvitationRepository       { return NewInvitationRepository(s.db) }
+func (s gormStore) Transfers() TransferRepository           { return NewTransferRepository(s.db) }
+func (s gormStore) Notifications() NotificationRepository   { return NewNotificationRepository(s.db) }
+func (s gormStore) CalendarFeeds() CalendarFeedRepository   { return NewCalendarFeedRepository(s.db) }
+func (s gormStore) Sessions() SessionRepository             { return NewSessionRepository(s.db) }
+func (s gormStore) AccessTokens() AccessTokenRepository     { return NewAccessTokenRepository(s.db) }
+func (s gormStore) PasswordResets() PasswordResetRepository { return NewPasswordResetRepository(s.db) }
+func (s gormStore) RecoveryCodes() RecoveryCodeRepository   { return NewRecoveryCodeRepository(s.db) }
+func (s gormStore) LoginThrottles() LoginThrottleRepository { return NewLoginThrottleRepository(s.db) }
+func (s gormStore) Identities() IdentityRepository          { return NewIdentityRepository(s.db) }
+func (s gormStore) SavedSearches() SavedSearchRepository    { return NewSavedSearchRepository(s.db) }
+func (s gormStore) Search() SearchRepository                { return NewSearchRepository(s.db) }
+
+func (s gormStore) Transaction(fn func(Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(gormStore{db: tx})
+	})
+}
+
+// notFound translates the not found error of GORM into ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
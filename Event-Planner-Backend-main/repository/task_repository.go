@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// TaskRepository reads and writes the tasks of events. Tasks are returned
+// with their assignee and creator.
+type TaskRepository interface {
+	// ListByEvent returns the tasks of an event, optionally only those with
+	// the given status, by due date then newest first.
+	ListByEvent(eventID uint, status string) ([]models.Task, error)
+	// ListByIDs returns the tasks with the given IDs that exist, with their
+	// event and its organizer.
+	ListByIDs(ids []uint) ([]models.Task, error)
+	// FindInEvent returns a task of an event, or ErrNotFound.
+	FindInEvent(eventID, taskID uint) (models.Task, error)
+	// Create inserts a task and sets its ID.
+	Create(task *models.Task) error
+	// Update sets the given columns of a task.
+	Update(taskID uint, updates map[string]interface{}) error
+	// Delete deletes a task.
+	Delete(taskID uint) error
+	// UnassignUser unassigns the tasks of an event assigned to a user, and
+	// returns how many there were.
+	UnassignUser(eventID, userID uint) (int64, error)
+}
+
+// gormTaskRepository implements TaskRepository with GORM.
+type gormTaskRepository struct {
+	db *gorm.DB
+}
+
+// NewTaskRepository returns a TaskRepository backed by db.
+func NewTaskRepository(db *gorm.DB) TaskRepository {
+	return gormTaskRepository{db: db}
+}
+
+// withPeople preloads the assignee and creator of tasks.
+func (r gormTaskRepository) withPeople() *gorm.DB {
+	return r.db.Preload("Assignee").Preload("Creator")
+}
+
+func (r gormTaskRepository) ListByEvent(eventID uint, status string) ([]models.Task, error) {
+	query := r.withPeople().Where("event_id = ?", eventID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var tasks []models.Task
+	err := query.Order("due_date ASC, created_at DESC").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r gormTaskRepository) ListByIDs(ids []uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.withPeople().
+		Preload("Event", func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Organizer")
+		}).
+		Where("task_id IN ?", ids).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (r gormTaskRepository) FindInEvent(eventID, taskID uint) (models.Task, error) {
+	var task models.Task
+	err := r.withPeople().Where("task_id = ? AND event_id = ?", taskID, eventID).First(&task).Error
+	return task, notFound(err)
+}
+
+func (r gormTaskRepository) Create(task *models.Task) error {
+	return r.db.Create(task).Error
+}
+
+func (r gormTaskRepository) Update(taskID uint, updates map[string]interface{}) error {
+	return r.db.Model(&models.Task{}).Where("task_id = ?", taskID).Updates(updates).Error
+}
+
+func (r gormTaskRepository) Delete(taskID uint) error {
+	return r.db.Where("task_id = ?", taskID).Delete(&models.Task{}).Error
+}
+
+func (r gormTaskRepository) UnassignUser(eventID, userID uint) (int64, error) {
+	result := r.db.Model(&models.Task{}).
+		Where("event_id = ? AND assigned_to = ?", eventID, userID).
+		Update("assigned_to", nil)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// TransferRepository reads and writes the ownership transfers of events.
+type TransferRepository interface {
+	// Create inserts a transfer and sets its ID.
+	Create(transfer *models.EventOwnershipTransfer) error
+	// ListByEvent returns the transfers of an event with both users, newest
+	// first.
+	ListByEvent(eventID uint) ([]models.EventOwnershipTransfer, error)
+	// FindPending returns the pending transfer of an event, to nomineeID
+	// unless it is 0, or ErrNotFound.
+	FindPending(eventID, nomineeID uint) (models.EventOwnershipTransfer, error)
+	// Respond sets the status of a transfer and when it changed.
+	Respond(transferID uint, status string, at time.Time) error
+	// CancelPending cancels the pending transfer of an event, only if it
+	// nominates nomineeID unless that is 0.
+	CancelPending(eventID, nomineeID uint, at time.Time) error
+}
+
+// gormTransferRepository implements TransferRepository with GORM.
+type gormTransferRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferRepository returns a TransferRepository backed by db.
+func NewTransferRepository(db *gorm.DB) TransferRepository {
+	return gormTransferRepository{db: db}
+}
+
+func (r gormTransferRepository) Create(transfer *models.EventOwnershipTransfer) error {
+	return r.db.Create(transfer).Error
+}
+
+func (r gormTransferRepository) ListByEvent(eventID uint) ([]models.EventOwnershipTransfer, error) {
+	var transfers []models.EventOwnershipTransfer
+	err := r.db.Where("event_id = ?", eventID).
+		Preload("FromUser").
+		Preload("ToUser").
+		Order("requested_at DESC, transfer_id DESC").
+		Find(&transfers).Error
+	return transfers, err
+}
+
+func (r gormTransferRepository) FindPending(eventID, nomineeID uint) (models.EventOwnershipTransfer, error) {
+	var transfer models.EventOwnershipTransfer
+	query := r.db.Where("event_id = ? AND status = ?", eventID, "pending")
+	if nomineeID != 0 {
+		query = query.Where("to_user_id = ?", nomineeID)
+	}
+	err := query.First(&transfer).Error
+	return transfer, notFound(err)
+}
+
+func (r gormTransferRepository) Respond(transferID uint, status string, at time.Time) error {
+	return r.db.Model(&models.EventOwnershipTransfer{}).Where("transfer_id = ?", transferID).
+		Updates(map[string]interface{}{"status": status, "responded_at": at}).Error
+}
+
+func (r gormTransferRepository) CancelPending(eventID, nomineeID uint, at time.Time) error {
+	query := r.db.Model(&models.EventOwnershipTransfer{}).Where("event_id = ? AND status = ?", eventID, "pending")
+	if nomineeID != 0 {
+		query = query.Where("to_user_id = ?", nomineeID)
+	}
+	return query.Updates(map[string]interface{}{"status": "cancelled", "responded_at": at}).Error
+}
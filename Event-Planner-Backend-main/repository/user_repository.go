@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"event_planner_backend/models"
+)
+
+// UserRepository reads and writes users.
+type UserRepository interface {
+	// FindByID returns the user with the given ID, or ErrNotFound.
+	FindByID(id uint) (models.User, error)
+	// FindByEmail returns the user with the given (lowercased) email, or
+	// ErrNotFound.
+	FindByEmail(email string) (models.User, error)
+	// FindByIDs returns the users with the given IDs that exist.
+	FindByIDs(ids []uint) ([]models.User, error)
+	// Create inserts a user and sets its ID.
+	Create(user *models.User) error
+	// Update sets the given columns of a user.
+	Update(userID uint, updates map[string]interface{}) error
+	// UseTOTPStep records the time step of an authenticator code of a user,
+	// and reports false when that step or a later one was already used.
+	UseTOTPStep(userID uint, step int64) (bool, error)
+	// ClaimVerificationEmail records that a verification email is sent at now
+	// to an unverified user, unless the last one was sent after since, and
+	// reports whether it did.
+	ClaimVerificationEmail(userID uint, now, since time.Time) (bool, error)
+}
+
+// gormUserRepository implements UserRepository with GORM.
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository returns a UserRepository backed by db.
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return gormUserRepository{db: db}
+}
+
+func (r gormUserRepository) FindByID(id uint) (models.User, error) {
+	var user models.User
+	err := r.db.Where("user_id = ?", id).First(&user).Error
+	return user, notFound(err)
+}
+
+func (r gormUserRepository) FindByEmail(email string) (models.User, error) {
+	var user models.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	return user, notFound(err)
+}
+
+func (r gormUserRepository) FindByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("user_id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (r gormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r gormUserRepository) Update(userID uint, updates map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("user_id = ?", userID).Updates(updates).Error
+}
+
+func (r gormUserRepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("user_id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r gormUserRepository) ClaimVerificationEmail(userID uint, now, since time.Time) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("user_id = ? AND email_verified_at IS NULL", userID).
+		Where("verification_sent_at IS NULL OR verification_sent_at <= ?", since).
+		Update("verification_sent_at", now)
+	return result.RowsAffected == 1, result.Error
+}
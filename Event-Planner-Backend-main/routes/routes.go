@@ -11,15 +11,16 @@ import (
 	"event_planner_backend/middleware"
 )
 
-// SetupRouter configures routes and middleware.
-func SetupRouter() *gin.Engine {
+// SetupRouter configures routes and middleware around the handlers of svc.
+func SetupRouter(svc *controllers.Service) *gin.Engine {
 	r := gin.Default()
+	store := svc.Store()
 
 	// CORS for Angular dev server and OpenShift
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:4200", "https://*.openshiftapps.com"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Timezone"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		AllowOriginFunc: func(origin string) bool {
@@ -30,33 +31,126 @@ func SetupRouter() *gin.Engine {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Public keys verifying the tokens, for other services
+	r.GET("/.well-known/jwks.json", svc.GetJWKS)
+
 	api := r.Group("/api")
 	{
 		// Public routes
 		api.GET("/ping", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "pong"})
 		})
-		api.POST("/signup", controllers.Signup)
-		api.POST("/login", controllers.Login)
+		api.POST("/signup", svc.Signup)
+		api.POST("/login", svc.Login)
+		api.POST("/login/mfa", svc.LoginMFA)           // Authenticated by the challenge token of /login
+		api.POST("/token/refresh", svc.RefreshSession) // Authenticated by the refresh token
+		api.GET("/oidc/providers", svc.GetOIDCProviders)
+		api.POST("/oidc/:provider/login", svc.StartOIDCLogin)
+		api.POST("/oidc/:provider/callback", svc.CompleteOIDCLogin) // Authenticated by the provider
+		api.POST("/password/forgot", svc.ForgotPassword)
+		api.POST("/password/reset", svc.ResetPassword)         // Authenticated by the mailed token
+		api.POST("/email/verify", svc.VerifyEmail)             // Authenticated by the mailed token
+		api.GET("/calendar/feeds/:token", svc.GetCalendarFeed) // Authenticated by the secret token
+		api.GET("/invitations/:token", svc.GetInvitation)      // Authenticated by the signed token
+		api.GET("/rsvp/:token", svc.GetRSVP)                   // Authenticated by the signed token
+		api.POST("/rsvp/:token", svc.RespondRSVP)
 
 		// Protected routes (require authentication)
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(store))
 		{
+			protected.POST("/logout", svc.Logout)
+			protected.POST("/email/verify/resend", svc.ResendVerificationEmail)
+
+			// Two-factor authentication routes
+			protected.GET("/mfa", svc.GetMFAStatus)
+			protected.POST("/mfa/enroll", svc.EnrollMFA)
+			protected.POST("/mfa/confirm", svc.ConfirmMFA)
+			protected.POST("/mfa/recovery-codes", svc.RegenerateRecoveryCodes)
+			protected.POST("/mfa/disable", svc.DisableMFA)
+
+			// Personal access token routes
+			protected.GET("/tokens", svc.GetAccessTokens)
+			protected.POST("/tokens", svc.CreateAccessToken)
+			protected.DELETE("/tokens/:tokenId", svc.RevokeAccessToken)
+
 			// Event routes
-			protected.POST("/events", controllers.CreateEvent)
-			protected.GET("/events/organized", controllers.GetMyOrganizedEvents)
-			protected.GET("/events/invited", controllers.GetMyInvitedEvents)
-			protected.GET("/events/:id", controllers.GetEventDetails)
-			protected.DELETE("/events/:id", controllers.DeleteEvent)
-			protected.POST("/events/:id/invite", controllers.InviteUserToEvent)
+			protected.POST("/events", middleware.RequireVerifiedEmail(store, middleware.ActionCreateEvents), svc.CreateEvent)
+			protected.POST("/events/import", middleware.RequireVerifiedEmail(store, middleware.ActionCreateEvents), svc.ImportEvents)
+			protected.GET("/events/organized", svc.GetMyOrganizedEvents)
+			protected.GET("/events/invited", svc.GetMyInvitedEvents)
+			protected.GET("/events/:id", svc.GetEventDetails)
+			protected.PUT("/events/:id", svc.UpdateEvent)
+			protected.PATCH("/events/:id", svc.UpdateEvent)
+			protected.DELETE("/events/:id", svc.DeleteEvent)
+			protected.GET("/events/:id/changes", svc.GetEventChanges)
+			protected.POST("/events/:id/invite", middleware.RequireVerifiedEmail(store, middleware.ActionInvite), svc.InviteUserToEvent)
+			protected.POST("/events/:id/invite/bulk", middleware.RequireVerifiedEmail(store, middleware.ActionInvite), svc.BulkInviteToEvent)
+			protected.GET("/events/:id/invitations", svc.GetEventInvitations)
+			protected.DELETE("/events/:id/invitations/:invitationId", svc.RevokeEventInvitation)
+			protected.POST("/invitations/accept", svc.AcceptInvitation)
+			protected.GET("/events/:id/ics", svc.GetEventICS)
+
+			// Tag routes
+			protected.POST("/events/:id/tags", svc.AddEventTags)
+			protected.DELETE("/events/:id/tags/:tag", svc.RemoveEventTag)
+			protected.GET("/tags", svc.GetTagSuggestions)
 
 			// Response/Attendance routes
-			protected.PUT("/events/:id/attendance", controllers.UpdateAttendanceStatus)
-			protected.GET("/events/:id/attendees", controllers.GetEventAttendees)
+			protected.PUT("/events/:id/attendance", svc.UpdateAttendanceStatus)
+			protected.GET("/events/:id/attendees", svc.GetEventAttendees)
+			protected.DELETE("/events/:id/attendees/:userId", svc.RemoveAttendee)
+			protected.PUT("/events/:id/attendees/:userId/role", svc.UpdateAttendeeRole)
+			protected.GET("/events/:id/transfer", svc.GetOwnershipTransfers)
+			protected.POST("/events/:id/transfer", svc.RequestOwnershipTransfer)
+			protected.DELETE("/events/:id/transfer", svc.CancelOwnershipTransfer)
+			protected.POST("/events/:id/transfer/accept", svc.AcceptOwnershipTransfer)
+			protected.POST("/events/:id/transfer/decline", svc.DeclineOwnershipTransfer)
+			protected.GET("/events/:id/rsvp-links", svc.GetEventRSVPLinks)
+
+			// Occurrence routes (recurring events)
+			protected.GET("/events/:id/occurrences", svc.GetEventOccurrences)
+			protected.PATCH("/events/:id/occurrences/:occurrenceId", svc.UpdateEventOccurrence)
+			protected.DELETE("/events/:id/occurrences/:occurrenceId", svc.DeleteEventOccurrence)
+			protected.PUT("/events/:id/occurrences/:occurrenceId/attendance", svc.UpdateOccurrenceAttendance)
+			protected.GET("/events/:id/occurrences/:occurrenceId/attendees", svc.GetOccurrenceAttendees)
+
+			// Task routes
+			protected.GET("/events/:id/tasks", svc.GetEventTasks)
+			protected.POST("/events/:id/tasks", svc.CreateTask)
+			protected.GET("/events/:id/tasks/:taskId", svc.GetTaskDetails)
+			protected.PATCH("/events/:id/tasks/:taskId", svc.UpdateTask)
+			protected.DELETE("/events/:id/tasks/:taskId", svc.DeleteTask)
+			protected.PUT("/events/:id/tasks/:taskId/assignee", svc.AssignTask)
+			protected.DELETE("/events/:id/tasks/:taskId/assignee", svc.UnassignTask)
+
+			// Calendar feed routes
+			protected.GET("/calendar/feed", svc.GetCalendarFeedStatus)
+			protected.POST("/calendar/feed", svc.CreateCalendarFeed)
+			protected.DELETE("/calendar/feed", svc.DeleteCalendarFeed)
+
+			// Notification routes
+			protected.GET("/notifications", svc.GetNotifications)
+			protected.PUT("/notifications/read", svc.MarkAllNotificationsRead)
+			protected.PUT("/notifications/:id/read", svc.MarkNotificationRead)
 
 			// Search routes
-			protected.GET("/search", controllers.SearchEventsAndTasks)
+			protected.GET("/search", svc.SearchEventsAndTasks)
+
+			// Saved search routes
+			protected.GET("/saved-searches", svc.GetSavedSearches)
+			protected.POST("/saved-searches", svc.CreateSavedSearch)
+			protected.PATCH("/saved-searches/:searchId", svc.UpdateSavedSearch)
+			protected.DELETE("/saved-searches/:searchId", svc.DeleteSavedSearch)
+			protected.GET("/saved-searches/:searchId/results", svc.RunSavedSearch)
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireAdmin(store))
+			{
+				admin.GET("/login-throttles", svc.GetLoginThrottles)
+				admin.DELETE("/login-throttles/:throttleId", svc.ClearLoginThrottle)
+			}
 		}
 	}
 
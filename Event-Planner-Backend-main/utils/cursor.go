@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were issued
+// for another query.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPayload is the content of a pagination cursor.
+type cursorPayload struct {
+	Query  string   `json:"q"` // Fingerprint of the query the cursor belongs to
+	Values []string `json:"v"` // Sort key values of the last item returned
+}
+
+// QueryFingerprint returns a short digest of the parts of a query, to bind
+// cursors to the query they were issued for.
+func QueryFingerprint(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// EncodeCursor returns an opaque cursor pointing after the item with the
+// given sort key values.
+func EncodeCursor(fingerprint string, values []string) string {
+	b, _ := json.Marshal(cursorPayload{Query: fingerprint, Values: values})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns the sort key values of a cursor issued by EncodeCursor
+// for the same fingerprint.
+func DecodeCursor(cursor, fingerprint string) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(b, &payload); err != nil || payload.Query != fingerprint {
+		return nil, ErrInvalidCursor
+	}
+	return payload.Values, nil
+}
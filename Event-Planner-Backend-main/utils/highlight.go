@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// snippetContext is the number of bytes kept before the first match of a
+// snippet, and snippetLength its total length.
+const (
+	snippetContext = 60
+	snippetLength  = 200
+)
+
+var (
+	// booleanPhrase matches the quoted phrases of boolean queries.
+	booleanPhrase = regexp.MustCompile(`[+\-~<>]?"([^"]*)"`)
+	// asciiWord matches the characters \b treats as part of words.
+	asciiWord = regexp.MustCompile(`^\w$`)
+)
+
+// SearchTerms splits a full-text query into the words and phrases it
+// matches. In boolean mode, quoted phrases are kept whole, a trailing * marks
+// a prefix, and excluded (-) terms are dropped.
+func SearchTerms(query string, boolean bool) []string {
+	var terms []string
+	if boolean {
+		for _, phrase := range booleanPhrase.FindAllStringSubmatch(query, -1) {
+			if words := strings.Fields(phrase[1]); len(words) > 0 && !strings.HasPrefix(phrase[0], "-") {
+				terms = append(terms, strings.Join(words, " "))
+			}
+		}
+		query = booleanPhrase.ReplaceAllString(query, " ")
+		for _, word := range strings.Fields(query) {
+			if strings.HasPrefix(strings.TrimLeft(word, "(+~<>"), "-") {
+				continue
+			}
+			word = strings.Trim(word, "+~<>()@")
+			if strings.TrimRight(word, "*") != "" {
+				terms = append(terms, word)
+			}
+		}
+		return terms
+	}
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// HighlightPattern returns a case-insensitive pattern matching terms as whole
+// words (prefixes for terms ending with *), or nil without terms.
+func HighlightPattern(terms []string) *regexp.Regexp {
+	alternatives := make([]string, 0, len(terms))
+	for _, term := range terms {
+		prefix := strings.TrimRight(term, "*")
+		pattern := regexp.QuoteMeta(prefix)
+		// \b only works next to ASCII letters and digits
+		if first, _ := utf8.DecodeRuneInString(prefix); asciiWord.MatchString(string(first)) {
+			pattern = `\b` + pattern
+		}
+		if prefix != term {
+			pattern += `[\p{L}\p{N}_]*`
+		} else if last, _ := utf8.DecodeLastRuneInString(prefix); asciiWord.MatchString(string(last)) {
+			pattern += `\b`
+		}
+		alternatives = append(alternatives, pattern)
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)(?:` + strings.Join(alternatives, "|") + `)`)
+}
+
+// Highlight returns an HTML snippet of text around the first match of
+// pattern, with the matches wrapped in <mark> and the rest escaped, or false
+// when nothing matches.
+func Highlight(text string, pattern *regexp.Regexp) (string, bool) {
+	if pattern == nil {
+		return "", false
+	}
+	matches := pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start := matches[0][0] - snippetContext
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(text) {
+		end = len(text)
+	}
+	// Cut at rune boundaries, and preferably at spaces
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if start > 0 {
+		if i := strings.IndexByte(text[start:matches[0][0]], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if end < len(text) {
+		if i := strings.LastIndexByte(text[matches[0][1]:end], ' '); i >= 0 {
+			end = matches[0][1] + i
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[0] < pos || m[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
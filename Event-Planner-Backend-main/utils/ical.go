@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalLineLimit is the maximum length of a content line in octets, excluding CRLF.
+const icalLineLimit = 75
+
+// ICalWriter builds an iCalendar (RFC 5545) document with CRLF line endings
+// and long lines folded.
+type ICalWriter struct {
+	b strings.Builder
+}
+
+// Begin opens a component such as VCALENDAR or VEVENT.
+func (w *ICalWriter) Begin(component string) {
+	w.Prop("BEGIN", component)
+}
+
+// End closes a component.
+func (w *ICalWriter) End(component string) {
+	w.Prop("END", component)
+}
+
+// Prop writes a property with a raw value. The name may carry parameters,
+// e.g. "DTSTART;TZID=Europe/Berlin".
+func (w *ICalWriter) Prop(name, value string) {
+	line := name + ":" + value
+	for len(line) > icalLineLimit {
+		// Fold without splitting a UTF-8 sequence; continuation lines start
+		// with a space, which counts towards the limit.
+		cut := icalLineLimit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.b.WriteString(line[:cut])
+		w.b.WriteString("\r\n")
+		line = " " + line[cut:]
+	}
+	w.b.WriteString(line)
+	w.b.WriteString("\r\n")
+}
+
+// Text writes a property with a TEXT value, escaping it.
+func (w *ICalWriter) Text(name, value string) {
+	w.Prop(name, EscapeICalText(value))
+}
+
+// Time writes a date-time property: in UTC for UTC instants, otherwise as
+// local time with a TZID parameter.
+func (w *ICalWriter) Time(name string, t time.Time) {
+	w.Prop(name+icalTZIDParam(t.Location()), formatICalTime(t))
+}
+
+// Times writes a date-time list property such as EXDATE, in the location of
+// the first value.
+func (w *ICalWriter) Times(name string, times []time.Time) {
+	if len(times) == 0 {
+		return
+	}
+	loc := times[0].Location()
+	values := make([]string, len(times))
+	for i, t := range times {
+		values[i] = formatICalTime(t.In(loc))
+	}
+	w.Prop(name+icalTZIDParam(loc), strings.Join(values, ","))
+}
+
+// TimeZone writes a VTIMEZONE component for loc with the UTC offset
+// transitions between from and to. Nothing is written for UTC, whose times
+// are written with a "Z" suffix instead.
+func (w *ICalWriter) TimeZone(loc *time.Location, from, to time.Time) {
+	if loc == time.UTC || loc.String() == "UTC" {
+		return
+	}
+
+	w.Begin("VTIMEZONE")
+	w.Prop("TZID", loc.String())
+	t := from.In(loc)
+	for i := 0; i < 1000; i++ {
+		start, end := t.ZoneBounds()
+		name, offset := t.Zone()
+		offsetFrom := offset
+		if !start.IsZero() {
+			_, offsetFrom = start.Add(-time.Second).Zone()
+		} else {
+			start = time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+		}
+
+		component := "STANDARD"
+		if t.IsDST() {
+			component = "DAYLIGHT"
+		}
+		w.Begin(component)
+		// DTSTART is the local time of the transition before it happens
+		w.Prop("DTSTART", start.In(time.FixedZone("", offsetFrom)).Format(icalLocalLayout))
+		w.Prop("TZOFFSETFROM", formatICalOffset(offsetFrom))
+		w.Prop("TZOFFSETTO", formatICalOffset(offset))
+		if name != "" && !strings.HasPrefix(name, "+") && !strings.HasPrefix(name, "-") {
+			w.Prop("TZNAME", EscapeICalText(name))
+		}
+		w.End(component)
+
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		t = end.In(loc)
+	}
+	w.End("VTIMEZONE")
+}
+
+// String returns the document written so far.
+func (w *ICalWriter) String() string {
+	return w.b.String()
+}
+
+// EscapeICalText escapes a TEXT value: backslashes, semicolons, commas and newlines.
+func EscapeICalText(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`).Replace(value)
+}
+
+// QuoteICalParam returns a parameter value, quoted when it contains
+// characters that are not allowed unquoted. Double quotes are dropped, as
+// they cannot be escaped.
+func QuoteICalParam(value string) string {
+	value = strings.ReplaceAll(value, `"`, "")
+	if strings.ContainsAny(value, ":;,") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func icalTZIDParam(loc *time.Location) string {
+	if loc == time.UTC || loc.String() == "UTC" {
+		return ""
+	}
+	return ";TZID=" + QuoteICalParam(loc.String())
+}
+
+func formatICalTime(t time.Time) string {
+	if loc := t.Location(); loc == time.UTC || loc.String() == "UTC" {
+		return FormatICalUTC(t)
+	}
+	return t.Format(icalLocalLayout)
+}
+
+func formatICalOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	hours, minutes, secs := seconds/3600, seconds/60%60, seconds%60
+	value := sign + twoDigits(hours) + twoDigits(minutes)
+	if secs != 0 {
+		value += twoDigits(secs)
+	}
+	return value
+}
+
+func twoDigits(n int) string {
+	return string([]byte{byte('0' + n/10%10), byte('0' + n%10)})
+}
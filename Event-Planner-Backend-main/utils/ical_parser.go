@@ -0,0 +1,577 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ICalProperty is a content line such as `DTSTART;TZID=Europe/Berlin:20240715T180000`.
+type ICalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// ICalComponent is a BEGIN/END block with its properties and sub-components.
+type ICalComponent struct {
+	Name       string
+	Properties []ICalProperty
+	Components []*ICalComponent
+}
+
+// Prop returns the first property with the given name, or nil.
+func (c *ICalComponent) Prop(name string) *ICalProperty {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Value returns the value of the first property with the given name, or "".
+func (c *ICalComponent) Value(name string) string {
+	if p := c.Prop(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+// ICalEvent is a VEVENT read from an iCalendar document.
+type ICalEvent struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	Timezone     string // IANA name of the start, "UTC" for UTC or unresolved zones
+	AllDay       bool
+	RRule        string
+	ExDates      []time.Time
+	RDates       []time.Time
+	RecurrenceID *time.Time // Set when the VEVENT overrides one occurrence of a series
+	Cancelled    bool
+	Warnings     []string
+}
+
+// windowsZones maps common Windows time zone names, as written by Outlook
+// and Exchange, to IANA names.
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"Egypt Standard Time":            "Africa/Cairo",
+	"Arab Standard Time":             "Asia/Riyadh",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"Russian Standard Time":          "Europe/Moscow",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"SA Pacific Standard Time":       "America/Bogota",
+	"E. South America Standard Time": "America/Sao_Paulo",
+}
+
+// ParseICal reads an iCalendar document into its top-level component.
+func ParseICal(r io.Reader) (*ICalComponent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *ICalComponent
+	var stack []*ICalComponent
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseICalLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		switch prop.Name {
+		case "BEGIN":
+			comp := &ICalComponent{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, comp)
+			} else if root == nil {
+				root = comp
+			} else {
+				return nil, fmt.Errorf("line %d: more than one top-level component", n+1)
+			}
+			stack = append(stack, comp)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside of a component", n+1)
+			}
+			comp := stack[len(stack)-1]
+			comp.Properties = append(comp.Properties, prop)
+		}
+	}
+	if root == nil || root.Name != "VCALENDAR" {
+		return nil, errors.New("not an iCalendar file: VCALENDAR missing")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// ParseICalEvents reads the VEVENTs of an iCalendar document. Floating and
+// date-only times are read in defaultLoc. Time zones are resolved by IANA
+// name where possible, otherwise from the VTIMEZONE components of the
+// document.
+func ParseICalEvents(r io.Reader, defaultLoc *time.Location) ([]ICalEvent, error) {
+	root, err := ParseICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := map[string]*icalZone{}
+	for _, comp := range root.Components {
+		if comp.Name == "VTIMEZONE" {
+			if zone, err := parseICalZone(comp); err == nil {
+				zones[comp.Value("TZID")] = zone
+			}
+		}
+	}
+	resolver := icalTimeResolver{zones: zones, defaultLoc: defaultLoc}
+
+	var events []ICalEvent
+	for _, comp := range root.Components {
+		if comp.Name != "VEVENT" {
+			continue
+		}
+		event, err := resolver.event(comp)
+		if err != nil {
+			uid := comp.Value("UID")
+			if uid == "" {
+				uid = comp.Value("SUMMARY")
+			}
+			return nil, fmt.Errorf("event %q: %w", uid, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// UnescapeICalText reverses EscapeICalText.
+func UnescapeICalText(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// ParseICalDuration parses an RFC 5545 duration such as "PT1H30M" or "P1D".
+func ParseICalDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign, s = -1, s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var total time.Duration
+	num := ""
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= '0' && ch <= '9':
+			num += string(ch)
+		case ch == 'T':
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		default:
+			unit, ok := units[ch]
+			if !ok || num == "" {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			n, _ := strconv.Atoi(num)
+			total += time.Duration(n) * unit
+			num = ""
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * total, nil
+}
+
+// unfoldICalLines splits a document into logical content lines.
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff") // Byte order mark
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalLine parses `NAME;PARAM=VALUE;PARAM="QUOTED":VALUE`.
+func parseICalLine(line string) (ICalProperty, error) {
+	prop := ICalProperty{Params: map[string]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return prop, fmt.Errorf("invalid content line %q", truncateICal(line))
+	}
+	prop.Name = strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return prop, fmt.Errorf("invalid parameter in %q", truncateICal(line))
+		}
+		key := strings.ToUpper(rest[:eq])
+		j := i + 1 + eq + 1
+		var value strings.Builder
+		quoted := false
+		for ; j < len(line); j++ {
+			ch := line[j]
+			if ch == '"' {
+				quoted = !quoted
+				continue
+			}
+			if !quoted && (ch == ';' || ch == ':') {
+				break
+			}
+			value.WriteByte(ch)
+		}
+		if j >= len(line) {
+			return prop, fmt.Errorf("missing value in %q", truncateICal(line))
+		}
+		prop.Params[key] = value.String()
+		i = j
+	}
+	prop.Value = line[i+1:]
+	return prop, nil
+}
+
+func truncateICal(line string) string {
+	if len(line) > 40 {
+		return line[:40] + "..."
+	}
+	return line
+}
+
+// icalObservance is a STANDARD or DAYLIGHT block of a VTIMEZONE.
+type icalObservance struct {
+	start    time.Time // Onset as wall-clock time, stored in UTC
+	rule     *RRule
+	rdates   []time.Time
+	offsetTo int
+}
+
+// icalZone is a time zone defined by a VTIMEZONE component.
+type icalZone struct {
+	observances []icalObservance
+	fallback    int // Offset used before the first onset
+}
+
+func parseICalZone(comp *ICalComponent) (*icalZone, error) {
+	zone := &icalZone{}
+	first := time.Time{}
+	for _, sub := range comp.Components {
+		if sub.Name != "STANDARD" && sub.Name != "DAYLIGHT" {
+			continue
+		}
+		start, err := time.Parse(icalLocalLayout, sub.Value("DTSTART"))
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseICalOffset(sub.Value("TZOFFSETTO"))
+		if err != nil {
+			return nil, err
+		}
+		obs := icalObservance{start: start, offsetTo: to}
+		if value := sub.Value("RRULE"); value != "" {
+			if obs.rule, err = ParseRRule(value, time.UTC); err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range sub.Properties {
+			if p.Name == "RDATE" {
+				dates, _ := ParseICalDateList(p.Value, time.UTC)
+				obs.rdates = append(obs.rdates, dates...)
+			}
+		}
+		if first.IsZero() || start.Before(first) {
+			first = start
+			if from, err := parseICalOffset(sub.Value("TZOFFSETFROM")); err == nil {
+				zone.fallback = from
+			}
+		}
+		zone.observances = append(zone.observances, obs)
+	}
+	if len(zone.observances) == 0 {
+		return nil, errors.New("VTIMEZONE without observances")
+	}
+	return zone, nil
+}
+
+// offsetAt returns the UTC offset in seconds in effect at a wall-clock time
+// (given in UTC).
+func (z *icalZone) offsetAt(local time.Time) int {
+	var onset time.Time
+	offset := z.fallback
+	for _, obs := range z.observances {
+		latest := time.Time{}
+		if obs.rule != nil {
+			obs.rule.Iterate(obs.start, func(t time.Time) bool {
+				if t.After(local) {
+					return false
+				}
+				latest = t
+				return true
+			})
+		} else if !obs.start.After(local) {
+			latest = obs.start
+		}
+		for _, t := range obs.rdates {
+			if !t.After(local) && t.After(latest) {
+				latest = t
+			}
+		}
+		if !latest.IsZero() && latest.After(onset) {
+			onset, offset = latest, obs.offsetTo
+		}
+	}
+	return offset
+}
+
+func parseICalOffset(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	sign := 1
+	switch value[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	h, err1 := strconv.Atoi(value[1:3])
+	m, err2 := strconv.Atoi(value[3:5])
+	s := 0
+	var err3 error
+	if len(value) == 7 {
+		s, err3 = strconv.Atoi(value[5:7])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	return sign * (h*3600 + m*60 + s), nil
+}
+
+// icalTimeResolver converts date-time properties to instants.
+type icalTimeResolver struct {
+	zones      map[string]*icalZone
+	defaultLoc *time.Location
+}
+
+// icalTime is a resolved date-time property.
+type icalTime struct {
+	t        time.Time
+	timezone string // IANA name, or "UTC" when it could not be resolved
+	allDay   bool
+	warning  string
+}
+
+// location maps a TZID to an IANA location: directly, by a known Windows
+// name, or by a trailing "Area/City" part as in "/mozilla.org/.../Europe/Berlin".
+func (r icalTimeResolver) location(tzid string) *time.Location {
+	candidates := []string{tzid}
+	if name, ok := windowsZones[tzid]; ok {
+		candidates = append(candidates, name)
+	}
+	if parts := strings.Split(tzid, "/"); len(parts) > 2 {
+		candidates = append(candidates, strings.Join(parts[len(parts)-2:], "/"))
+	}
+	for _, name := range candidates {
+		if name == "" || strings.HasPrefix(name, "/") {
+			continue
+		}
+		if loc, err := LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return nil
+}
+
+func (r icalTimeResolver) resolve(p *ICalProperty, value string) (icalTime, error) {
+	value = strings.TrimSpace(value)
+	if p.Params["VALUE"] == "DATE" || len(value) == len(icalDateOnlyLayout) {
+		t, err := time.ParseInLocation(icalDateOnlyLayout, value, r.defaultLoc)
+		return icalTime{t: t, timezone: r.defaultLoc.String(), allDay: true}, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalUTCLayout, value)
+		return icalTime{t: t, timezone: "UTC"}, err
+	}
+
+	tzid := p.Params["TZID"]
+	if tzid == "" {
+		t, err := time.ParseInLocation(icalLocalLayout, value, r.defaultLoc)
+		return icalTime{t: t, timezone: r.defaultLoc.String()}, err
+	}
+	if loc := r.location(tzid); loc != nil {
+		t, err := time.ParseInLocation(icalLocalLayout, value, loc)
+		return icalTime{t: t, timezone: loc.String()}, err
+	}
+
+	local, err := time.Parse(icalLocalLayout, value)
+	if err != nil {
+		return icalTime{}, err
+	}
+	if zone, ok := r.zones[tzid]; ok {
+		t := local.Add(-time.Duration(zone.offsetAt(local)) * time.Second)
+		return icalTime{t: t, timezone: "UTC", warning: "time zone " + tzid + " is not a known IANA zone; times were converted to UTC"}, nil
+	}
+	t := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, r.defaultLoc)
+	return icalTime{t: t, timezone: r.defaultLoc.String(), warning: "unknown time zone " + tzid + "; times were read in " + r.defaultLoc.String()}, nil
+}
+
+// resolveList resolves a date-time list property such as EXDATE.
+func (r icalTimeResolver) resolveList(p *ICalProperty) ([]time.Time, error) {
+	var result []time.Time
+	for _, value := range strings.Split(p.Value, ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		if p.Params["VALUE"] == "PERIOD" {
+			value = strings.SplitN(value, "/", 2)[0]
+		}
+		t, err := r.resolve(p, value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t.t)
+	}
+	return result, nil
+}
+
+func (r icalTimeResolver) event(comp *ICalComponent) (ICalEvent, error) {
+	event := ICalEvent{
+		UID:         strings.TrimSpace(comp.Value("UID")),
+		Summary:     UnescapeICalText(comp.Value("SUMMARY")),
+		Description: UnescapeICalText(comp.Value("DESCRIPTION")),
+		Location:    UnescapeICalText(comp.Value("LOCATION")),
+		RRule:       strings.TrimSpace(comp.Value("RRULE")),
+		Cancelled:   strings.EqualFold(comp.Value("STATUS"), "CANCELLED"),
+	}
+	warn := func(message string) {
+		for _, w := range event.Warnings {
+			if w == message {
+				return
+			}
+		}
+		event.Warnings = append(event.Warnings, message)
+	}
+
+	dtstart := comp.Prop("DTSTART")
+	if dtstart == nil {
+		return event, errors.New("DTSTART missing")
+	}
+	start, err := r.resolve(dtstart, dtstart.Value)
+	if err != nil {
+		return event, fmt.Errorf("invalid DTSTART: %w", err)
+	}
+	event.Start, event.Timezone, event.AllDay = start.t, start.timezone, start.allDay
+	if start.warning != "" {
+		warn(start.warning)
+	}
+
+	switch {
+	case comp.Prop("DTEND") != nil:
+		end, err := r.resolve(comp.Prop("DTEND"), comp.Value("DTEND"))
+		if err != nil {
+			return event, fmt.Errorf("invalid DTEND: %w", err)
+		}
+		event.End = end.t
+	case comp.Prop("DURATION") != nil:
+		d, err := ParseICalDuration(comp.Value("DURATION"))
+		if err != nil {
+			return event, err
+		}
+		event.End = event.Start.Add(d)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	}
+
+	if p := comp.Prop("RECURRENCE-ID"); p != nil {
+		id, err := r.resolve(p, p.Value)
+		if err != nil {
+			return event, fmt.Errorf("invalid RECURRENCE-ID: %w", err)
+		}
+		event.RecurrenceID = &id.t
+	}
+
+	for i := range comp.Properties {
+		p := &comp.Properties[i]
+		if p.Name != "EXDATE" && p.Name != "RDATE" {
+			continue
+		}
+		times, err := r.resolveList(p)
+		if err != nil {
+			return event, fmt.Errorf("invalid %s: %w", p.Name, err)
+		}
+		if p.Name == "EXDATE" {
+			event.ExDates = append(event.ExDates, times...)
+		} else {
+			event.RDates = append(event.RDates, times...)
+		}
+	}
+	if event.AllDay {
+		warn("all-day event imported as starting at midnight")
+	}
+	return event, nil
+}
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// icalDocument joins content lines with CRLF.
+func icalDocument(lines ...string) string {
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// parseEvent parses a document holding a single VEVENT with the given
+// lines, reading floating times in loc.
+func parseEvent(t *testing.T, loc *time.Location, lines ...string) ICalEvent {
+	t.Helper()
+	doc := append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "BEGIN:VEVENT", "UID:event-1"}, lines...), "END:VEVENT", "END:VCALENDAR")
+	events, err := ParseICalEvents(strings.NewReader(icalDocument(doc...)), loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events, want 1", len(events))
+	}
+	return events[0]
+}
+
+func TestParseICalUnfolding(t *testing.T) {
+	doc := "\ufeffBEGIN:VCALENDAR\r\n" + // Byte order mark
+		"BEGIN:VEVENT\r\n" +
+		"UID:event-1\r\n" +
+		"DTSTART:20240715T160000Z\r\n" +
+		"SUMMARY:Summer \r\n" +
+		" party\r\n" +
+		"DESCRIPTION:Bring food\\, drinks\\; and\\n\r\n" +
+		"\tgood mood\r\n" +
+		"LOCATION:Hall\n" + // Bare LF line endings are accepted
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	events, err := ParseICalEvents(strings.NewReader(doc), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Summary != "Summer party" {
+		t.Errorf("summary = %q", event.Summary)
+	}
+	if event.Description != "Bring food, drinks; and\ngood mood" {
+		t.Errorf("description = %q", event.Description)
+	}
+	if event.Location != "Hall" {
+		t.Errorf("location = %q", event.Location)
+	}
+}
+
+func TestParseICalNestedComponents(t *testing.T) {
+	doc := icalDocument(
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Test//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"BEGIN:STANDARD",
+		"DTSTART:19701025T030000",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:19700329T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"END:DAYLIGHT",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:event-1",
+		"DTSTART;TZID=Europe/Berlin:20240715T180000",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"SUMMARY:After the alarm",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	)
+	root, err := ParseICal(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "VCALENDAR" || root.Value("PRODID") != "-//Test//EN" || len(root.Components) != 2 {
+		t.Fatalf("root = %+v", root)
+	}
+	zone, event := root.Components[0], root.Components[1]
+	if zone.Name != "VTIMEZONE" || len(zone.Components) != 2 || zone.Components[1].Name != "DAYLIGHT" {
+		t.Errorf("time zone = %+v", zone)
+	}
+	if event.Name != "VEVENT" || len(event.Components) != 1 || event.Components[0].Value("TRIGGER") != "-PT15M" {
+		t.Errorf("event = %+v", event)
+	}
+	// Properties go to the innermost open component
+	if event.Prop("TRIGGER") != nil || event.Value("SUMMARY") != "After the alarm" {
+		t.Errorf("event properties = %+v", event.Properties)
+	}
+	if p := event.Prop("DTSTART"); p == nil || p.Params["TZID"] != "Europe/Berlin" || p.Value != "20240715T180000" {
+		t.Errorf("DTSTART = %+v", p)
+	}
+}
+
+func TestParseICalEventTimes(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	summer := time.Date(2024, 7, 15, 16, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		lines    []string
+		start    time.Time
+		end      time.Time
+		timezone string
+		allDay   bool
+		warning  string
+	}{
+		{"utc", []string{"DTSTART:20240715T160000Z", "DTEND:20240715T170000Z"},
+			summer, summer.Add(time.Hour), "UTC", false, ""},
+		{"tzid", []string{"DTSTART;TZID=Europe/Berlin:20240715T180000", "DURATION:PT1H30M"},
+			summer, summer.Add(90 * time.Minute), "Europe/Berlin", false, ""},
+		{"quoted windows tzid", []string{`DTSTART;TZID="W. Europe Standard Time":20240715T180000`},
+			summer, time.Time{}, "Europe/Berlin", false, ""},
+		{"tzid with a path", []string{"DTSTART;TZID=/mozilla.org/20050126_1/Europe/Berlin:20240715T180000"},
+			summer, time.Time{}, "Europe/Berlin", false, ""},
+		{"floating", []string{"DTSTART:20240715T180000"},
+			summer, time.Time{}, "Europe/Berlin", false, ""},
+		{"date", []string{"DTSTART;VALUE=DATE:20240715"},
+			time.Date(2024, 7, 14, 22, 0, 0, 0, time.UTC), time.Date(2024, 7, 15, 22, 0, 0, 0, time.UTC), "Europe/Berlin", true,
+			"all-day event imported as starting at midnight"},
+		{"date with an end", []string{"DTSTART;VALUE=DATE:20240715", "DTEND;VALUE=DATE:20240718"},
+			time.Date(2024, 7, 14, 22, 0, 0, 0, time.UTC), time.Date(2024, 7, 17, 22, 0, 0, 0, time.UTC), "Europe/Berlin", true,
+			"all-day event imported as starting at midnight"},
+		{"unknown tzid", []string{"DTSTART;TZID=Nowhere:20240715T180000"},
+			summer, time.Time{}, "Europe/Berlin", false, "unknown time zone Nowhere; times were read in Europe/Berlin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := parseEvent(t, berlin, tt.lines...)
+			if !event.Start.Equal(tt.start) || !event.End.Equal(tt.end) {
+				t.Errorf("event from %v to %v, want %v to %v", event.Start, event.End, tt.start, tt.end)
+			}
+			if event.Timezone != tt.timezone || event.AllDay != tt.allDay {
+				t.Errorf("time zone %q, all day %v; want %q, %v", event.Timezone, event.AllDay, tt.timezone, tt.allDay)
+			}
+			if warnings := strings.Join(event.Warnings, "; "); warnings != tt.warning {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestParseICalCustomTimeZone(t *testing.T) {
+	// A zone without IANA name is converted with its VTIMEZONE
+	doc := icalDocument(
+		"BEGIN:VCALENDAR",
+		"BEGIN:VTIMEZONE",
+		"TZID:Company Time",
+		"BEGIN:STANDARD",
+		"DTSTART:19701025T030000",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:19700329T020000",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"END:DAYLIGHT",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:event-1",
+		"DTSTART;TZID=Company Time:20240715T180000",
+		"DTEND;TZID=Company Time:20241215T180000",
+		"RRULE:FREQ=WEEKLY",
+		"EXDATE;TZID=Company Time:20240722T180000,20240729T180000",
+		"RDATE;VALUE=PERIOD:20240801T100000Z/PT1H",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20240715T180000",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	)
+	events, err := ParseICalEvents(strings.NewReader(doc), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := events[0]
+	summer := time.Date(2024, 7, 15, 16, 0, 0, 0, time.UTC)
+	if !event.Start.Equal(summer) || !event.End.Equal(time.Date(2024, 12, 15, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("event from %v to %v", event.Start, event.End)
+	}
+	if event.Timezone != "UTC" || len(event.Warnings) != 1 || !strings.Contains(event.Warnings[0], "Company Time") {
+		t.Errorf("time zone %q, warnings %q", event.Timezone, event.Warnings)
+	}
+	expectTimes(t, "exdates", event.ExDates, []time.Time{summer.AddDate(0, 0, 7), summer.AddDate(0, 0, 14)})
+	expectTimes(t, "rdates", event.RDates, []time.Time{time.Date(2024, 8, 1, 10, 0, 0, 0, time.UTC)})
+	if event.RRule != "FREQ=WEEKLY" || event.RecurrenceID == nil || !event.RecurrenceID.Equal(summer) || !event.Cancelled {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestParseICalMalformed(t *testing.T) {
+	event := func(lines ...string) string {
+		return icalDocument(append(append([]string{"BEGIN:VCALENDAR", "BEGIN:VEVENT"}, lines...), "END:VEVENT", "END:VCALENDAR")...)
+	}
+	tests := map[string]string{
+		"empty":                       "",
+		"not a calendar":              icalDocument("BEGIN:VEVENT", "DTSTART:20240715T160000Z", "END:VEVENT"),
+		"unterminated":                icalDocument("BEGIN:VCALENDAR", "BEGIN:VEVENT"),
+		"mismatched end":              icalDocument("BEGIN:VCALENDAR", "BEGIN:VEVENT", "END:VCALENDAR"),
+		"two calendars":               icalDocument("BEGIN:VCALENDAR", "END:VCALENDAR", "BEGIN:VCALENDAR", "END:VCALENDAR"),
+		"property outside":            icalDocument("VERSION:2.0", "BEGIN:VCALENDAR", "END:VCALENDAR"),
+		"line without value":          event("DTSTART:20240715T160000Z", "GARBAGE"),
+		"parameter without value":     event("DTSTART;TZID:20240715T160000Z"),
+		"unterminated quote":          event(`DTSTART;TZID="Europe/Berlin:20240715T180000`),
+		"missing start":               event("SUMMARY:No start"),
+		"invalid start":               event("DTSTART:2024-07-15T16:00:00Z"),
+		"invalid end":                 event("DTSTART:20240715T160000Z", "DTEND:tomorrow"),
+		"invalid duration":            event("DTSTART:20240715T160000Z", "DURATION:1H"),
+		"invalid exdate":              event("DTSTART:20240715T160000Z", "EXDATE:20240722T160000Z,next week"),
+		"invalid recurrence id":       event("DTSTART:20240715T160000Z", "RECURRENCE-ID:20240715T16"),
+		"invalid date with parameter": event("DTSTART;VALUE=DATE:2024071"),
+	}
+	for name, doc := range tests {
+		if events, err := ParseICalEvents(strings.NewReader(doc), time.UTC); err == nil {
+			t.Errorf("%s: parsed %+v", name, events)
+		}
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT1H30M":    90 * time.Minute,
+		"P1D":        24 * time.Hour,
+		"P1W":        7 * 24 * time.Hour,
+		"P1DT2H":     26 * time.Hour,
+		"-PT15M":     -15 * time.Minute,
+		"+PT10S":     10 * time.Second,
+		" pt45m ":    45 * time.Minute,
+		"P1DT1H1M1S": 25*time.Hour + time.Minute + time.Second,
+	}
+	for value, want := range tests {
+		if got, err := ParseICalDuration(value); err != nil || got != want {
+			t.Errorf("ParseICalDuration(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "P", "PT", "1H", "PT1H30", "P1H", "PT1D", "PXD"} {
+		if _, err := ParseICalDuration(value); err == nil {
+			t.Errorf("ParseICalDuration(%q) succeeded", value)
+		}
+	}
+}
+
+func TestICalWriterRoundTrip(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	start := time.Date(2024, 3, 24, 18, 0, 0, 0, berlin)
+	exdates := []time.Time{start.AddDate(0, 0, 7), start.AddDate(0, 0, 14)}
+	summary := `Team dinner; "Müller & Söhne", room 2\3`
+	description := strings.Repeat("Menü: crème brûlée, façon maison. ", 5) + "\nSecond line"
+
+	var w ICalWriter
+	w.Begin("VCALENDAR")
+	w.Prop("VERSION", "2.0")
+	w.TimeZone(berlin, start, start.AddDate(0, 2, 0))
+	w.Begin("VEVENT")
+	w.Prop("UID", "event-1@example.com")
+	w.Text("SUMMARY", summary)
+	w.Text("DESCRIPTION", description)
+	w.Time("DTSTART", start)
+	w.Time("DTEND", start.Add(2*time.Hour).UTC())
+	w.Prop("RRULE", "FREQ=WEEKLY;COUNT=5")
+	w.Times("EXDATE", exdates)
+	w.End("VEVENT")
+	w.End("VCALENDAR")
+	doc := w.String()
+
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\r\n"), "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+
+	events, err := ParseICalEvents(strings.NewReader(doc), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.UID != "event-1@example.com" || event.Summary != summary || event.Description != description {
+		t.Errorf("text = %q, %q, %q", event.UID, event.Summary, event.Description)
+	}
+	if !event.Start.Equal(start) || !event.End.Equal(start.Add(2*time.Hour)) || event.Timezone != "Europe/Berlin" {
+		t.Errorf("event from %v to %v in %s", event.Start, event.End, event.Timezone)
+	}
+	if event.RRule != "FREQ=WEEKLY;COUNT=5" || len(event.Warnings) != 0 {
+		t.Errorf("rule %q, warnings %q", event.RRule, event.Warnings)
+	}
+	expectTimes(t, "exdates", event.ExDates, exdates)
+
+	// The VTIMEZONE written converts the times of a zone renamed by the reader
+	renamed := strings.ReplaceAll(doc, "Europe/Berlin", "Company Time")
+	events, err = ParseICalEvents(strings.NewReader(renamed), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !events[0].Start.Equal(start) {
+		t.Errorf("start with the written VTIMEZONE = %v, want %v", events[0].Start, start)
+	}
+	expectTimes(t, "exdates with the written VTIMEZONE", events[0].ExDates, exdates)
+}
@@ -7,26 +7,23 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// GenerateJWT creates a JWT with subject userID and 24h expiry.
-func GenerateJWT(secret string, userID uint, email string) (string, error) {
+// GenerateJWT creates an access token with subject userID, expiring after
+// ttl. The jti identifies the token so that it can be revoked.
+func GenerateJWT(keys *JWTKeySet, userID uint, email, jti string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
-		"sub": userID,
+		"sub":   userID,
 		"email": email,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
-		"iat": time.Now().Unix(),
+		"jti":   jti,
+		"exp":   time.Now().Add(ttl).Unix(),
+		"iat":   time.Now().Unix(),
 	}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+	return keys.sign(claims)
 }
 
-// ParseAndValidateJWT parses and validates a token, returning claims if valid.
-func ParseAndValidateJWT(secret, tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
+// ParseAndValidateJWT parses and validates a token signed by one of keys,
+// returning claims if valid.
+func ParseAndValidateJWT(keys *JWTKeySet, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, keys.keyFunc)
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
@@ -35,3 +32,51 @@ func ParseAndValidateJWT(secret, tokenString string) (jwt.MapClaims, error) {
 	}
 	return nil, errors.New("invalid claims")
 }
+
+// Purposes of scoped tokens.
+const (
+	// PurposeInvite marks tokens of invitations sent to unregistered emails.
+	PurposeInvite = "invite"
+	// PurposeRSVP marks tokens of RSVP links, answering one event for one user.
+	PurposeRSVP = "rsvp"
+	// PurposeVerifyEmail marks tokens proving access to an email address.
+	PurposeVerifyEmail = "verify_email"
+	// PurposeMFA marks tokens of logins waiting for a second factor.
+	PurposeMFA = "mfa"
+)
+
+// GenerateScopedJWT creates a JWT that is only valid for purpose, carrying
+// claims and expiring after ttl. Scoped tokens have no subject and are never
+// accepted as login tokens.
+func GenerateScopedJWT(keys *JWTKeySet, purpose string, claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	scoped := jwt.MapClaims{}
+	for k, v := range claims {
+		scoped[k] = v
+	}
+	scoped["purpose"] = purpose
+	scoped["iat"] = time.Now().Unix()
+	scoped["exp"] = time.Now().Add(ttl).Unix()
+	delete(scoped, "sub")
+	return keys.sign(scoped)
+}
+
+// ParseScopedJWT validates a token created by GenerateScopedJWT for purpose.
+func ParseScopedJWT(keys *JWTKeySet, purpose, tokenString string) (jwt.MapClaims, error) {
+	claims, err := ParseAndValidateJWT(keys, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if p, _ := claims["purpose"].(string); p != purpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
+}
+
+// ClaimUint reads a numeric claim.
+func ClaimUint(claims jwt.MapClaims, name string) (uint, bool) {
+	v, ok := claims[name].(float64)
+	if !ok || v < 0 || v != float64(uint(v)) {
+		return 0, false
+	}
+	return uint(v), true
+}
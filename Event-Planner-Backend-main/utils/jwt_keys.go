@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is a key signing or verifying JWTs with one algorithm.
+type JWTKey struct {
+	ID     string // kid header of the tokens it signs
+	Method jwt.SigningMethod
+	sign   interface{}      // Private key or HMAC secret; nil for verification-only keys
+	verify interface{}      // Public key or HMAC secret
+	public crypto.PublicKey // nil for HMAC secrets, which are never published
+}
+
+// NewHMACKey returns an HS256 key for secret. Its ID derives from the secret,
+// without revealing it.
+func NewHMACKey(secret string) *JWTKey {
+	sum := sha256.Sum256([]byte("kid:" + secret))
+	return &JWTKey{
+		ID:     "hs-" + hex.EncodeToString(sum[:6]),
+		Method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// ParseJWTKeyPEM reads an RSA (RS256) or Ed25519 (EdDSA) key from PEM. A
+// private key can sign; a public key only verifies. The ID is the RFC 7638
+// thumbprint of the public key, so every instance derives the same one.
+func ParseJWTKeyPEM(data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &JWTKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.sign, key.verify, key.public = jwt.SigningMethodRS256, k, &k.PublicKey, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.verify, key.public = jwt.SigningMethodRS256, k, k
+	case ed25519.PrivateKey:
+		pub := k.Public().(ed25519.PublicKey)
+		key.Method, key.sign, key.verify, key.public = jwt.SigningMethodEdDSA, k, pub, pub
+	case ed25519.PublicKey:
+		key.Method, key.verify, key.public = jwt.SigningMethodEdDSA, k, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", parsed)
+	}
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys need at least 2048 bits")
+	}
+	key.ID = jwkThumbprint(key.public)
+	return key, nil
+}
+
+// CanSign reports whether the key holds its private part.
+func (k *JWTKey) CanSign() bool { return k.sign != nil }
+
+// jwk returns the public JSON Web Key, or nil for HMAC secrets.
+func (k *JWTKey) jwk() map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "crv": "Ed25519", "x": b64(pub)}
+	}
+	return nil
+}
+
+// jwkThumbprint returns the RFC 7638 thumbprint of a public key: the SHA-256
+// of its required JWK members in lexicographic order.
+func jwkThumbprint(public crypto.PublicKey) string {
+	k := (&JWTKey{public: public}).jwk()
+	// encoding/json sorts map keys, as the thumbprint requires
+	b, _ := json.Marshal(k)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWTKeySet signs tokens with one key and verifies them with any of its keys,
+// so that keys can be rotated without invalidating the tokens in use.
+type JWTKeySet struct {
+	signing *JWTKey
+	keys    map[string]*JWTKey
+	legacy  *JWTKey // Verifies tokens without kid, issued before kid existed
+}
+
+// NewJWTKeySet returns a set signing with signing and verifying with it and
+// verifiers. Tokens without kid are checked with the HMAC key, if any.
+func NewJWTKeySet(signing *JWTKey, verifiers ...*JWTKey) (*JWTKeySet, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, errors.New("the signing key has no private part")
+	}
+	set := &JWTKeySet{signing: signing, keys: map[string]*JWTKey{}}
+	for _, k := range append([]*JWTKey{signing}, verifiers...) {
+		if _, dup := set.keys[k.ID]; dup {
+			continue
+		}
+		set.keys[k.ID] = k
+		if k.Method == jwt.SigningMethodHS256 && set.legacy == nil {
+			set.legacy = k
+		}
+	}
+	return set, nil
+}
+
+// SigningKey returns the key new tokens are signed with.
+func (s *JWTKeySet) SigningKey() *JWTKey { return s.signing }
+
+// sign signs claims with the signing key, naming it in the kid header.
+func (s *JWTKeySet) sign(claims jwt.MapClaims) (string, error) {
+	t := jwt.NewWithClaims(s.signing.Method, claims)
+	t.Header["kid"] = s.signing.ID
+	return t.SignedString(s.signing.sign)
+}
+
+// keyFunc picks the key of a token by its kid, and refuses tokens whose
+// algorithm is not that of the key.
+func (s *JWTKeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	var key *JWTKey
+	if kid, ok := t.Header["kid"].(string); ok {
+		key = s.keys[kid]
+	} else if _, present := t.Header["kid"]; !present {
+		key = s.legacy
+	}
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verify, nil
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set. HMAC
+// secrets are left out.
+func (s *JWTKeySet) JWKS() map[string]interface{} {
+	keys := []map[string]string{}
+	for _, k := range append([]*JWTKey{s.signing}, s.sortedKeys()...) {
+		jwk := k.jwk()
+		if jwk == nil {
+			continue
+		}
+		jwk["kid"], jwk["alg"], jwk["use"] = k.ID, k.Method.Alg(), "sig"
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// sortedKeys returns the keys other than the signing one, by ID.
+func (s *JWTKeySet) sortedKeys() []*JWTKey {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		if id != s.signing.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	keys := make([]*JWTKey, len(ids))
+	for i, id := range ids {
+		keys[i] = s.keys[id]
+	}
+	return keys
+}
@@ -4,6 +4,10 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// dummyHash is checked when there is no hash to check, so that a login with an
+// unknown email takes as long as one with a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
 // HashPassword returns a bcrypt hash for a plaintext password.
 func HashPassword(plain string) (string, error) {
 	h, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
@@ -17,3 +21,13 @@ func HashPassword(plain string) (string, error) {
 func CheckPassword(hash, plain string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
 }
+
+// CheckPasswordConstantTime is CheckPassword, except that an empty hash costs
+// a bcrypt comparison too, and fails.
+func CheckPasswordConstantTime(hash, plain string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(plain))
+		return false
+	}
+	return CheckPassword(hash, plain)
+}
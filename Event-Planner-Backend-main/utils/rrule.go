@@ -0,0 +1,634 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies supported by RRule.
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// maxRRulePeriods bounds expansion of rules that can never match again
+// (e.g. BYMONTH=2;BYMONTHDAY=30).
+const maxRRulePeriods = 100000
+
+// iCalendar date-time layouts.
+const (
+	icalUTCLayout      = "20060102T150405Z"
+	icalLocalLayout    = "20060102T150405"
+	icalDateOnlyLayout = "20060102"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is a BYDAY entry such as "MO" (every Monday) or "-1FR"
+// (the last Friday of the month or year).
+type WeekdayNum struct {
+	N   int // 0 means every such weekday in the period
+	Day time.Weekday
+}
+
+// RRule is a parsed RFC 5545 recurrence rule. The DAILY, WEEKLY, MONTHLY and
+// YEARLY frequencies are supported with INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY, BYMONTH, BYSETPOS and WKST.
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time // Zero when the rule has no UNTIL
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// ParseRRule parses a recurrence rule such as "FREQ=WEEKLY;BYDAY=MO,WE".
+// An optional "RRULE:" prefix is accepted. A floating or date-only UNTIL is
+// read in loc; a date-only UNTIL includes the whole day.
+func ParseRRule(value string, loc *time.Location) (*RRule, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "RRULE:"), "rrule:")
+	if value == "" {
+		return nil, errors.New("empty rule")
+	}
+
+	r := &RRule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, val := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch val {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = val
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(val); err != nil || r.Interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(val); err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+		case "UNTIL":
+			if r.Until, err = parseUntil(val, loc); err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(item)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = parseIntList(val, -31, 31); err != nil {
+				return nil, fmt.Errorf("invalid BYMONTHDAY %q", val)
+			}
+		case "BYMONTH":
+			if r.ByMonth, err = parseIntList(val, 1, 12); err != nil {
+				return nil, fmt.Errorf("invalid BYMONTH %q", val)
+			}
+		case "BYSETPOS":
+			if r.BySetPos, err = parseIntList(val, -366, 366); err != nil {
+				return nil, fmt.Errorf("invalid BYSETPOS %q", val)
+			}
+		case "WKST":
+			day, ok := weekdayCodes[val]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			r.WeekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	if r.Freq == FreqWeekly && len(r.ByMonthDay) > 0 {
+		return nil, errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	if r.Freq == FreqDaily || r.Freq == FreqWeekly {
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return nil, fmt.Errorf("BYDAY ordinals are not allowed with FREQ=%s", r.Freq)
+			}
+		}
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return nil, errors.New("BYSETPOS requires another BYxxx rule part")
+	}
+	return r, nil
+}
+
+// String returns the rule in canonical RFC 5545 form, without the "RRULE:" prefix.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+FormatICalUTC(r.Until))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = weekdayNames[wd.Day]
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterate calls fn with each occurrence start in order, beginning with
+// dtstart itself, until fn returns false or the rule is exhausted.
+// Occurrences keep the wall-clock time of dtstart in its location, so a
+// weekly 18:00 event stays at 18:00 across DST changes.
+func (r *RRule) Iterate(dtstart time.Time, fn func(time.Time) bool) {
+	r.iterate(dtstart, 0, fn)
+}
+
+// IterateFrom is Iterate for callers that only need the occurrences from
+// from on: rules without COUNT skip straight to the period containing from,
+// so some earlier occurrences may be left out.
+func (r *RRule) IterateFrom(dtstart, from time.Time, fn func(time.Time) bool) {
+	first := 0
+	if r.Count == 0 {
+		first = r.periodsBefore(civilDate(dtstart), civilDate(from.In(dtstart.Location())))
+	}
+	r.iterate(dtstart, first, fn)
+}
+
+// iterate runs Iterate from the given period on; dtstart itself is only
+// emitted from the first period.
+func (r *RRule) iterate(dtstart time.Time, first int, fn func(time.Time) bool) {
+	if !r.Until.IsZero() && dtstart.After(r.Until) {
+		return
+	}
+	if first == 0 && !fn(dtstart) {
+		return
+	}
+	emitted := 1
+
+	loc := dtstart.Location()
+	hour, minute, sec := dtstart.Clock()
+	start := civilDate(dtstart)
+
+	for period := first; period < first+maxRRulePeriods; period++ {
+		for _, day := range r.periodDates(start, dtstart, period) {
+			t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, sec, dtstart.Nanosecond(), loc)
+			if !t.After(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return
+			}
+			if r.Count > 0 && emitted >= r.Count {
+				return
+			}
+			emitted++
+			if !fn(t) {
+				return
+			}
+		}
+	}
+}
+
+// periodsBefore returns how many whole periods of the rule, counted from the
+// one of start, end before the date day. One period is kept as a margin for
+// occurrences shifted by time zones.
+func (r *RRule) periodsBefore(start, day time.Time) int {
+	if !day.After(start) {
+		return 0
+	}
+	var n int
+	switch r.Freq {
+	case FreqDaily:
+		n = int(day.Sub(start).Hours() / 24)
+	case FreqWeekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		n = int(day.Sub(start.AddDate(0, 0, -offset)).Hours()/24) / 7
+	case FreqMonthly:
+		n = (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+	case FreqYearly:
+		n = day.Year() - start.Year()
+	}
+	n = n/r.Interval - 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Between returns the occurrence starts in [from, to), at most limit of them
+// (no limit when limit <= 0).
+func (r *RRule) Between(dtstart, from, to time.Time, limit int) []time.Time {
+	var result []time.Time
+	r.IterateFrom(dtstart, from, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			result = append(result, t)
+		}
+		return limit <= 0 || len(result) < limit
+	})
+	return result
+}
+
+// Includes reports whether t is an occurrence start of the rule.
+func (r *RRule) Includes(dtstart, t time.Time) bool {
+	found := false
+	r.IterateFrom(dtstart, t, func(occ time.Time) bool {
+		if occ.Equal(t) {
+			found = true
+		}
+		return occ.Before(t)
+	})
+	return found
+}
+
+// CountBefore returns how many occurrences start strictly before t.
+func (r *RRule) CountBefore(dtstart, t time.Time) int {
+	n := 0
+	r.Iterate(dtstart, func(occ time.Time) bool {
+		if !occ.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// periodDates returns the sorted candidate dates (at UTC midnight) of the
+// given period, after BYSETPOS is applied.
+func (r *RRule) periodDates(start, dtstart time.Time, period int) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		day := start.AddDate(0, 0, period*r.Interval)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+	case FreqWeekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := start.AddDate(0, 0, -offset+period*r.Interval*7)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.matchesMonth(day) && r.matchesWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case FreqMonthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(month) {
+			days = r.monthDates(month, start.Day(), true)
+		}
+	case FreqYearly:
+		year := start.Year() + period*r.Interval
+		days = r.yearDates(year, start)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return r.applySetPos(days)
+}
+
+// monthDates expands BYMONTHDAY/BYDAY within a month. BYDAY ordinals count
+// within the month when ordinals is true.
+func (r *RRule) monthDates(month time.Time, defaultDay int, ordinals bool) []time.Time {
+	last := daysIn(month)
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if defaultDay > last {
+			return nil
+		}
+		return []time.Time{month.AddDate(0, 0, defaultDay-1)}
+	}
+
+	var monthDays map[int]bool
+	if len(r.ByMonthDay) > 0 {
+		monthDays = map[int]bool{}
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + d + 1
+			}
+			if d >= 1 && d <= last {
+				monthDays[d] = true
+			}
+		}
+	}
+
+	var weekDays map[int]bool
+	if len(r.ByDay) > 0 {
+		weekDays = map[int]bool{}
+		for _, wd := range r.ByDay {
+			for _, d := range weekdayDates(month, last, wd, ordinals) {
+				weekDays[d] = true
+			}
+		}
+	}
+
+	var result []time.Time
+	for d := 1; d <= last; d++ {
+		if monthDays != nil && !monthDays[d] {
+			continue
+		}
+		if weekDays != nil && !weekDays[d] {
+			continue
+		}
+		result = append(result, month.AddDate(0, 0, d-1))
+	}
+	return result
+}
+
+// yearDates expands a YEARLY period.
+func (r *RRule) yearDates(year int, start time.Time) []time.Time {
+	// BYMONTH scopes everything to those months
+	if len(r.ByMonth) > 0 {
+		var result []time.Time
+		for _, m := range r.ByMonth {
+			month := time.Date(year, time.Month(m), 1, 0, 0, 0, 0, time.UTC)
+			result = append(result, r.monthDates(month, start.Day(), true)...)
+		}
+		return result
+	}
+
+	// BYDAY without BYMONTH: ordinals count within the year
+	if len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+		first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		total := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		seen := map[int]bool{}
+		for _, wd := range r.ByDay {
+			for _, d := range weekdayDates(first, total, wd, true) {
+				seen[d] = true
+			}
+		}
+		var result []time.Time
+		for d := 1; d <= total; d++ {
+			if seen[d] {
+				result = append(result, first.AddDate(0, 0, d-1))
+			}
+		}
+		return result
+	}
+
+	// BYMONTHDAY without BYMONTH applies to every month
+	if len(r.ByMonthDay) > 0 {
+		var result []time.Time
+		for m := 1; m <= 12; m++ {
+			month := time.Date(year, time.Month(m), 1, 0, 0, 0, 0, time.UTC)
+			result = append(result, r.monthDates(month, start.Day(), false)...)
+		}
+		return result
+	}
+
+	// Same month and day as the start; skipped in years without that day
+	month := time.Date(year, start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return r.monthDates(month, start.Day(), true)
+}
+
+// applySetPos keeps only the BYSETPOS positions of the period's candidates.
+func (r *RRule) applySetPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	keep := map[int]bool{}
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(days) + pos
+		}
+		if i >= 0 && i < len(days) {
+			keep[i] = true
+		}
+	}
+	var result []time.Time
+	for i, day := range days {
+		if keep[i] {
+			result = append(result, day)
+		}
+	}
+	return result
+}
+
+func (r *RRule) matchesMonth(day time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if time.Month(m) == day.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(day)
+	for _, d := range r.ByMonthDay {
+		if d < 0 {
+			d = last + d + 1
+		}
+		if d == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayDates returns the day numbers (1-based, counted from first) within a
+// span of length days that fall on wd.Day, narrowed to the wd.N-th one when
+// ordinals is true and N is set.
+func weekdayDates(first time.Time, length int, wd WeekdayNum, ordinals bool) []int {
+	var matches []int
+	offset := (int(wd.Day) - int(first.Weekday()) + 7) % 7
+	for d := offset + 1; d <= length; d += 7 {
+		matches = append(matches, d)
+	}
+	if !ordinals || wd.N == 0 {
+		return matches
+	}
+	i := wd.N - 1
+	if wd.N < 0 {
+		i = len(matches) + wd.N
+	}
+	if i < 0 || i >= len(matches) {
+		return nil
+	}
+	return []int{matches[i]}
+}
+
+// civilDate returns the calendar date of t (in t's location) at UTC midnight.
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysIn returns the number of days in the month of t.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+	code := value[len(value)-2:]
+	day, ok := weekdayCodes[code]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+	n := 0
+	if prefix := value[:len(value)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+	}
+	return WeekdayNum{N: n, Day: day}, nil
+}
+
+func parseIntList(value string, min, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, errors.New("out of range")
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if len(value) == len(icalDateOnlyLayout) {
+		day, err := time.ParseInLocation(icalDateOnlyLayout, value, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return ParseICalDateTime(value, loc)
+}
+
+// FormatICalUTC formats t as an iCalendar UTC date-time, e.g. 20240715T180000Z.
+func FormatICalUTC(t time.Time) string {
+	return t.UTC().Format(icalUTCLayout)
+}
+
+// ParseICalDateTime parses an iCalendar date-time: UTC ("20240715T180000Z"),
+// floating ("20240715T180000", read in loc) or a date ("20240715", midnight in loc).
+func ParseICalDateTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(icalUTCLayout, value)
+	case len(value) == len(icalDateOnlyLayout):
+		return time.ParseInLocation(icalDateOnlyLayout, value, loc)
+	default:
+		return time.ParseInLocation(icalLocalLayout, value, loc)
+	}
+}
+
+// ParseICalDateList parses a comma separated list of iCalendar date-times.
+func ParseICalDateList(value string, loc *time.Location) ([]time.Time, error) {
+	var result []time.Time
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		t, err := ParseICalDateTime(item, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", item)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// FormatICalDateList formats instants as a comma separated list of UTC date-times.
+func FormatICalDateList(times []time.Time) string {
+	parts := make([]string, len(times))
+	for i, t := range times {
+		parts[i] = FormatICalUTC(t)
+	}
+	return strings.Join(parts, ",")
+}
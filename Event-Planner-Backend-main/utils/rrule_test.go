@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// mustLocation loads a time zone or fails the test.
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// mustRRule parses a rule or fails the test.
+func mustRRule(t *testing.T, value string, loc *time.Location) *RRule {
+	t.Helper()
+	rule, err := ParseRRule(value, loc)
+	if err != nil {
+		t.Fatalf("ParseRRule(%q): %v", value, err)
+	}
+	return rule
+}
+
+// occurrences returns the first n occurrence starts of a rule.
+func occurrences(rule *RRule, dtstart time.Time, n int) []time.Time {
+	var result []time.Time
+	rule.Iterate(dtstart, func(t time.Time) bool {
+		result = append(result, t)
+		return len(result) < n
+	})
+	return result
+}
+
+// expectTimes fails the test unless got holds the same instants as want.
+func expectTimes(t *testing.T, name string, got, want []time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+		return
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("%s: occurrence %d is %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestIterateFromSkipsPeriods(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	tests := []struct {
+		rule    string
+		dtstart time.Time
+	}{
+		{"FREQ=DAILY;INTERVAL=3", time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=DAILY;BYMONTH=2,8;BYDAY=TU", time.Date(2020, 2, 4, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", time.Date(2020, 1, 3, 18, 0, 0, 0, berlin)},
+		{"FREQ=WEEKLY;WKST=SU;BYDAY=SU,SA;INTERVAL=3", time.Date(2020, 1, 4, 23, 30, 0, 0, berlin)},
+		{"FREQ=MONTHLY;BYDAY=-1FR", time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY;INTERVAL=5;BYMONTHDAY=31", time.Date(2020, 1, 31, 8, 0, 0, 0, berlin)},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", time.Date(2020, 2, 29, 10, 0, 0, 0, time.UTC)},
+		{"FREQ=DAILY;UNTIL=20350101T000000Z", time.Date(2020, 1, 1, 0, 30, 0, 0, berlin)},
+	}
+	// Windows starting after many periods, one ending before dtstart
+	windows := []struct{ from, to time.Time }{
+		{time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 30, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2028, 2, 1, 0, 0, 0, 0, berlin), time.Date(2028, 3, 1, 0, 0, 0, 0, berlin)},
+		{time.Date(2034, 12, 30, 12, 0, 0, 0, time.UTC), time.Date(2035, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2036, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2052, 3, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		rule := mustRRule(t, tt.rule, tt.dtstart.Location())
+		for _, w := range windows {
+			// Iterate from dtstart is the reference
+			var want []time.Time
+			rule.Iterate(tt.dtstart, func(occ time.Time) bool {
+				if !occ.Before(w.to) {
+					return false
+				}
+				if !occ.Before(w.from) {
+					want = append(want, occ)
+				}
+				return true
+			})
+			expectTimes(t, tt.rule+" from "+w.from.String(), rule.Between(tt.dtstart, w.from, w.to, 0), want)
+			for _, occ := range want {
+				if !rule.Includes(tt.dtstart, occ) {
+					t.Errorf("%s: %v not included", tt.rule, occ)
+				}
+			}
+		}
+	}
+}
+
+func TestBySetPos(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		rule    string
+		dtstart time.Time
+		want    []time.Time
+	}{
+		{
+			// Last weekday of the month
+			"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", date(2024, 1, 31),
+			[]time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 29), date(2024, 4, 30), date(2024, 5, 31)},
+		},
+		{
+			// Second Tuesday of the month
+			"FREQ=MONTHLY;BYDAY=TU;BYSETPOS=2", date(2024, 1, 9),
+			[]time.Time{date(2024, 1, 9), date(2024, 2, 13), date(2024, 3, 12), date(2024, 4, 9)},
+		},
+		{
+			// First and last of the 1st and 15th of the month, i.e. both
+			"FREQ=MONTHLY;BYMONTHDAY=1,15,-1;BYSETPOS=1,-1", date(2024, 2, 1),
+			[]time.Time{date(2024, 2, 1), date(2024, 2, 29), date(2024, 3, 1), date(2024, 3, 31)},
+		},
+		{
+			// First Sunday of March and last Sunday of September
+			"FREQ=YEARLY;BYMONTH=3,9;BYDAY=SU;BYSETPOS=1,-1", date(2024, 3, 3),
+			[]time.Time{date(2024, 3, 3), date(2024, 9, 29), date(2025, 3, 2), date(2025, 9, 28)},
+		},
+		{
+			// Positions beyond the set are ignored
+			"FREQ=WEEKLY;BYDAY=MO,WE;BYSETPOS=2,3", date(2024, 1, 3),
+			[]time.Time{date(2024, 1, 3), date(2024, 1, 10), date(2024, 1, 17)},
+		},
+	}
+	for _, tt := range tests {
+		rule := mustRRule(t, tt.rule, time.UTC)
+		expectTimes(t, tt.rule, occurrences(rule, tt.dtstart, len(tt.want)), tt.want)
+	}
+}
+
+func TestUntilAndCount(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 10, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  time.Time
+		from, to time.Time
+		want     []time.Time
+	}{
+		{"count", "FREQ=DAILY;COUNT=5", day(1), day(1), day(31),
+			[]time.Time{day(1), day(2), day(3), day(4), day(5)}},
+		{"count crossing the window", "FREQ=DAILY;COUNT=5", day(1), day(3), day(31),
+			[]time.Time{day(3), day(4), day(5)}},
+		{"count ending before the window", "FREQ=DAILY;COUNT=5", day(1), day(6), day(31), nil},
+		{"count of several per period", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", day(1), day(5), day(31),
+			[]time.Time{day(8), day(10)}},
+		{"count counting dtstart off the rule", "FREQ=WEEKLY;BYDAY=FR;COUNT=3", day(2), day(1), day(31),
+			[]time.Time{day(2), day(5), day(12)}},
+		{"count with a window years later", "FREQ=DAILY;COUNT=3", day(1), day(1).AddDate(5, 0, 0), day(1).AddDate(6, 0, 0), nil},
+		{"until on an occurrence", "FREQ=DAILY;UNTIL=20240105T100000Z", day(1), day(4), day(31),
+			[]time.Time{day(4), day(5)}},
+		{"until between occurrences", "FREQ=DAILY;UNTIL=20240105T095959Z", day(1), day(4), day(31),
+			[]time.Time{day(4)}},
+		{"date-only until", "FREQ=DAILY;UNTIL=20240103", day(1), day(1), day(31),
+			[]time.Time{day(1), day(2), day(3)}},
+		{"until before dtstart", "FREQ=DAILY;UNTIL=20231231T000000Z", day(1), day(1), day(31), nil},
+		{"until in a window years later", "FREQ=MONTHLY;UNTIL=20300110T000000Z", day(1), day(1).AddDate(5, 11, 0), day(1).AddDate(7, 0, 0),
+			[]time.Time{time.Date(2029, 12, 1, 10, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		rule := mustRRule(t, tt.rule, time.UTC)
+		expectTimes(t, tt.name, rule.Between(tt.dtstart, tt.from, tt.to, 0), tt.want)
+	}
+
+	rule := mustRRule(t, "FREQ=DAILY;COUNT=5", time.UTC)
+	if n := rule.CountBefore(day(1), day(4)); n != 3 {
+		t.Errorf("CountBefore = %d, want 3", n)
+	}
+	if rule.Includes(day(1), day(6)) {
+		t.Error("occurrence past COUNT included")
+	}
+
+	for _, value := range []string{"FREQ=DAILY;COUNT=2;UNTIL=20240105T100000Z", "FREQ=DAILY;COUNT=0", "FREQ=DAILY;UNTIL=2024"} {
+		if _, err := ParseRRule(value, time.UTC); err == nil {
+			t.Errorf("ParseRRule(%q) succeeded", value)
+		}
+	}
+}
+
+func TestDaylightSavingTime(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	utc := func(m time.Month, d, h int) time.Time { return time.Date(2024, m, d, h, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []time.Time
+	}{
+		{"spring forward", "FREQ=WEEKLY;COUNT=3", time.Date(2024, 3, 24, 18, 0, 0, 0, berlin),
+			[]time.Time{utc(3, 24, 17), utc(3, 31, 16), utc(4, 7, 16)}},
+		{"fall back", "FREQ=DAILY;COUNT=3", time.Date(2024, 10, 26, 18, 0, 0, 0, berlin),
+			[]time.Time{utc(10, 26, 16), utc(10, 27, 17), utc(10, 28, 17)}},
+		// A floating UNTIL is read in the zone of the event
+		{"floating until", "FREQ=WEEKLY;UNTIL=20240407T180000", time.Date(2024, 3, 24, 18, 0, 0, 0, berlin),
+			[]time.Time{utc(3, 24, 17), utc(3, 31, 16), utc(4, 7, 16)}},
+		{"monthly across both changes", "FREQ=MONTHLY;INTERVAL=4;COUNT=3", time.Date(2024, 2, 15, 9, 0, 0, 0, berlin),
+			[]time.Time{utc(2, 15, 8), utc(6, 15, 7), utc(10, 15, 7)}},
+	}
+	for _, tt := range tests {
+		rule := mustRRule(t, tt.rule, berlin)
+		got := occurrences(rule, tt.dtstart, len(tt.want)+1)
+		expectTimes(t, tt.name, got, tt.want)
+		for _, occ := range got {
+			if h, m, _ := occ.Clock(); h != 18 && h != 9 || m != 0 {
+				t.Errorf("%s: %v does not keep the wall-clock time", tt.name, occ)
+			}
+		}
+	}
+
+	// A window after the change skips to it and keeps the local time
+	rule := mustRRule(t, "FREQ=WEEKLY;BYDAY=SU", berlin)
+	dtstart := time.Date(2020, 1, 5, 18, 0, 0, 0, berlin)
+	got := rule.Between(dtstart, utc(3, 30, 0), utc(4, 1, 0), 0)
+	expectTimes(t, "window after the change", got, []time.Time{utc(3, 31, 16)})
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// locationCache avoids reading the zoneinfo database on every request.
+var locationCache sync.Map
+
+// LoadLocation returns the IANA time zone for name, caching the result.
+// An empty name resolves to UTC.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// localLayouts are accepted for instants without an explicit UTC offset.
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseInstant parses an RFC 3339 timestamp, or a local date-time without
+// offset (e.g. "2024-07-15T18:00") interpreted as wall-clock time in loc.
+func ParseInstant(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("empty timestamp")
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("expected RFC 3339 (2006-01-02T15:04:05Z07:00) or local 2006-01-02T15:04")
+}